@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/fabiocorneti/todoist-assistant/internal/config"
+	"github.com/fabiocorneti/todoist-assistant/internal/daemon"
+	"github.com/fabiocorneti/todoist-assistant/internal/server"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+const (
+	exitError = 1
+)
+
+type app struct {
+	configFile string
+	logLevel   string
+	logFormat  string
+	exitCode   int
+}
+
+// Execute runs the command line interface and returns the exit code.
+func Execute() int {
+	a := &app{}
+	if err := a.rootCommand().Execute(); err != nil {
+		return exitError
+	}
+	return a.exitCode
+}
+
+func (a *app) rootCommand() *cobra.Command {
+	root := &cobra.Command{
+		Use:   "todoist-assistant",
+		Short: "Automates labels in Todoist and creates tasks from Jira issues",
+		Long: "Automates labels in Todoist and creates tasks from Jira issues.\n\n" +
+			"Without a subcommand, runs as a daemon.",
+		Args:          cobra.NoArgs,
+		SilenceUsage:  true,
+		SilenceErrors: false,
+		RunE:          a.runDaemon,
+	}
+	root.PersistentFlags().StringVarP(&a.configFile, "config", "c", "",
+		"path of the configuration file (default $TODOIST_ASSISTANT_CONFIG or the first config.yaml found)")
+	root.PersistentFlags().StringVar(&a.logLevel, "log-level", "",
+		"log level, overrides the configuration (trace, debug, info, warn, error)")
+	root.PersistentFlags().StringVar(&a.logFormat, "log-format", "",
+		"log format, overrides the configuration (text, json)")
+
+	root.AddCommand(
+		&cobra.Command{
+			Use:   "daemon",
+			Short: "Process tasks on start and then on every update interval",
+			Args:  cobra.NoArgs,
+			RunE:  a.runDaemon,
+		},
+		a.runCommand(),
+		a.configCommand(),
+		a.jiraCommand(),
+		a.projectsCommand(),
+		a.undoCommand(),
+	)
+	return root
+}
+
+// configuration loads the configuration using the global flags; it returns an error if the
+// configuration is invalid.
+func (a *app) configuration() (config.Config, *logrus.Logger, error) {
+	a.applyConfigFlags()
+	cfg, err := config.GetConfiguration()
+	return cfg, config.GetLogger(), err
+}
+
+func (a *app) applyConfigFlags() {
+	if a.configFile != "" {
+		config.SetConfigFile(a.configFile)
+	}
+	if a.logLevel != "" {
+		config.SetLogLevel(a.logLevel)
+	}
+	if a.logFormat != "" {
+		config.SetLogFormat(a.logFormat)
+	}
+}
+
+func (a *app) runDaemon(_ *cobra.Command, _ []string) error {
+	a.applyConfigFlags()
+	provider, err := config.GetProvider()
+	if err != nil {
+		return err
+	}
+	logger := config.GetLogger()
+
+	d := daemon.NewDaemon(provider, logger)
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	go func() {
+		if watchErr := provider.Watch(ctx, logger); watchErr != nil {
+			logger.WithError(watchErr).Error("Cannot watch the configuration, changes will be applied on SIGHUP")
+		}
+	}()
+	go func() {
+		if serveErr := server.Serve(ctx, provider, d.Status(), logger); serveErr != nil {
+			logger.WithError(serveErr).Error("Cannot serve health checks and metrics")
+		}
+	}()
+
+	a.exitCode = d.Run()
+	return nil
+}
+
+// signalContext returns a context cancelled on SIGINT or SIGTERM.
+func signalContext(cmd *cobra.Command) (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+}
@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/fabiocorneti/todoist-assistant/internal/config"
+	"github.com/spf13/cobra"
+)
+
+func (a *app) configCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the configuration",
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "validate",
+		Short: "Check that the configuration is valid",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			a.applyConfigFlags()
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			if problems := cfg.Validate(); len(problems) > 0 {
+				for _, problem := range problems {
+					if _, err = fmt.Fprintln(out, problem); err != nil {
+						return err
+					}
+				}
+				return fmt.Errorf("the configuration has %d problems", len(problems))
+			}
+
+			if _, err = fmt.Fprintln(out, "Configuration is valid"); err != nil {
+				return err
+			}
+			for _, secret := range cfg.SecretSources() {
+				if _, err = fmt.Fprintf(out, "  %s: read from %s\n", secret.Name, secret.Source); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+	return cmd
+}
@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/fabiocorneti/todoist-assistant/internal/jira"
+	"github.com/fabiocorneti/todoist-assistant/internal/process"
+	"github.com/spf13/cobra"
+)
+
+func (a *app) jiraCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "jira",
+		Short: "Inspect Jira instances",
+	}
+
+	var site string
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List the issues returned by the JQL of each Jira instance",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			cfg, _, err := a.configuration()
+			if err != nil {
+				return err
+			}
+
+			ctx, stop := signalContext(cmd)
+			defer stop()
+
+			cassette, err := process.OpenCassette(cfg)
+			if err != nil {
+				return err
+			}
+			writer := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0) //nolint:gomnd // column padding
+			fmt.Fprintln(writer, "SITE\tKEY\tSTATUS\tPRIORITY\tSUMMARY")
+			for _, jiraConfig := range cfg.Jira {
+				if site != "" && jiraConfig.Site != site {
+					continue
+				}
+				client, err := jira.NewHTTPClient(jiraConfig, cfg.RetryPolicy(), cfg.RequestTimeout)
+				if err != nil {
+					return fmt.Errorf("error configuring the client of %s: %w", jiraConfig.Site, err)
+				}
+				if cassette != nil {
+					client.UseCassette(cassette)
+				}
+				issues, err := jira.FetchJiraIssues(ctx, client, jiraConfig)
+				if err != nil {
+					return fmt.Errorf("error fetching issues from %s: %w", jiraConfig.Site, err)
+				}
+				for _, issue := range issues {
+					fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\n", jiraConfig.Site, issue.Key,
+						issue.Fields.Status.Name, issue.Fields.Priority.Name, issue.Fields.Summary)
+				}
+			}
+			if err = writer.Flush(); err != nil || cassette == nil {
+				return err
+			}
+			return cassette.Err()
+		},
+	}
+	list.Flags().StringVar(&site, "site", "", "only list issues from the Jira instance with this site URL")
+
+	cmd.AddCommand(list)
+	return cmd
+}
@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/fabiocorneti/todoist-assistant/internal/process"
+	"github.com/fabiocorneti/todoist-assistant/internal/todoist"
+	"github.com/spf13/cobra"
+)
+
+func (a *app) projectsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "projects",
+		Short: "Inspect Todoist projects",
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List Todoist projects",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			cfg, _, err := a.configuration()
+			if err != nil {
+				return err
+			}
+
+			ctx, stop := signalContext(cmd)
+			defer stop()
+
+			client, err := todoist.NewTodoistClient(cfg.Todoist.APIURL, cfg.Todoist.Token, cfg.Todoist.Transport,
+				cfg.RetryPolicy(), cfg.RequestTimeout, cfg.IsTest())
+			if err != nil {
+				return err
+			}
+			cassette, err := process.OpenCassette(cfg)
+			if err != nil {
+				return err
+			}
+			if cassette != nil {
+				client.UseCassette(cassette)
+			}
+			projects, err := client.GetProjects(ctx)
+			if err != nil {
+				return fmt.Errorf("error fetching Todoist projects: %w", err)
+			}
+
+			writer := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0) //nolint:gomnd // column padding
+			fmt.Fprintln(writer, "ID\tPARENT ID\tNAME")
+			for _, project := range projects {
+				fmt.Fprintf(writer, "%s\t%s\t%s\n", project.ID, project.ParentID, project.Name)
+			}
+			if err = writer.Flush(); err != nil || cassette == nil {
+				return err
+			}
+			return cassette.Err()
+		},
+	})
+	return cmd
+}
@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"errors"
+
+	"github.com/fabiocorneti/todoist-assistant/internal/daemon"
+	"github.com/fabiocorneti/todoist-assistant/internal/process"
+	"github.com/spf13/cobra"
+)
+
+func (a *app) runCommand() *cobra.Command {
+	var dryRun bool
+	var record, replay string
+	cmd := &cobra.Command{
+		Use:   "run",
+		Short: "Process tasks once and exit",
+		Long: "Process tasks once and exit, e.g. from cron or a Kubernetes CronJob.\n\n" +
+			"The exit code is 0 if the run succeeded and 1 if it had failures.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			cfg, logger, err := a.configuration()
+			if err != nil {
+				return err
+			}
+			if dryRun {
+				cfg.DryRun = true
+			}
+			if record != "" {
+				cfg.RecordPath = record
+			}
+			if replay != "" {
+				cfg.ReplayPath = replay
+			}
+			if cfg.RecordPath != "" && cfg.ReplayPath != "" {
+				return errors.New("--record and --replay cannot be used together")
+			}
+
+			ctx, stop := signalContext(cmd)
+			defer stop()
+
+			summary := process.RunProcess(ctx, cfg, logger)
+			switch {
+			case ctx.Err() != nil:
+				a.exitCode = daemon.ExitRunCancelled
+			case summary.HasFailures():
+				a.exitCode = daemon.ExitRunFailed
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "print the changes instead of sending them to Todoist")
+	cmd.Flags().StringVar(&record, "record", "",
+		"record the requests to Todoist and Jira to a cassette file, with tokens and emails scrubbed")
+	cmd.Flags().StringVar(&replay, "replay", "",
+		"replay the responses recorded in a cassette file instead of sending requests")
+	return cmd
+}
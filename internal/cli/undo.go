@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"github.com/fabiocorneti/todoist-assistant/internal/daemon"
+	"github.com/fabiocorneti/todoist-assistant/internal/process"
+	"github.com/spf13/cobra"
+)
+
+func (a *app) undoCommand() *cobra.Command {
+	var runID string
+	var dryRun bool
+	cmd := &cobra.Command{
+		Use:   "undo",
+		Short: "Revert the changes made to Todoist tasks by a run",
+		Long: "Revert the changes recorded in the audit log for a run: labels and priorities are restored,\n" +
+			"completed tasks are reopened and created tasks are deleted.\n\n" +
+			"The ID of a run is logged in the run_id field of its log entries.\n" +
+			"The exit code is 0 if every change was reverted and 1 otherwise.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			cfg, logger, err := a.configuration()
+			if err != nil {
+				return err
+			}
+			if dryRun {
+				cfg.DryRun = true
+			}
+
+			ctx, stop := signalContext(cmd)
+			defer stop()
+
+			summary := process.Undo(ctx, cfg, logger, runID)
+			switch {
+			case ctx.Err() != nil:
+				a.exitCode = daemon.ExitRunCancelled
+			case summary.HasFailures():
+				a.exitCode = daemon.ExitRunFailed
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&runID, "run", "", "the ID of the run to undo")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "print the changes instead of sending them to Todoist")
+	cmd.MarkFlagRequired("run") //nolint:errcheck // the flag is defined above
+	return cmd
+}
@@ -1,12 +1,18 @@
 package config
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"os"
-	"strconv"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/fabiocorneti/todoist-assistant/internal/httpclient"
 	"github.com/sirupsen/logrus"
-	"gopkg.in/yaml.v2"
 )
 
 const (
@@ -16,59 +22,238 @@ const (
 	p4 = 1
 )
 
+// Flavors of Jira, see JiraConfig.Flavor.
+const (
+	FlavorCloud      = "cloud"
+	FlavorDataCenter = "datacenter"
+)
+
+// Sources of the due dates of the tasks linked to Jira issues, see JiraConfig.DueDateSources.
+const (
+	DueDateSourceDueDate    = "duedate"
+	DueDateSourceSprint     = "sprint"
+	DueDateSourceFixVersion = "fixVersion"
+
+	// DefaultSprintField is the custom field holding the sprints of an issue in Jira Cloud.
+	DefaultSprintField = "customfield_10020"
+)
+
+// Log formats.
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
 type Config struct {
 	LogLevel       string `yaml:"logLevel"`
+	LogFormat      string `yaml:"logFormat"`
 	UpdateInterval int    `yaml:"updateInterval"`
-	Todoist        struct {
+	StatePath      string `yaml:"statePath"`
+	AuditPath      string `yaml:"auditPath"`
+	// RecordPath is a cassette file to which the requests to Todoist and Jira are recorded, and
+	// ReplayPath a cassette whose responses are replayed instead of sending the requests.
+	RecordPath      string        `yaml:"recordPath"`
+	ReplayPath      string        `yaml:"replayPath"`
+	DryRun          bool          `yaml:"dryRun"`
+	PlanFormat      string        `yaml:"planFormat"`
+	RunTimeout      time.Duration `yaml:"runTimeout"`
+	RequestTimeout  time.Duration `yaml:"requestTimeout"`
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout"`
+	Todoist         struct {
 		Token                 string `yaml:"token"`
+		TokenFile             string `yaml:"tokenFile"`
+		TokenCommand          string `yaml:"tokenCommand"`
+		TokenSecret           string `yaml:"tokenSecret"`
+		APIURL                string `yaml:"apiURL"`
+		Transport             string `yaml:"transport"`
 		NextActionLabel       string `yaml:"nextActionLabel"`
 		AssignProjectLabel    bool   `yaml:"assignProjectLabel"`
 		AssignNextActionLabel bool   `yaml:"assignNextActionLabel"`
 		ParentProjectName     string `yaml:"parentProjectName"`
 		ProjectsLabelPrefix   string `yaml:"projectsLabelPrefix"`
 	} `yaml:"todoist"`
-	Jira   []JiraConfig `yaml:"jira"`
-	loaded bool
+	Jira     []JiraConfig   `yaml:"jira"`
+	Retry    RetryConfig    `yaml:"retry"`
+	Schedule ScheduleConfig `yaml:"schedule"`
+	Server   ServerConfig   `yaml:"server"`
+
+	// path is the configuration file the configuration was read from.
+	path          string
+	secretSources []SecretSource
+	// origins and problems are set when the configuration is loaded; undecoded is set if the
+	// problems prevented decoding it.
+	origins   origins
+	problems  []Problem
+	undecoded bool
+}
+
+type RetryConfig struct {
+	MaxAttempts    int           `yaml:"maxAttempts"`
+	InitialBackoff time.Duration `yaml:"initialBackoff"`
+	MaxBackoff     time.Duration `yaml:"maxBackoff"`
+}
+
+// ScheduleConfig configures when the daemon runs the processes.
+type ScheduleConfig struct {
+	TimeZone   string             `yaml:"timeZone"`
+	QuietHours []QuietHoursConfig `yaml:"quietHours"`
+	// Processes are the schedules of the processes by name, defaulting to the update interval.
+	Processes map[string]string `yaml:"processes"`
+}
+
+// ServerConfig configures the HTTP server exposing the health and the metrics of the daemon.
+type ServerConfig struct {
+	// Address is the address the server listens on, such as :9090; the server is disabled if empty.
+	Address string `yaml:"address"`
+	// ReadyIntervals is the number of scheduled intervals without a successful run after which the
+	// daemon is not ready.
+	ReadyIntervals int `yaml:"readyIntervals"`
+}
+
+type QuietHoursConfig struct {
+	Days []string `yaml:"days"`
+	From string   `yaml:"from"`
+	To   string   `yaml:"to"`
 }
 
 type JiraConfig struct {
-	Site               string              `yaml:"site"`
-	Username           string              `yaml:"username"`
-	Token              string              `yaml:"token"`
-	JQL                string              `yaml:"jql"`
-	Labels             []string            `yaml:"labels"`
-	CompletionStatuses []string            `yaml:"completionStatuses"`
-	Project            string              `yaml:"project"`
-	SyncJiraLabels     bool                `yaml:"syncJiraLabels"`
-	SyncJiraComponents bool                `yaml:"syncJiraComponents"`
-	PriorityMap        map[string][]string `yaml:"priorityMap"`
+	// Name optionally identifies the instance in conf.d fragments and environment variables.
+	Name         string `yaml:"name"`
+	Site         string `yaml:"site"`
+	Username     string `yaml:"username"`
+	Token        string `yaml:"token"`
+	TokenFile    string `yaml:"tokenFile"`
+	TokenCommand string `yaml:"tokenCommand"`
+	TokenSecret  string `yaml:"tokenSecret"`
+	// Flavor is FlavorCloud, the default, or FlavorDataCenter for Jira Server and Data Center, where
+	// the token is a personal access token unless a username is set.
+	Flavor string `yaml:"flavor"`
+	// CACertFile is a PEM bundle of certificate authorities trusted for the site besides the system
+	// ones, and ClientCertFile and ClientKeyFile are the PEM certificate and key presented to it.
+	CACertFile         string   `yaml:"caCertFile"`
+	ClientCertFile     string   `yaml:"clientCertFile"`
+	ClientKeyFile      string   `yaml:"clientKeyFile"`
+	JQL                string   `yaml:"jql"`
+	Labels             []string `yaml:"labels"`
+	CompletionStatuses []string `yaml:"completionStatuses"`
+	// CompletionTransition is the name of the Jira transition run on an issue when its task is
+	// completed in Todoist; issues are not transitioned if empty.
+	CompletionTransition string              `yaml:"completionTransition"`
+	Project              string              `yaml:"project"`
+	SyncJiraLabels       bool                `yaml:"syncJiraLabels"`
+	SyncJiraComponents   bool                `yaml:"syncJiraComponents"`
+	PriorityMap          map[string][]string `yaml:"priorityMap"`
+	// DueDateSources are the sources of the due dates of the tasks, in order of precedence: the due
+	// date of the issue, the end date of its active sprint or the earliest release date of its fix
+	// versions. Due dates are not synced if empty.
+	DueDateSources []string `yaml:"dueDateSources"`
+	// SprintField is the custom field holding the sprints of an issue, DefaultSprintField if empty.
+	SprintField string `yaml:"sprintField"`
+	// FieldMappings map the values of other fields of the issues to their tasks; only the fields
+	// they reference are fetched.
+	FieldMappings []FieldMapping `yaml:"fieldMappings"`
+	// Schedule overrides the schedule of the Jira process for this instance.
+	Schedule string `yaml:"schedule"`
 }
 
-var (
-	configuration Config
-	log           = logrus.New()
-)
+// DataCenter returns whether the instance runs Jira Server or Data Center rather than Jira Cloud.
+func (c JiraConfig) DataCenter() bool {
+	return c.Flavor == FlavorDataCenter
+}
 
-func (cfg *Config) IsTest() bool {
-	return cfg.Todoist.Token == "TEST"
+// BearerAuth returns whether requests are authenticated with the token as a personal access token
+// rather than with the username and the token.
+func (c JiraConfig) BearerAuth() bool {
+	return c.DataCenter() && c.Username == ""
 }
 
-func (cfg *Config) validate() {
-	if cfg.Todoist.Token == "" {
-		log.Fatal("Todoist API token is not set")
+// TLSConfig returns the TLS configuration of the connections to the site, or nil if neither
+// certificate authorities nor a client certificate are configured.
+func (c JiraConfig) TLSConfig() (*tls.Config, error) {
+	if c.CACertFile == "" && c.ClientCertFile == "" && c.ClientKeyFile == "" {
+		return nil, nil
 	}
-	if cfg.UpdateInterval <= 0 {
-		log.Fatal("Update interval must be greater than 0")
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if c.CACertFile != "" {
+		pool, err := loadCACerts(c.CACertFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = pool
 	}
-
-	for _, jiraCfg := range cfg.Jira {
-		for key := range jiraCfg.PriorityMap {
-			_, err := cfg.ToAPIPriority(key)
-			if err != nil {
-				log.Fatalf("Invalid key found in PriorityMap: %s. Only p1-p4 are allowed.", key)
-			}
+	if c.ClientCertFile != "" || c.ClientKeyFile != "" {
+		certificate, err := loadClientCert(c.ClientCertFile, c.ClientKeyFile)
+		if err != nil {
+			return nil, err
 		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+	return tlsConfig, nil
+}
+
+// loadCACerts returns the system certificate pool with the certificates of the PEM bundle at path.
+func loadCACerts(path string) (*x509.CertPool, error) {
+	bundle, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the CA bundle: %w", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(bundle) {
+		return nil, fmt.Errorf("no PEM certificates found in %s", path)
+	}
+	return pool, nil
+}
+
+func loadClientCert(certFile, keyFile string) (tls.Certificate, error) {
+	certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("error loading the client certificate: %w", err)
+	}
+	return certificate, nil
+}
+
+// FieldMapping maps the values of a field of the issues to labels, a priority or a line of the
+// description of their tasks; exactly one of Label, PriorityMap and Description is set.
+type FieldMapping struct {
+	// Field is the ID of the field, such as customfield_10042 or reporter, optionally followed by the
+	// path of a value within it, such as customfield_10042.child.value.
+	Field string `yaml:"field"`
+	// Label is the prefix of the labels of the values, e.g. Jira/Customer for Jira/Customer/Acme.
+	Label string `yaml:"label"`
+	// PriorityMap maps Todoist priorities to values, like JiraConfig.PriorityMap.
+	PriorityMap map[string][]string `yaml:"priorityMap"`
+	// Description is the name of the line of the description holding the values, e.g. Story points.
+	Description string `yaml:"description"`
+}
+
+// FieldID returns the ID of the field, without the path of the value.
+func (m FieldMapping) FieldID() string {
+	id, _, _ := strings.Cut(m.Field, ".")
+	return id
+}
+
+// SprintFieldID returns the custom field holding the sprints of an issue.
+func (c JiraConfig) SprintFieldID() string {
+	if c.SprintField == "" {
+		return DefaultSprintField
 	}
+	return c.SprintField
+}
+
+var (
+	provider          *Provider
+	providerMu        sync.Mutex
+	log               = logrus.New()
+	configFile        string
+	logLevelOverride  string
+	logFormatOverride string
+)
+
+func (cfg *Config) IsTest() bool {
+	return cfg.Todoist.Token == "TEST"
 }
 
 // ToAPIPriority converts a configuration priority to a Todoist API priority
@@ -87,60 +272,129 @@ func (cfg *Config) ToAPIPriority(configPriority string) (int, error) {
 	}
 }
 
-func GetConfiguration() Config {
-	if !configuration.loaded {
-		loadConfiguration()
+// RetryPolicy returns the retry policy for HTTP clients.
+func (cfg *Config) RetryPolicy() httpclient.RetryPolicy {
+	return httpclient.RetryPolicy{
+		MaxAttempts:    cfg.Retry.MaxAttempts,
+		InitialBackoff: cfg.Retry.InitialBackoff,
+		MaxBackoff:     cfg.Retry.MaxBackoff,
 	}
-	return configuration
-}
-
-func GetLogger() *logrus.Logger {
-	return log
 }
 
-func loadConfiguration() {
-	configFile := "config.yaml"
-	if _, err := os.Stat(configFile); err == nil {
-		configuration, err = readConfig(configFile)
+// GetProvider returns the provider of the configuration, loading the configuration on the first
+// call; it returns a *ProblemsError if the configuration is invalid.
+func GetProvider() (*Provider, error) {
+	providerMu.Lock()
+	defer providerMu.Unlock()
+	if provider == nil {
+		cfg, err := Load()
 		if err != nil {
-			log.Fatalf("Error reading config: %v", err)
+			return nil, err
+		}
+		if problems := cfg.Validate(); len(problems) > 0 {
+			return nil, &ProblemsError{Problems: problems}
 		}
+		configureLogger(cfg)
+		provider = NewProvider(cfg)
 	}
+	return provider, nil
+}
 
-	overrideConfigFromEnv(&configuration)
+// GetConfiguration returns the current configuration; see GetProvider.
+func GetConfiguration() (Config, error) {
+	p, err := GetProvider()
+	if err != nil {
+		return Config{}, err
+	}
+	return p.Get(), nil
+}
 
-	setDefaults(&configuration)
+func GetLogger() *logrus.Logger {
+	return log
+}
+
+// SetConfigFile sets the path of the configuration file, which takes precedence over
+// TODOIST_ASSISTANT_CONFIG and the search paths; the file must exist.
+// It must be called before GetConfiguration.
+func SetConfigFile(path string) {
+	configFile = path
+}
+
+// SetLogLevel overrides the log level set in the configuration file and in the environment.
+// It must be called before GetConfiguration.
+func SetLogLevel(level string) {
+	logLevelOverride = level
+}
 
-	configuration.validate()
+// SetLogFormat overrides the log format set in the configuration file and in the environment.
+// It must be called before GetConfiguration.
+func SetLogFormat(format string) {
+	logFormatOverride = format
+}
 
-	if configuration.LogLevel == "" {
-		configuration.LogLevel = "error"
+// Load reads the configuration; it returns an error if the configuration files cannot be read,
+// while problems with their contents are returned by Validate.
+func Load() (Config, error) {
+	path, err := findConfigFile()
+	if err != nil {
+		return Config{}, fmt.Errorf("error reading config: %w", err)
+	}
+	cfg, err := readConfig(path, os.Environ())
+	if err != nil {
+		return Config{}, fmt.Errorf("error reading config: %w", err)
 	}
 
-	switch configuration.LogLevel {
-	case "info":
-		log.SetLevel(logrus.InfoLevel)
-	case "debug":
-		log.SetLevel(logrus.DebugLevel)
-	default:
-		log.SetLevel(logrus.ErrorLevel)
+	if logLevelOverride != "" {
+		cfg.LogLevel = logLevelOverride
+	}
+	if logFormatOverride != "" {
+		cfg.LogFormat = logFormatOverride
 	}
+	setDefaults(&cfg)
+	cfg.path = path
+	return cfg, nil
+}
 
-	if configuration.Todoist.NextActionLabel == "" {
-		configuration.Todoist.NextActionLabel = "Next Action"
+// configureLogger applies the log level and format of cfg to the logger.
+func configureLogger(cfg Config) {
+	level, err := logrus.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		level = logrus.ErrorLevel
+	}
+	log.SetLevel(level)
+	if cfg.LogFormat == LogFormatJSON {
+		log.SetFormatter(&logrus.JSONFormatter{})
+	} else {
+		log.SetFormatter(&logrus.TextFormatter{})
 	}
-	configuration.loaded = true
 }
 
-func readConfig(path string) (Config, error) {
-	var cfg Config
-	file, err := os.ReadFile(path)
+// readConfig reads the configuration from the file at path, its conf.d fragments and the
+// environment; an empty path reads the configuration from the environment only.
+// Secrets are read from their providers.
+func readConfig(path string, environ []string) (Config, error) {
+	p := &problems{origins: origins{}}
+	tree, err := loadSources(path, environ, p)
 	if err != nil {
-		return cfg, err
+		return Config{}, err
 	}
-	err = yaml.Unmarshal(file, &cfg)
 
-	return cfg, err
+	checkTree(tree, reflect.TypeOf(Config{}), "", p)
+	var cfg Config
+	if len(p.list) == 0 {
+		if cfg, err = decodeTree(tree); err != nil {
+			p.list = append(p.list, Problem{Message: err.Error()})
+		}
+	}
+	cfg.origins = p.origins
+	if len(p.list) > 0 {
+		cfg.problems = p.list
+		cfg.undecoded = true
+		return cfg, nil
+	}
+	cfg.resolveSecrets(context.Background(), p)
+	cfg.problems = p.list
+	return cfg, nil
 }
 
 func setDefaults(cfg *Config) {
@@ -150,33 +404,47 @@ func setDefaults(cfg *Config) {
 	if cfg.Todoist.ProjectsLabelPrefix == "" {
 		cfg.Todoist.ProjectsLabelPrefix = "Projects"
 	}
-}
-
-func overrideConfigFromEnv(cfg *Config) {
-	if logLevel := os.Getenv("LOG_LEVEL"); logLevel != "" {
-		cfg.LogLevel = logLevel
+	if cfg.StatePath == "" {
+		cfg.StatePath = "state.json"
 	}
-	if interval := os.Getenv("UPDATE_INTERVAL"); interval != "" {
-		if val, err := strconv.Atoi(interval); err == nil {
-			cfg.UpdateInterval = val
-		}
+	if cfg.AuditPath == "" {
+		cfg.AuditPath = "audit.jsonl"
+	}
+	if cfg.RunTimeout <= 0 {
+		cfg.RunTimeout = 10 * time.Minute
+	}
+	if cfg.RequestTimeout <= 0 {
+		cfg.RequestTimeout = 30 * time.Second
+	}
+	if cfg.ShutdownTimeout <= 0 {
+		cfg.ShutdownTimeout = 5 * time.Second
+	}
+	defaultRetry := httpclient.DefaultRetryPolicy()
+	if cfg.Retry.MaxAttempts <= 0 {
+		cfg.Retry.MaxAttempts = defaultRetry.MaxAttempts
+	}
+	if cfg.Retry.InitialBackoff <= 0 {
+		cfg.Retry.InitialBackoff = defaultRetry.InitialBackoff
+	}
+	if cfg.Retry.MaxBackoff <= 0 {
+		cfg.Retry.MaxBackoff = defaultRetry.MaxBackoff
 	}
-	if token := os.Getenv("TODOIST__TOKEN"); token != "" {
-		cfg.Todoist.Token = token
+	if cfg.PlanFormat == "" {
+		cfg.PlanFormat = "text"
 	}
-	if label := os.Getenv("TODOIST__NEXT_ACTION_LABEL"); label != "" {
-		cfg.Todoist.NextActionLabel = label
+	if cfg.Todoist.Transport == "" {
+		cfg.Todoist.Transport = "rest"
 	}
-	if parentProjectName := os.Getenv("TODOIST__PARENT_PROJECT_NAME"); parentProjectName != "" {
-		cfg.Todoist.ParentProjectName = parentProjectName
+	if cfg.LogLevel == "" {
+		cfg.LogLevel = "error"
 	}
-	if prefix := os.Getenv("TODOIST__PROJECTS_LABEL_PREFIX"); prefix != "" {
-		cfg.Todoist.ProjectsLabelPrefix = prefix
+	if cfg.LogFormat == "" {
+		cfg.LogFormat = LogFormatText
 	}
-	if assignNextActionLabel := os.Getenv("TODOIST__ASSIGN_NEXT_ACTION_LABEL"); assignNextActionLabel == "true" {
-		cfg.Todoist.AssignNextActionLabel = true
+	if cfg.Server.ReadyIntervals <= 0 {
+		cfg.Server.ReadyIntervals = 3
 	}
-	if assignProjectLabel := os.Getenv("TODOIST__ASSIGN_PROJECT_LABEL"); assignProjectLabel == "true" {
-		cfg.Todoist.AssignProjectLabel = true
+	if cfg.Todoist.NextActionLabel == "" {
+		cfg.Todoist.NextActionLabel = "Next Action"
 	}
 }
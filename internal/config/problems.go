@@ -0,0 +1,119 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Problem is an error in the configuration.
+type Problem struct {
+	// Path is the path of the invalid value, e.g. jira.0.site; it is empty for problems that do not
+	// concern a single value.
+	Path string
+	// Source is where the value was set, e.g. config.yaml:12 or environment variable JIRA__0__SITE;
+	// it is empty if the value was not set.
+	Source string
+	// Message describes the problem.
+	Message string
+}
+
+func (p Problem) String() string {
+	var parts []string
+	for _, part := range []string{p.Source, p.Path, p.Message} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ": ")
+}
+
+// ProblemsError is returned when the configuration has problems.
+type ProblemsError struct {
+	Problems []Problem
+}
+
+func (e *ProblemsError) Error() string {
+	lines := []string{fmt.Sprintf("the configuration has %d problems:", len(e.Problems))}
+	for _, problem := range e.Problems {
+		lines = append(lines, "  "+problem.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// origin describes where a value of the configuration was set.
+type origin struct {
+	file string
+	line int
+	// env are the environment variables the value was read from, either through an override or
+	// through interpolation.
+	env []string
+}
+
+func (o origin) String() string {
+	if o.file == "" {
+		return "environment variable " + strings.Join(o.env, ", ")
+	}
+	return fmt.Sprintf("%s:%d", o.file, o.line)
+}
+
+// origins maps the paths of the values of the configuration to where they were set.
+type origins map[string]origin
+
+// source returns where the value at path was set or, for values that were not set, where the
+// closest parent value was set.
+func (o origins) source(path string) string {
+	for {
+		if found, ok := o[path]; ok {
+			return found.String()
+		}
+		index := strings.LastIndex(path, ".")
+		if index < 0 {
+			return ""
+		}
+		path = path[:index]
+	}
+}
+
+// replace replaces the origins of the value at dst and its children with the origins of the value
+// at src and its children in from.
+func (o origins) replace(dst string, from origins, src string) {
+	for path := range o {
+		if isPathOrChild(path, dst) {
+			delete(o, path)
+		}
+	}
+	for path, found := range from {
+		if isPathOrChild(path, src) {
+			o[joinPath(dst, strings.TrimPrefix(strings.TrimPrefix(path, src), "."))] = found
+		}
+	}
+}
+
+func isPathOrChild(path, parent string) bool {
+	return parent == "" || path == parent || strings.HasPrefix(path, parent+".")
+}
+
+func joinPath(path, key string) string {
+	switch {
+	case path == "":
+		return key
+	case key == "":
+		return path
+	default:
+		return path + "." + key
+	}
+}
+
+// problems collects the problems found in the configuration.
+type problems struct {
+	origins origins
+	list    []Problem
+}
+
+func (p *problems) add(path, format string, args ...any) {
+	p.list = append(p.list, Problem{
+		Path:    path,
+		Source:  p.origins.source(path),
+		Message: fmt.Sprintf(format, args...),
+	})
+}
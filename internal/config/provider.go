@@ -0,0 +1,140 @@
+package config
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/sirupsen/logrus"
+)
+
+// reloadDelay is how long the watcher waits for further changes before reloading, since editors
+// and Kubernetes config maps usually change files with several operations.
+const reloadDelay = 500 * time.Millisecond
+
+// Provider holds the current configuration and replaces it when the configuration is reloaded;
+// a configuration with problems is never swapped in.
+type Provider struct {
+	mu      sync.RWMutex
+	current Config
+	load    func() (Config, error)
+}
+
+// NewProvider creates a Provider whose current configuration is cfg.
+func NewProvider(cfg Config) *Provider {
+	return &Provider{current: cfg, load: Load}
+}
+
+// Get returns the current configuration.
+func (p *Provider) Get() Config {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.current
+}
+
+// Reload loads the configuration again and makes it the current one if it has no problems;
+// otherwise the current configuration is kept and the problems are returned.
+func (p *Provider) Reload() ([]Problem, error) {
+	cfg, err := p.load()
+	if err != nil {
+		return nil, err
+	}
+	if problems := cfg.Validate(); len(problems) > 0 {
+		return problems, nil
+	}
+
+	p.mu.Lock()
+	p.current = cfg
+	p.mu.Unlock()
+	configureLogger(cfg)
+	return nil, nil
+}
+
+// ReloadAndLog reloads the configuration and logs the outcome.
+func (p *Provider) ReloadAndLog(logger *logrus.Logger) {
+	problems, err := p.Reload()
+	switch {
+	case err != nil:
+		logger.WithError(err).Error("Configuration not reloaded, keeping the current configuration")
+	case len(problems) > 0:
+		for _, problem := range problems {
+			logger.Error(problem.String())
+		}
+		logger.Errorf("Configuration not reloaded because of %d problems, keeping the current configuration",
+			len(problems))
+	default:
+		logger.Info("Configuration reloaded")
+	}
+}
+
+// Watch reloads the configuration whenever the configuration file or its conf.d fragments change,
+// until ctx is done; it returns immediately if the configuration was not read from a file.
+func (p *Provider) Watch(ctx context.Context, logger *logrus.Logger) error {
+	path := p.Get().path
+	if path == "" {
+		return nil
+	}
+	path = filepath.Clean(path)
+	dir := filepath.Dir(path)
+	fragments := filepath.Join(dir, fragmentsDir)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	// Directories are watched instead of files, so that files replaced by renaming them are still
+	// watched afterwards.
+	if err = watcher.Add(dir); err != nil {
+		return err
+	}
+	if err = watcher.Add(fragments); err != nil {
+		logger.Debugf("Not watching %s: %v", fragments, err)
+	}
+
+	var reload <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if !isConfigEvent(event, path, fragments) {
+				continue
+			}
+			if event.Name == fragments && event.Has(fsnotify.Create) {
+				if err = watcher.Add(fragments); err != nil {
+					logger.Debugf("Not watching %s: %v", fragments, err)
+				}
+			}
+			logger.Debugf("Configuration changed: %s", event)
+			reload = time.After(reloadDelay)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			logger.WithError(err).Error("Error watching the configuration")
+		case <-reload:
+			reload = nil
+			p.ReloadAndLog(logger)
+		}
+	}
+}
+
+// isConfigEvent returns whether event concerns the configuration file at path, the fragments
+// directory or a fragment in it.
+func isConfigEvent(event fsnotify.Event, path, fragments string) bool {
+	name := filepath.Clean(event.Name)
+	if event.Op == fsnotify.Chmod {
+		return false
+	}
+	if name == path || name == fragments {
+		return true
+	}
+	return filepath.Dir(name) == fragments && filepath.Ext(name) == ".yaml"
+}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const validConfig = `todoist:
+  token: secret
+updateInterval: 1
+`
+
+func TestProviderReload(t *testing.T) {
+	testCases := []struct {
+		name             string
+		content          string
+		expectedInterval int
+		expectedProblems int
+	}{
+		{
+			name:             "Valid configuration is swapped in",
+			content:          "todoist:\n  token: secret\nupdateInterval: 2\n",
+			expectedInterval: 2,
+		},
+		{
+			name:             "Invalid configuration is ignored",
+			content:          "todoist:\n  token: secret\nupdate_interval: 2\n",
+			expectedInterval: 1,
+			expectedProblems: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			writeFile(t, path, validConfig)
+			configFile = path
+			defer func() { configFile = "" }()
+
+			cfg, err := Load()
+			require.NoError(t, err)
+			provider := NewProvider(cfg)
+
+			writeFile(t, path, tc.content)
+			problems, err := provider.Reload()
+			require.NoError(t, err)
+			assert.Len(t, problems, tc.expectedProblems)
+			assert.Equal(t, tc.expectedInterval, provider.Get().UpdateInterval)
+		})
+	}
+}
+
+func TestProviderWatch(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	writeFile(t, path, validConfig)
+	configFile = path
+	defer func() { configFile = "" }()
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	provider := NewProvider(cfg)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- provider.Watch(ctx, logrus.New())
+	}()
+	defer func() {
+		cancel()
+		assert.NoError(t, <-done)
+	}()
+
+	// the watcher may not be ready yet, so the fragment is written until it is picked up
+	assert.Eventually(t, func() bool {
+		writeFile(t, filepath.Join(dir, "conf.d", "interval.yaml"), "updateInterval: 3\n")
+		return provider.Get().UpdateInterval == 3
+	}, 5*time.Second, 2*reloadDelay)
+}
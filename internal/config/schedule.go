@@ -0,0 +1,68 @@
+package config
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/fabiocorneti/todoist-assistant/internal/schedule"
+)
+
+const (
+	// ProcessJira is the name of the process creating tasks from Jira issues.
+	ProcessJira = "jira"
+	// ProcessProjects is the name of the process assigning project and next action labels.
+	ProcessProjects = "projects"
+)
+
+// Location returns the time zone of the schedules and quiet hours; it is the local time zone if
+// not set.
+func (cfg *Config) Location() (*time.Location, error) {
+	if cfg.Schedule.TimeZone == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(cfg.Schedule.TimeZone)
+}
+
+// QuietHours returns the time windows in which the processes do not run.
+func (cfg *Config) QuietHours() ([]schedule.QuietHours, error) {
+	quietHours := make([]schedule.QuietHours, 0, len(cfg.Schedule.QuietHours))
+	for _, window := range cfg.Schedule.QuietHours {
+		parsed, err := schedule.ParseQuietHours(window.Days, window.From, window.To)
+		if err != nil {
+			return nil, err
+		}
+		quietHours = append(quietHours, parsed)
+	}
+	return quietHours, nil
+}
+
+// Jobs returns the jobs run by the daemon with their schedules: the projects process and the Jira
+// process for each Jira instance.
+func (cfg *Config) Jobs() []schedule.Job {
+	jobs := []schedule.Job{{Name: ProcessProjects, Spec: cfg.processSchedule(ProcessProjects)}}
+	for i, jiraCfg := range cfg.Jira {
+		spec := jiraCfg.Schedule
+		if spec == "" {
+			spec = cfg.processSchedule(ProcessJira)
+		}
+		jobs = append(jobs, schedule.Job{Name: cfg.JiraJob(i), Spec: spec})
+	}
+	return jobs
+}
+
+// JiraJob returns the name of the job processing the Jira instance at index, e.g. jira/work for
+// an instance named work.
+func (cfg *Config) JiraJob(index int) string {
+	name := cfg.Jira[index].Name
+	if name == "" {
+		name = strconv.Itoa(index)
+	}
+	return ProcessJira + "/" + name
+}
+
+func (cfg *Config) processSchedule(process string) string {
+	if spec := cfg.Schedule.Processes[process]; spec != "" {
+		return spec
+	}
+	return strconv.Itoa(cfg.UpdateInterval) + "m"
+}
@@ -0,0 +1,236 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	secretProviderFile    = "file"
+	secretProviderCommand = "command"
+
+	secretCommandTimeout = 30 * time.Second
+)
+
+// SecretProvider reads secrets kept outside of the configuration.
+type SecretProvider interface {
+	// Secret returns the secret identified by ref.
+	Secret(ctx context.Context, ref string) (string, error)
+}
+
+var (
+	secretProvidersMu sync.RWMutex
+	// secretProviders are the registered providers by name. A secret is read from a provider with a
+	// reference such as file:/run/secrets/todoist in a field like tokenSecret; the file and command
+	// providers can also be used through the fields with the matching suffix, e.g. tokenFile.
+	secretProviders = map[string]SecretProvider{}
+)
+
+func init() {
+	builtin := map[string]SecretProvider{
+		secretProviderFile:    fileSecretProvider{},
+		secretProviderCommand: commandSecretProvider{},
+	}
+	for name, provider := range builtin {
+		if err := RegisterSecretProvider(name, provider); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterSecretProvider makes a provider available to the configuration under name, e.g. vault for
+// references such as vault:kv/todoist; it must be called before the configuration is loaded. Names
+// must be unique and cannot contain a colon.
+func RegisterSecretProvider(name string, provider SecretProvider) error {
+	if name == "" || strings.Contains(name, ":") {
+		return fmt.Errorf("invalid secret provider name %q", name)
+	}
+	if provider == nil {
+		return fmt.Errorf("secret provider %s is nil", name)
+	}
+	secretProvidersMu.Lock()
+	defer secretProvidersMu.Unlock()
+	if _, exists := secretProviders[name]; exists {
+		return fmt.Errorf("secret provider %s is already registered", name)
+	}
+	secretProviders[name] = provider
+	return nil
+}
+
+// secretProvider returns the provider registered under name.
+func secretProvider(name string) (SecretProvider, bool) {
+	secretProvidersMu.RLock()
+	defer secretProvidersMu.RUnlock()
+	provider, exists := secretProviders[name]
+	return provider, exists
+}
+
+// fileSecretProvider reads a secret from a file, e.g. a Docker or Kubernetes secret.
+type fileSecretProvider struct{}
+
+func (fileSecretProvider) Secret(_ context.Context, path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// commandSecretProvider runs a shell command, e.g. pass show todoist, and reads the secret from the
+// first line of its output.
+type commandSecretProvider struct{}
+
+func (commandSecretProvider) Secret(ctx context.Context, command string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, secretCommandTimeout)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("%w: %s", err, message)
+		}
+		return "", err
+	}
+	secret, _, _ := strings.Cut(string(output), "\n")
+	return strings.TrimSpace(secret), nil
+}
+
+// SecretSource describes where a secret in the configuration was read from.
+type SecretSource struct {
+	// Name is the path of the secret in the configuration, e.g. todoist.token.
+	Name string
+	// Source describes the source without revealing the secret, e.g. file /run/secrets/todoist.
+	Source string
+}
+
+// secretRefKeys are the suffixes of the keys referencing a secret by provider; secretRefKey is the
+// suffix of the key referencing a secret in any provider.
+var secretRefKeys = map[string]string{
+	secretProviderFile:    "File",
+	secretProviderCommand: "Command",
+}
+
+const secretRefKey = "Secret"
+
+// secretField is a secret in the configuration with the references to the providers it can be
+// read from: refs by provider, and secret for a reference such as vault:kv/todoist.
+type secretField struct {
+	name   string
+	path   string
+	value  *string
+	refs   map[string]string
+	secret string
+}
+
+func (cfg *Config) secretFields() []secretField {
+	fields := []secretField{{
+		name:  "todoist.token",
+		path:  "todoist.token",
+		value: &cfg.Todoist.Token,
+		refs: map[string]string{
+			secretProviderFile:    cfg.Todoist.TokenFile,
+			secretProviderCommand: cfg.Todoist.TokenCommand,
+		},
+		secret: cfg.Todoist.TokenSecret,
+	}}
+	for i := range cfg.Jira {
+		jiraCfg := &cfg.Jira[i]
+		instance := jiraCfg.Name
+		if instance == "" {
+			instance = strconv.Itoa(i)
+		}
+		fields = append(fields, secretField{
+			name:  "jira." + instance + ".token",
+			path:  "jira." + strconv.Itoa(i) + ".token",
+			value: &jiraCfg.Token,
+			refs: map[string]string{
+				secretProviderFile:    jiraCfg.TokenFile,
+				secretProviderCommand: jiraCfg.TokenCommand,
+			},
+			secret: jiraCfg.TokenSecret,
+		})
+	}
+	return fields
+}
+
+// resolveSecrets reads the secrets set through a provider and records the source of every secret,
+// adding the secrets that cannot be read to p.
+func (cfg *Config) resolveSecrets(ctx context.Context, p *problems) {
+	cfg.secretSources = nil
+	for _, field := range cfg.secretFields() {
+		source, path, err := field.resolve(ctx, p.origins)
+		if err != nil {
+			p.add(path, "%v", err)
+			continue
+		}
+		if source != "" {
+			cfg.secretSources = append(cfg.secretSources, SecretSource{Name: field.name, Source: source})
+		}
+	}
+}
+
+// resolve reads the secret if it is set through a provider and returns its source; on errors, it
+// returns the path of the value causing them.
+func (field secretField) resolve(ctx context.Context, o origins) (string, string, error) {
+	provider, ref, path := "", "", ""
+	for _, name := range []string{secretProviderFile, secretProviderCommand, ""} {
+		candidate, candidatePath := field.refs[name], field.path+secretRefKeys[name]
+		if name == "" {
+			candidate, candidatePath = field.secret, field.path+secretRefKey
+		}
+		if candidate == "" {
+			continue
+		}
+		if path != "" || *field.value != "" {
+			return "", candidatePath,
+				errors.New("only one of the secret and its file, command or secret reference can be set")
+		}
+		provider, ref, path = name, candidate, candidatePath
+	}
+
+	if path == "" {
+		if *field.value == "" {
+			return "", "", nil
+		}
+		if found := o[field.path]; len(found.env) > 0 {
+			return "environment variable " + strings.Join(found.env, ", "), "", nil
+		}
+		return "configuration file " + o[field.path].file, "", nil
+	}
+
+	if provider == "" {
+		var found bool
+		if provider, ref, found = strings.Cut(ref, ":"); !found || ref == "" {
+			return "", path, fmt.Errorf("%q is not a reference such as file:/run/secrets/token", field.secret)
+		}
+	}
+	secretProvider, exists := secretProvider(provider)
+	if !exists {
+		return "", path, fmt.Errorf("unknown secret provider %s", provider)
+	}
+	secret, err := secretProvider.Secret(ctx, ref)
+	if err != nil {
+		return "", path, fmt.Errorf("reading the secret from %s %s: %w", provider, ref, err)
+	}
+	if secret == "" {
+		return "", path, fmt.Errorf("the secret read from %s %s is empty", provider, ref)
+	}
+	*field.value = secret
+	return provider + " " + ref, "", nil
+}
+
+// SecretSources returns where each secret in the configuration was read from.
+func (cfg *Config) SecretSources() []SecretSource {
+	return cfg.secretSources
+}
@@ -0,0 +1,162 @@
+package config
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type staticSecretProvider map[string]string
+
+func (p staticSecretProvider) Secret(_ context.Context, ref string) (string, error) {
+	return p[ref], nil
+}
+
+func TestRegisterSecretProvider(t *testing.T) {
+	t.Cleanup(func() {
+		secretProvidersMu.Lock()
+		defer secretProvidersMu.Unlock()
+		delete(secretProviders, "registered")
+	})
+
+	require.NoError(t, RegisterSecretProvider("registered", staticSecretProvider{}))
+	assert.EqualError(t, RegisterSecretProvider("registered", staticSecretProvider{}),
+		"secret provider registered is already registered")
+	assert.EqualError(t, RegisterSecretProvider("file", staticSecretProvider{}),
+		"secret provider file is already registered")
+	assert.EqualError(t, RegisterSecretProvider("vault:kv", staticSecretProvider{}),
+		`invalid secret provider name "vault:kv"`)
+	assert.EqualError(t, RegisterSecretProvider("", staticSecretProvider{}), `invalid secret provider name ""`)
+	assert.EqualError(t, RegisterSecretProvider("missing", nil), "secret provider missing is nil")
+}
+
+func TestResolveSecrets(t *testing.T) {
+	dir := t.TempDir()
+	tokenFile := filepath.Join(dir, "token")
+	writeFile(t, tokenFile, "file-secret\n")
+	require.NoError(t, RegisterSecretProvider("static", staticSecretProvider{"todoist": "static-secret"}))
+	t.Cleanup(func() {
+		secretProvidersMu.Lock()
+		defer secretProvidersMu.Unlock()
+		delete(secretProviders, "static")
+	})
+
+	testCases := []struct {
+		name            string
+		cfg             func(cfg *Config)
+		origins         origins
+		expectedTokens  []string
+		expectedSources []SecretSource
+		expectedError   string
+	}{
+		{
+			name: "Plaintext secrets",
+			cfg: func(cfg *Config) {
+				cfg.Todoist.Token = "todoist"
+				cfg.Jira = []JiraConfig{{Name: "work", Token: "jira"}, {}}
+			},
+			origins: origins{
+				"todoist.token": {file: "config.yaml", line: 2},
+				"jira.0.token":  {file: "config.yaml", line: 5, env: []string{"JIRA_TOKEN"}},
+			},
+			expectedTokens: []string{"todoist", "jira", ""},
+			expectedSources: []SecretSource{
+				{Name: "todoist.token", Source: "configuration file config.yaml"},
+				{Name: "jira.work.token", Source: "environment variable JIRA_TOKEN"},
+			},
+		},
+		{
+			name: "Secrets from providers",
+			cfg: func(cfg *Config) {
+				cfg.Todoist.TokenFile = tokenFile
+				cfg.Jira = []JiraConfig{{TokenCommand: "printf 'command-secret\\nmetadata\\n'"}}
+			},
+			expectedTokens: []string{"file-secret", "command-secret"},
+			expectedSources: []SecretSource{
+				{Name: "todoist.token", Source: "file " + tokenFile},
+				{Name: "jira.0.token", Source: "command printf 'command-secret\\nmetadata\\n'"},
+			},
+		},
+		{
+			name: "Secrets from references",
+			cfg: func(cfg *Config) {
+				cfg.Todoist.TokenSecret = "static:todoist"
+				cfg.Jira = []JiraConfig{{TokenSecret: "file:" + tokenFile}}
+			},
+			expectedTokens: []string{"static-secret", "file-secret"},
+			expectedSources: []SecretSource{
+				{Name: "todoist.token", Source: "static todoist"},
+				{Name: "jira.0.token", Source: "file " + tokenFile},
+			},
+		},
+		{
+			name: "Unknown provider",
+			cfg: func(cfg *Config) {
+				cfg.Todoist.TokenSecret = "vault:kv/todoist"
+			},
+			expectedError: "todoist.tokenSecret: unknown secret provider vault",
+		},
+		{
+			name: "Invalid reference",
+			cfg: func(cfg *Config) {
+				cfg.Jira = []JiraConfig{{TokenSecret: "token"}}
+			},
+			expectedError: `jira.0.tokenSecret: "token" is not a reference such as file:/run/secrets/token`,
+		},
+		{
+			name: "Conflicting references",
+			cfg: func(cfg *Config) {
+				cfg.Jira = []JiraConfig{{TokenCommand: "true", TokenSecret: "static:todoist"}}
+			},
+			expectedError: "jira.0.tokenSecret: only one of the secret and its file, command or secret reference can be set",
+		},
+		{
+			name: "Conflicting sources",
+			cfg: func(cfg *Config) {
+				cfg.Todoist.Token = "todoist"
+				cfg.Todoist.TokenFile = tokenFile
+			},
+			expectedError: "todoist.tokenFile: only one of the secret and its file, command or secret reference can be set",
+		},
+		{
+			name: "Failing providers",
+			cfg: func(cfg *Config) {
+				cfg.Todoist.TokenFile = filepath.Join(dir, "missing")
+				cfg.Jira = []JiraConfig{{TokenCommand: "echo failed >&2; exit 1"}}
+			},
+			expectedError: "jira.0.tokenCommand: reading the secret from command echo failed >&2; exit 1: exit status 1: failed",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := Config{}
+			tc.cfg(&cfg)
+
+			p := &problems{origins: tc.origins}
+			if p.origins == nil {
+				p.origins = origins{}
+			}
+			cfg.resolveSecrets(context.Background(), p)
+			if tc.expectedError != "" {
+				var messages []string
+				for _, problem := range p.list {
+					messages = append(messages, problem.Path+": "+problem.Message)
+				}
+				assert.Contains(t, messages, tc.expectedError)
+				return
+			}
+			assert.Empty(t, p.list)
+
+			tokens := []string{cfg.Todoist.Token}
+			for _, jiraCfg := range cfg.Jira {
+				tokens = append(tokens, jiraCfg.Token)
+			}
+			assert.Equal(t, tc.expectedTokens, tokens)
+			assert.Equal(t, tc.expectedSources, cfg.SecretSources())
+		})
+	}
+}
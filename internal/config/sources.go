@@ -0,0 +1,548 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// ConfigFileEnv is the environment variable setting the path of the configuration file.
+	ConfigFileEnv = "TODOIST_ASSISTANT_CONFIG"
+
+	appName           = "todoist-assistant"
+	defaultConfigFile = "config.yaml"
+	fragmentsDir      = "conf.d"
+	envPrefix         = "TODOIST_ASSISTANT_"
+	envSeparator      = "__"
+	nameKey           = "name"
+)
+
+// legacyEnv are the environment variables overriding the configuration without envPrefix, kept
+// from the versions that only supported these.
+var legacyEnv = map[string]bool{
+	"LOG_LEVEL":                         true,
+	"UPDATE_INTERVAL":                   true,
+	"TODOIST__TOKEN":                    true,
+	"TODOIST__NEXT_ACTION_LABEL":        true,
+	"TODOIST__PARENT_PROJECT_NAME":      true,
+	"TODOIST__PROJECTS_LABEL_PREFIX":    true,
+	"TODOIST__ASSIGN_NEXT_ACTION_LABEL": true,
+	"TODOIST__ASSIGN_PROJECT_LABEL":     true,
+}
+
+var (
+	interpolationPattern = regexp.MustCompile(`\$\$|\$\{[A-Za-z_][A-Za-z0-9_]*\}`)
+	durationType         = reflect.TypeOf(time.Duration(0))
+)
+
+// searchPaths returns the paths where the configuration file is looked up when it is not set
+// explicitly, in order of precedence.
+func searchPaths() []string {
+	paths := []string{defaultConfigFile}
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			configHome = filepath.Join(home, ".config")
+		}
+	}
+	if configHome != "" {
+		paths = append(paths, filepath.Join(configHome, appName, defaultConfigFile))
+	}
+	return append(paths, filepath.Join("/etc", appName, defaultConfigFile))
+}
+
+// findConfigFile returns the path set with SetConfigFile or ConfigFileEnv, which must exist, or
+// the first existing file in searchPaths; it returns an empty path if there is no file.
+func findConfigFile() (string, error) {
+	path := configFile
+	if path == "" {
+		path = os.Getenv(ConfigFileEnv)
+	}
+	if path != "" {
+		if _, err := os.Stat(path); err != nil {
+			return "", err
+		}
+		return path, nil
+	}
+	for _, candidate := range searchPaths() {
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+	}
+	return "", nil
+}
+
+// loadSources merges the configuration file, the *.yaml fragments in the conf.d directory next to
+// it in lexical order and the environment variables into a single tree; ${VAR} references in the
+// files are replaced with the value of the environment variable VAR.
+//
+// Errors reading the files are returned, while problems with their contents are added to p together
+// with the origins of the values.
+func loadSources(path string, environ []string, p *problems) (map[string]any, error) {
+	tree := map[string]any{}
+	if path != "" {
+		files := []string{path}
+		fragments, err := filepath.Glob(filepath.Join(filepath.Dir(path), fragmentsDir, "*.yaml"))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(fragments)
+		files = append(files, fragments...)
+
+		for _, file := range files {
+			source, fileOrigins, err := readYAML(file)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", file, err)
+			}
+			tree = mergeValues(tree, source, "", "", p.origins, fileOrigins).(map[string]any)
+		}
+	}
+
+	interpolate(tree, "", envLookup(environ), p)
+	applyEnv(tree, environ, p)
+	return tree, nil
+}
+
+func envLookup(environ []string) func(name string) (string, bool) {
+	values := map[string]string{}
+	for _, variable := range environ {
+		if name, value, found := strings.Cut(variable, "="); found {
+			values[name] = value
+		}
+	}
+	return func(name string) (string, bool) {
+		value, ok := values[name]
+		return value, ok
+	}
+}
+
+// interpolate replaces ${VAR} references in the strings of node with the value of the environment
+// variable VAR, recording their origin; $$ is replaced with $.
+func interpolate(node any, path string, lookup func(string) (string, bool), p *problems) any {
+	switch value := node.(type) {
+	case map[string]any:
+		for key, child := range value {
+			value[key] = interpolate(child, joinPath(path, key), lookup, p)
+		}
+		return value
+	case []any:
+		for i, child := range value {
+			value[i] = interpolate(child, joinPath(path, strconv.Itoa(i)), lookup, p)
+		}
+		return value
+	case string:
+		var names []string
+		result := interpolationPattern.ReplaceAllStringFunc(value, func(match string) string {
+			if match == "$$" {
+				return "$"
+			}
+			name := match[2 : len(match)-1]
+			variable, ok := lookup(name)
+			if !ok {
+				p.add(path, "environment variable %s is not set", name)
+			}
+			names = append(names, name)
+			return variable
+		})
+		if len(names) > 0 {
+			found := p.origins[path]
+			found.env = names
+			p.origins[path] = found
+		}
+		return result
+	default:
+		return node
+	}
+}
+
+// readYAML reads a YAML file and the origins of its values.
+func readYAML(path string) (map[string]any, origins, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var document yaml.Node
+	if err = yaml.Unmarshal(data, &document); err != nil {
+		return nil, nil, err
+	}
+	tree := map[string]any{}
+	fileOrigins := origins{}
+	if len(document.Content) == 0 {
+		return tree, fileOrigins, nil
+	}
+	if err = document.Content[0].Decode(&tree); err != nil {
+		return nil, nil, err
+	}
+	recordOrigins(document.Content[0], "", path, fileOrigins)
+	return tree, fileOrigins, nil
+}
+
+func recordOrigins(node *yaml.Node, path, file string, o origins) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			child := joinPath(path, key.Value)
+			o[child] = origin{file: file, line: key.Line}
+			recordOrigins(value, child, file, o)
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			child := joinPath(path, strconv.Itoa(i))
+			o[child] = origin{file: file, line: item.Line}
+			recordOrigins(item, child, file, o)
+		}
+	}
+}
+
+// mergeValues merges src into dst: maps are merged key by key, lists whose items all have a name
+// are merged item by item matching names, and any other value in src replaces the one in dst.
+// The origins of the values from src, in srcOrigins, are copied to dstOrigins.
+func mergeValues(dst, src any, dstPath, srcPath string, dstOrigins, srcOrigins origins) any {
+	switch srcValue := src.(type) {
+	case map[string]any:
+		dstMap, ok := dst.(map[string]any)
+		if !ok {
+			dstOrigins.replace(dstPath, srcOrigins, srcPath)
+			return srcValue
+		}
+		if found, ok := srcOrigins[srcPath]; ok {
+			dstOrigins[dstPath] = found
+		}
+		for key, value := range srcValue {
+			dstMap[key] = mergeValues(dstMap[key], value, joinPath(dstPath, key), joinPath(srcPath, key),
+				dstOrigins, srcOrigins)
+		}
+		return dstMap
+	case []any:
+		dstList, ok := dst.([]any)
+		if !ok || !allNamed(srcValue) {
+			dstOrigins.replace(dstPath, srcOrigins, srcPath)
+			return srcValue
+		}
+		for i, item := range srcValue {
+			name := item.(map[string]any)[nameKey]
+			itemPath := joinPath(srcPath, strconv.Itoa(i))
+			index := indexOfName(dstList, func(other string) bool { return other == name })
+			if index < 0 {
+				dstList = append(dstList, item)
+				dstOrigins.replace(joinPath(dstPath, strconv.Itoa(len(dstList)-1)), srcOrigins, itemPath)
+				continue
+			}
+			dstList[index] = mergeValues(dstList[index], item, joinPath(dstPath, strconv.Itoa(index)), itemPath,
+				dstOrigins, srcOrigins)
+		}
+		return dstList
+	default:
+		dstOrigins.replace(dstPath, srcOrigins, srcPath)
+		return src
+	}
+}
+
+func allNamed(list []any) bool {
+	for _, item := range list {
+		m, ok := item.(map[string]any)
+		if !ok {
+			return false
+		}
+		if _, ok = m[nameKey].(string); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func indexOfName(list []any, match func(name string) bool) int {
+	for i, item := range list {
+		if m, ok := item.(map[string]any); ok {
+			if name, ok := m[nameKey].(string); ok && match(name) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// applyEnv overrides the tree with the environment variables whose name is envPrefix followed by
+// a path of fields of Config, e.g. TODOIST_ASSISTANT_TODOIST__NEXT_ACTION_LABEL; list items are
+// selected by index or by name, e.g. TODOIST_ASSISTANT_JIRA__WORK__TOKEN. The legacyEnv variables
+// are applied first, so that the prefixed ones take precedence; other variables are ignored.
+func applyEnv(tree map[string]any, environ []string, p *problems) {
+	configType := reflect.TypeOf(Config{})
+	var legacy, prefixed []string
+	for _, variable := range environ {
+		name, _, _ := strings.Cut(variable, "=")
+		switch {
+		case strings.HasPrefix(name, envPrefix):
+			prefixed = append(prefixed, variable)
+		case legacyEnv[name]:
+			legacy = append(legacy, variable)
+		}
+	}
+	sort.Strings(legacy)
+	sort.Strings(prefixed)
+
+	for _, variable := range append(legacy, prefixed...) {
+		name, value, found := strings.Cut(variable, "=")
+		if !found || value == "" {
+			continue
+		}
+		segments := strings.Split(strings.TrimPrefix(name, envPrefix), envSeparator)
+		field, ok := fieldByEnvName(configType, segments[0])
+		if !ok || (len(segments) == 1 && !isLeaf(field.Type)) {
+			continue
+		}
+		_, fieldPath, err := setEnvValue(tree, configType, segments, value, "")
+		source := origin{env: []string{name}}
+		if err != nil {
+			p.list = append(p.list, Problem{Source: source.String(), Message: err.Error()})
+			continue
+		}
+		p.origins.replace(fieldPath, origins{fieldPath: source}, fieldPath)
+	}
+}
+
+// setEnvValue sets the value at the path described by segments in node, whose Go type is t, and
+// returns the updated node and the path of the field.
+func setEnvValue(node any, t reflect.Type, segments []string, value, path string) (any, string, error) {
+	if len(segments) == 0 {
+		if !isLeaf(t) {
+			return nil, "", errors.New("nested fields must be set individually")
+		}
+		return envValue(t, value), path, nil
+	}
+	segment := segments[0]
+
+	switch t.Kind() {
+	case reflect.Struct:
+		field, ok := fieldByEnvName(t, segment)
+		if !ok {
+			return nil, "", fmt.Errorf("unknown field %s", segment)
+		}
+		m, _ := node.(map[string]any)
+		if m == nil {
+			m = map[string]any{}
+		}
+		key := yamlName(field)
+		child, fieldPath, err := setEnvValue(m[key], field.Type, segments[1:], value, joinPath(path, key))
+		if err != nil {
+			return nil, "", err
+		}
+		m[key] = child
+		return m, fieldPath, nil
+	case reflect.Map:
+		m, _ := node.(map[string]any)
+		if m == nil {
+			m = map[string]any{}
+		}
+		key := strings.ToLower(segment)
+		child, fieldPath, err := setEnvValue(m[key], t.Elem(), segments[1:], value, joinPath(path, key))
+		if err != nil {
+			return nil, "", err
+		}
+		m[key] = child
+		return m, fieldPath, nil
+	case reflect.Slice:
+		list, _ := node.([]any)
+		index, err := strconv.Atoi(segment)
+		if err != nil {
+			index = indexOfName(list, func(name string) bool { return envName(name) == segment })
+			if index < 0 {
+				list = append(list, map[string]any{nameKey: strings.ToLower(segment)})
+				index = len(list) - 1
+			}
+		}
+		if index < 0 || index > len(list) {
+			return nil, "", fmt.Errorf("index %d is out of range", index)
+		}
+		if index == len(list) {
+			list = append(list, nil)
+		}
+		child, fieldPath, err := setEnvValue(list[index], t.Elem(), segments[1:], value,
+			joinPath(path, strconv.Itoa(index)))
+		if err != nil {
+			return nil, "", err
+		}
+		list[index] = child
+		return list, fieldPath, nil
+	default:
+		return nil, "", fmt.Errorf("%s does not have fields", segment)
+	}
+}
+
+// envValue converts the value of an environment variable for a field of type t: strings are
+// kept as they are, lists can be comma separated and other values are parsed as YAML.
+func envValue(t reflect.Type, value string) any {
+	switch {
+	case t.Kind() == reflect.String:
+		return value
+	case t.Kind() == reflect.Slice && !strings.HasPrefix(value, "["):
+		var items []any
+		for _, item := range strings.Split(value, ",") {
+			items = append(items, strings.TrimSpace(item))
+		}
+		return items
+	}
+	var parsed any
+	if err := yaml.Unmarshal([]byte(value), &parsed); err != nil || parsed == nil {
+		return value
+	}
+	return parsed
+}
+
+// isLeaf returns whether a field of type t can be set by a single environment variable.
+func isLeaf(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Struct, reflect.Map:
+		return false
+	case reflect.Slice:
+		return isLeaf(t.Elem())
+	default:
+		return true
+	}
+}
+
+func fieldByEnvName(t reflect.Type, segment string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.IsExported() && envName(yamlName(field)) == segment {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func yamlName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	if name == "" {
+		return strings.ToLower(field.Name)
+	}
+	return name
+}
+
+// envName converts a YAML key or an item name to its environment variable form, e.g.
+// nextActionLabel to NEXT_ACTION_LABEL.
+func envName(name string) string {
+	var b strings.Builder
+	previous := '_'
+	for _, r := range name {
+		switch {
+		case unicode.IsUpper(r) && unicode.IsLower(previous):
+			b.WriteRune('_')
+			b.WriteRune(r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(unicode.ToUpper(r))
+		default:
+			r = '_'
+			b.WriteRune(r)
+		}
+		previous = r
+	}
+	return b.String()
+}
+
+// checkTree checks that the values in node, whose Go type is t, can be decoded; unlike YAML
+// decoding, it reports every unknown key and invalid value with its origin.
+func checkTree(node any, t reflect.Type, path string, p *problems) {
+	if node == nil {
+		return
+	}
+	switch {
+	case t == durationType:
+		if value, ok := node.(string); !ok {
+			p.add(path, "expected a duration such as 30s, got %v", node)
+		} else if _, err := time.ParseDuration(value); err != nil {
+			p.add(path, "expected a duration such as 30s, got %q", value)
+		}
+		return
+	case t.Kind() == reflect.Struct:
+		m, ok := node.(map[string]any)
+		if !ok {
+			p.add(path, "expected a map, got %v", node)
+			return
+		}
+		fields := map[string]reflect.StructField{}
+		for i := 0; i < t.NumField(); i++ {
+			if field := t.Field(i); field.IsExported() {
+				fields[yamlName(field)] = field
+			}
+		}
+		for _, key := range sortedKeys(m) {
+			field, ok := fields[key]
+			if !ok {
+				p.add(joinPath(path, key), "unknown field")
+				continue
+			}
+			checkTree(m[key], field.Type, joinPath(path, key), p)
+		}
+	case t.Kind() == reflect.Map:
+		m, ok := node.(map[string]any)
+		if !ok {
+			p.add(path, "expected a map, got %v", node)
+			return
+		}
+		for _, key := range sortedKeys(m) {
+			checkTree(m[key], t.Elem(), joinPath(path, key), p)
+		}
+	case t.Kind() == reflect.Slice:
+		list, ok := node.([]any)
+		if !ok {
+			p.add(path, "expected a list, got %v", node)
+			return
+		}
+		for i, item := range list {
+			checkTree(item, t.Elem(), joinPath(path, strconv.Itoa(i)), p)
+		}
+	case t.Kind() == reflect.Int:
+		if _, ok := node.(int); !ok {
+			p.add(path, "expected an integer, got %v", node)
+		}
+	case t.Kind() == reflect.Bool:
+		if _, ok := node.(bool); !ok {
+			p.add(path, "expected true or false, got %v", node)
+		}
+	case t.Kind() == reflect.String:
+		switch node.(type) {
+		case map[string]any, []any:
+			p.add(path, "expected a string, got a %s", describe(node))
+		}
+	}
+}
+
+func describe(node any) string {
+	if _, ok := node.([]any); ok {
+		return "list"
+	}
+	return "map"
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// decodeTree decodes the merged configuration tree into a Config.
+func decodeTree(tree map[string]any) (Config, error) {
+	var cfg Config
+	data, err := yaml.Marshal(tree)
+	if err != nil {
+		return cfg, err
+	}
+	err = yaml.Unmarshal(data, &cfg)
+	return cfg, err
+}
@@ -0,0 +1,264 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o700))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+}
+
+func TestReadConfigLayers(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	writeFile(t, path, `
+updateInterval: 10
+todoist:
+  token: file-token
+  nextActionLabel: Next
+jira:
+  - name: work
+    site: https://work.atlassian.net
+    token: work-token
+    labels: [Work]
+  - name: oss
+    site: https://oss.atlassian.net
+`)
+	writeFile(t, filepath.Join(dir, "conf.d", "10-jira.yaml"), `
+jira:
+  - name: oss
+    token: oss-token
+  - name: home
+    site: https://home.atlassian.net
+`)
+	writeFile(t, filepath.Join(dir, "conf.d", "20-interval.yaml"), "updateInterval: 15\n")
+	writeFile(t, filepath.Join(dir, "conf.d", "ignored.yml"), "updateInterval: 20\n")
+
+	environ := []string{
+		"HOME=/home/user",
+		"UPDATE_INTERVAL=30",
+		"TODOIST_ASSISTANT_RUN_TIMEOUT=2m",
+		"TODOIST_ASSISTANT_DRY_RUN=true",
+		"TODOIST__TOKEN=env-token",
+		"TODOIST__ASSIGN_PROJECT_LABEL=true",
+		"TODOIST_ASSISTANT_JIRA__0__LABELS=Work, Jira",
+		"TODOIST_ASSISTANT_JIRA__OSS__JQL=project = OSS",
+		"TODOIST_ASSISTANT_JIRA__3__SITE=https://new.atlassian.net",
+		"TODOIST_ASSISTANT_JIRA__HOME__PRIORITY_MAP__P1=Highest,High",
+		"TODOIST_ASSISTANT_RETRY__MAX_ATTEMPTS=2",
+		"TODOIST__PARENT_PROJECT_NAME=",
+	}
+
+	cfg, err := readConfig(path, environ)
+	require.NoError(t, err)
+	assert.Empty(t, cfg.problems)
+	assert.Equal(t, "environment variable TODOIST__TOKEN", cfg.origins.source("todoist.token"))
+	assert.Equal(t, "environment variable TODOIST_ASSISTANT_JIRA__OSS__JQL", cfg.origins.source("jira.1.jql"))
+	assert.Equal(t, path+":9", cfg.origins.source("jira.0.token"))
+	assert.Equal(t, filepath.Join(dir, "conf.d", "10-jira.yaml")+":4", cfg.origins.source("jira.1.token"))
+	assert.Equal(t, filepath.Join(dir, "conf.d", "10-jira.yaml")+":6", cfg.origins.source("jira.2.site"))
+	assert.Equal(t, filepath.Join(dir, "conf.d", "10-jira.yaml")+":5", cfg.origins.source("jira.2.jql"))
+
+	assert.Equal(t, 30, cfg.UpdateInterval)
+	assert.Equal(t, 2*time.Minute, cfg.RunTimeout)
+	assert.True(t, cfg.DryRun)
+	assert.Equal(t, "env-token", cfg.Todoist.Token)
+	assert.Equal(t, "Next", cfg.Todoist.NextActionLabel)
+	assert.True(t, cfg.Todoist.AssignProjectLabel)
+	assert.Equal(t, 2, cfg.Retry.MaxAttempts)
+
+	require.Len(t, cfg.Jira, 4)
+	assert.Equal(t, "work-token", cfg.Jira[0].Token)
+	assert.Equal(t, []string{"Work", "Jira"}, cfg.Jira[0].Labels)
+	assert.Equal(t, "https://oss.atlassian.net", cfg.Jira[1].Site)
+	assert.Equal(t, "oss-token", cfg.Jira[1].Token)
+	assert.Equal(t, "project = OSS", cfg.Jira[1].JQL)
+	assert.Equal(t, "https://home.atlassian.net", cfg.Jira[2].Site)
+	assert.Equal(t, map[string][]string{"p1": {"Highest", "High"}}, cfg.Jira[2].PriorityMap)
+	assert.Equal(t, "https://new.atlassian.net", cfg.Jira[3].Site)
+}
+
+func TestApplyEnv(t *testing.T) {
+	testCases := []struct {
+		name          string
+		tree          map[string]any
+		environ       []string
+		expected      map[string]any
+		expectedError string
+	}{
+		{
+			name: "Unrelated variables are ignored",
+			tree: map[string]any{},
+			environ: []string{"PATH=/bin", "TODOIST=1", "JIRA_HOME=/opt/jira", "TODOIST_ASSISTANT_CONFIG=/etc/config.yaml",
+				"DRY_RUN=true", "LOG_FORMAT=json", "SERVER__ADDRESS=:9090", "TODOIST__TOKN=secret"},
+			expected: map[string]any{},
+		},
+		{
+			name:          "Unknown nested field",
+			tree:          map[string]any{},
+			environ:       []string{"TODOIST_ASSISTANT_TODOIST__TOKN=secret"},
+			expectedError: "environment variable TODOIST_ASSISTANT_TODOIST__TOKN: unknown field TOKN",
+		},
+		{
+			name:          "Index out of range",
+			tree:          map[string]any{},
+			environ:       []string{"TODOIST_ASSISTANT_JIRA__2__TOKEN=secret"},
+			expectedError: "environment variable TODOIST_ASSISTANT_JIRA__2__TOKEN: index 2 is out of range",
+		},
+		{
+			name:    "Item matched by name",
+			tree:    map[string]any{"jira": []any{map[string]any{"name": "My Work"}}},
+			environ: []string{"TODOIST_ASSISTANT_JIRA__MY_WORK__TOKEN=secret"},
+			expected: map[string]any{"jira": []any{
+				map[string]any{"name": "My Work", "token": "secret"},
+			}},
+		},
+		{
+			name:     "String values are not parsed",
+			tree:     map[string]any{},
+			environ:  []string{"TODOIST__TOKEN=123"},
+			expected: map[string]any{"todoist": map[string]any{"token": "123"}},
+		},
+		{
+			name:     "Prefixed variables take precedence",
+			tree:     map[string]any{},
+			environ:  []string{"TODOIST_ASSISTANT_LOG_LEVEL=debug", "LOG_LEVEL=warn"},
+			expected: map[string]any{"logLevel": "debug"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := &problems{origins: origins{}}
+			applyEnv(tc.tree, tc.environ, p)
+			if tc.expectedError != "" {
+				require.Len(t, p.list, 1)
+				assert.Equal(t, tc.expectedError, p.list[0].String())
+				return
+			}
+			assert.Empty(t, p.list)
+			assert.Equal(t, tc.expected, tc.tree)
+		})
+	}
+}
+
+func TestInterpolate(t *testing.T) {
+	lookup := envLookup([]string{"JIRA_TOKEN=secret", "HOST=example.com"})
+
+	testCases := []struct {
+		name            string
+		value           any
+		expected        any
+		expectedOrigins map[string]string
+		expectedError   string
+	}{
+		{
+			name:            "Whole value",
+			value:           map[string]any{"token": "${JIRA_TOKEN}"},
+			expected:        map[string]any{"token": "secret"},
+			expectedOrigins: map[string]string{"token": "environment variable JIRA_TOKEN"},
+		},
+		{
+			name:            "Part of a value in a list",
+			value:           []any{"https://${HOST}/jira", 3},
+			expected:        []any{"https://example.com/jira", 3},
+			expectedOrigins: map[string]string{"0": "environment variable HOST"},
+		},
+		{
+			name:            "Escaped dollar",
+			value:           "$${HOST} $HOST",
+			expected:        "${HOST} $HOST",
+			expectedOrigins: map[string]string{},
+		},
+		{
+			name:          "Unset variable",
+			value:         map[string]any{"todoist": map[string]any{"token": "${TODOIST_TOKEN}"}},
+			expectedError: "todoist.token: environment variable TODOIST_TOKEN is not set",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := &problems{origins: origins{}}
+			result := interpolate(tc.value, "", lookup, p)
+			if tc.expectedError != "" {
+				require.Len(t, p.list, 1)
+				assert.Equal(t, tc.expectedError, p.list[0].String())
+				return
+			}
+			assert.Empty(t, p.list)
+			assert.Equal(t, tc.expected, result)
+			sources := map[string]string{}
+			for path := range p.origins {
+				sources[path] = p.origins.source(path)
+			}
+			assert.Equal(t, tc.expectedOrigins, sources)
+		})
+	}
+}
+
+func TestFindConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	xdgFile := filepath.Join(dir, appName, defaultConfigFile)
+	envFile := filepath.Join(dir, "env.yaml")
+	writeFile(t, xdgFile, "")
+	writeFile(t, envFile, "")
+
+	testCases := []struct {
+		name          string
+		configFile    string
+		env           string
+		expected      string
+		expectedError bool
+	}{
+		{
+			name:     "XDG config home",
+			expected: xdgFile,
+		},
+		{
+			name:     "Environment variable",
+			env:      envFile,
+			expected: envFile,
+		},
+		{
+			name:       "Flag takes precedence",
+			configFile: xdgFile,
+			env:        envFile,
+			expected:   xdgFile,
+		},
+		{
+			name:          "Missing explicit file",
+			env:           filepath.Join(dir, "missing.yaml"),
+			expectedError: true,
+		},
+	}
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	defer os.Chdir(wd) //nolint:errcheck // restores the working directory of the test binary
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			configFile = tc.configFile
+			defer func() { configFile = "" }()
+			t.Setenv(ConfigFileEnv, tc.env)
+
+			path, err := findConfigFile()
+			if tc.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, path)
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package config
+
+import (
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fabiocorneti/todoist-assistant/internal/schedule"
+)
+
+// invalidLabelCharacters are the characters that Todoist does not accept in label names.
+const invalidLabelCharacters = `@"()|&!,\`
+
+// Validate returns all the problems found in the configuration, including the ones found while
+// loading it; the configuration is valid if there are none.
+func (cfg *Config) Validate() []Problem {
+	p := &problems{origins: cfg.origins, list: append([]Problem(nil), cfg.problems...)}
+	if p.origins == nil {
+		p.origins = origins{}
+	}
+	if cfg.undecoded {
+		return p.list
+	}
+
+	if cfg.Todoist.Token == "" {
+		p.add("todoist.token", "the Todoist API token is not set")
+	}
+	if cfg.UpdateInterval <= 0 {
+		p.add("updateInterval", "must be greater than 0")
+	}
+	checkOneOf(p, "logLevel", cfg.LogLevel, "panic", "fatal", "error", "warn", "info", "debug", "trace")
+	checkOneOf(p, "logFormat", cfg.LogFormat, LogFormatText, LogFormatJSON)
+	if cfg.Todoist.APIURL != "" {
+		checkURL(p, "todoist.apiURL", cfg.Todoist.APIURL)
+	}
+	checkOneOf(p, "todoist.transport", cfg.Todoist.Transport, "rest", "sync")
+	checkOneOf(p, "planFormat", cfg.PlanFormat, "text", "json")
+	checkLabel(p, "todoist.nextActionLabel", cfg.Todoist.NextActionLabel)
+	checkLabel(p, "todoist.projectsLabelPrefix", cfg.Todoist.ProjectsLabelPrefix)
+
+	if cfg.RecordPath != "" && cfg.ReplayPath != "" {
+		p.add("replayPath", "requests cannot be replayed while recording them to %s", cfg.RecordPath)
+	}
+	if cfg.ReplayPath != "" && cfg.Todoist.Transport == "sync" {
+		p.add("replayPath", "requests cannot be replayed with the sync Todoist transport")
+	}
+
+	if cfg.Server.Address != "" {
+		if _, _, err := net.SplitHostPort(cfg.Server.Address); err != nil {
+			p.add("server.address", "%q is not an address such as :9090", cfg.Server.Address)
+		}
+	}
+
+	names := map[string]bool{}
+	for i := range cfg.Jira {
+		jiraCfg := &cfg.Jira[i]
+		path := "jira." + strconv.Itoa(i)
+		if jiraCfg.Name != "" {
+			if names[jiraCfg.Name] {
+				p.add(path+".name", "another Jira instance is named %s", jiraCfg.Name)
+			}
+			names[jiraCfg.Name] = true
+		}
+		cfg.validateJira(p, path, jiraCfg)
+	}
+	cfg.validateSchedule(p)
+	return p.list
+}
+
+func (cfg *Config) validateSchedule(p *problems) {
+	loc, err := cfg.Location()
+	if err != nil {
+		p.add("schedule.timeZone", "unknown time zone %s", cfg.Schedule.TimeZone)
+		loc = time.Local
+	}
+	for i, window := range cfg.Schedule.QuietHours {
+		if _, err = schedule.ParseQuietHours(window.Days, window.From, window.To); err != nil {
+			p.add("schedule.quietHours."+strconv.Itoa(i), "%v", err)
+		}
+	}
+
+	processes := make([]string, 0, len(cfg.Schedule.Processes))
+	for process := range cfg.Schedule.Processes {
+		processes = append(processes, process)
+	}
+	sort.Strings(processes)
+	for _, process := range processes {
+		path := "schedule.processes." + process
+		if process != ProcessJira && process != ProcessProjects {
+			p.add(path, "unknown process %s, only %s and %s are allowed", process, ProcessJira, ProcessProjects)
+			continue
+		}
+		if _, err = schedule.Parse(cfg.Schedule.Processes[process], loc); err != nil {
+			p.add(path, "%v", err)
+		}
+	}
+	for i, jiraCfg := range cfg.Jira {
+		if jiraCfg.Schedule == "" {
+			continue
+		}
+		if _, err = schedule.Parse(jiraCfg.Schedule, loc); err != nil {
+			p.add("jira."+strconv.Itoa(i)+".schedule", "%v", err)
+		}
+	}
+}
+
+func (cfg *Config) validateJira(p *problems, path string, jiraCfg *JiraConfig) {
+	if jiraCfg.Site == "" {
+		p.add(path+".site", "the Jira site is not set")
+	} else {
+		checkURL(p, path+".site", jiraCfg.Site)
+	}
+	if jiraCfg.Flavor != "" {
+		checkOneOf(p, path+".flavor", jiraCfg.Flavor, FlavorCloud, FlavorDataCenter)
+	}
+	if jiraCfg.Username == "" && !jiraCfg.DataCenter() {
+		p.add(path+".username", "the Jira username is not set")
+	}
+	if jiraCfg.Token == "" {
+		p.add(path+".token", "the Jira API token is not set")
+	}
+	if strings.TrimSpace(jiraCfg.JQL) == "" {
+		p.add(path+".jql", "the JQL query is empty")
+	}
+	if jiraCfg.CACertFile != "" {
+		if _, err := loadCACerts(jiraCfg.CACertFile); err != nil {
+			p.add(path+".caCertFile", "%v", err)
+		}
+	}
+	if (jiraCfg.ClientCertFile == "") != (jiraCfg.ClientKeyFile == "") {
+		p.add(path+".clientCertFile", "clientCertFile and clientKeyFile must be set together")
+	} else if jiraCfg.ClientCertFile != "" {
+		if _, err := loadClientCert(jiraCfg.ClientCertFile, jiraCfg.ClientKeyFile); err != nil {
+			p.add(path+".clientCertFile", "%v", err)
+		}
+	}
+	if jiraCfg.CompletionTransition != "" && len(jiraCfg.CompletionStatuses) == 0 {
+		p.add(path+".completionTransition", "completionStatuses must include the status set by the transition")
+	}
+	for j, label := range jiraCfg.Labels {
+		checkLabel(p, path+".labels."+strconv.Itoa(j), label)
+	}
+	sources := map[string]bool{}
+	for j, source := range jiraCfg.DueDateSources {
+		sourcePath := path + ".dueDateSources." + strconv.Itoa(j)
+		checkOneOf(p, sourcePath, source, DueDateSourceDueDate, DueDateSourceSprint, DueDateSourceFixVersion)
+		if sources[source] {
+			p.add(sourcePath, "due date source %s is listed more than once", source)
+		}
+		sources[source] = true
+	}
+	cfg.validatePriorityMap(p, path+".priorityMap", jiraCfg.PriorityMap, "Jira priority")
+	for j := range jiraCfg.FieldMappings {
+		cfg.validateFieldMapping(p, path+".fieldMappings."+strconv.Itoa(j), &jiraCfg.FieldMappings[j])
+	}
+}
+
+func (cfg *Config) validateFieldMapping(p *problems, path string, mapping *FieldMapping) {
+	for _, segment := range strings.Split(mapping.Field, ".") {
+		if strings.TrimSpace(segment) == "" {
+			p.add(path+".field", "%q is not a field such as customfield_10042 or customfield_10042.value",
+				mapping.Field)
+			break
+		}
+	}
+	targets := 0
+	if mapping.Label != "" {
+		targets++
+		checkLabel(p, path+".label", mapping.Label)
+	}
+	if len(mapping.PriorityMap) > 0 {
+		targets++
+		cfg.validatePriorityMap(p, path+".priorityMap", mapping.PriorityMap, "value")
+	}
+	if mapping.Description != "" {
+		targets++
+		if strings.ContainsAny(mapping.Description, "\r\n") {
+			p.add(path+".description", "the name of the description line contains a line break")
+		}
+	}
+	if targets != 1 {
+		p.add(path, "exactly one of label, priorityMap and description must be set")
+	}
+}
+
+// validatePriorityMap checks that the keys of a priority map are Todoist priorities and that every
+// value, described by kind in problems, is mapped to a single priority.
+func (cfg *Config) validatePriorityMap(p *problems, path string, priorityMap map[string][]string, kind string) {
+	priorities := make([]string, 0, len(priorityMap))
+	for priority := range priorityMap {
+		priorities = append(priorities, priority)
+	}
+	sort.Strings(priorities)
+	mapped := map[string]string{}
+	for _, priority := range priorities {
+		priorityPath := path + "." + priority
+		if _, err := cfg.ToAPIPriority(priority); err != nil {
+			p.add(priorityPath, "unknown priority %s, only p1-p4 are allowed", priority)
+			continue
+		}
+		for j, name := range priorityMap[priority] {
+			if other, ok := mapped[name]; ok {
+				p.add(priorityPath+"."+strconv.Itoa(j), "%s %s is already mapped to %s", kind, name, other)
+				continue
+			}
+			mapped[name] = priority
+		}
+	}
+}
+
+func checkOneOf(p *problems, path, value string, allowed ...string) {
+	for _, candidate := range allowed {
+		if value == candidate {
+			return
+		}
+	}
+	p.add(path, "invalid value %q, only %s are allowed", value, strings.Join(allowed, ", "))
+}
+
+func checkURL(p *problems, path, value string) {
+	if parsed, err := url.Parse(value); err != nil ||
+		(parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+		p.add(path, "%q is not an http or https URL", value)
+	}
+}
+
+func checkLabel(p *problems, path, label string) {
+	switch {
+	case strings.TrimSpace(label) == "":
+		p.add(path, "the label is empty")
+	case strings.ContainsAny(label, invalidLabelCharacters):
+		p.add(path, "label %q contains characters not allowed by Todoist (%s)", label, invalidLabelCharacters)
+	}
+}
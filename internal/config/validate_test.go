@@ -0,0 +1,193 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	testCases := []struct {
+		name             string
+		content          string
+		environ          []string
+		expectedProblems []string
+	}{
+		{
+			name: "Valid configuration",
+			content: `todoist:
+  token: secret
+jira:
+  - site: https://example.atlassian.net
+    username: user@example.com
+    token: secret
+    jql: assignee = currentUser()
+    labels: [Work, Jira/Issues]
+    priorityMap:
+      p1: [Highest]
+      p2: [High, Medium]
+  - site: https://jira.example.com/jira/
+    flavor: datacenter
+    token: secret
+    jql: project = PRJ
+`,
+		},
+		{
+			name: "Unknown fields and invalid values",
+			content: `update_interval: 1
+todoist:
+  token: secret
+  transport: graphql
+runTimeout: 10
+dryRun: maybe
+jira:
+  - site: https://example.atlassian.net
+    sites: []
+`,
+			environ: []string{"TODOIST_ASSISTANT_RETRY__MAX_ATTEMPTS=many"},
+			expectedProblems: []string{
+				"CONFIG:6: dryRun: expected true or false, got maybe",
+				"CONFIG:9: jira.0.sites: unknown field",
+				"environment variable TODOIST_ASSISTANT_RETRY__MAX_ATTEMPTS: retry.maxAttempts: expected an integer, got many",
+				"CONFIG:5: runTimeout: expected a duration such as 30s, got 10",
+				"CONFIG:1: update_interval: unknown field",
+			},
+		},
+		{
+			name: "Replay with the sync transport",
+			content: `todoist:
+  token: secret
+  transport: sync
+replayPath: bug.jsonl
+`,
+			expectedProblems: []string{
+				"CONFIG:4: replayPath: requests cannot be replayed with the sync Todoist transport",
+			},
+		},
+		{
+			name: "Invalid values",
+			content: `logLevel: verbose
+todoist:
+  transport: graphql
+  nextActionLabel: "@next"
+jira:
+  - name: work
+    site: example.atlassian.net
+    jql: " "
+    labels: ["Work (Jira)"]
+    priorityMap:
+      p0: [Blocker]
+      p1: [Highest, High]
+      p2: [High]
+  - name: work
+    site: https://example.atlassian.net
+    username: user@example.com
+    token: secret
+    jql: assignee = currentUser()
+    completionTransition: Done
+    dueDateSources: [duedate, sprints, duedate]
+    fieldMappings:
+      - field: customfield_10042.
+        label: "Customer (Jira)"
+      - field: customfield_10050
+        label: Jira/Severity
+        description: Severity
+      - field: customfield_10060
+        priorityMap:
+          p5: [Sev1]
+server:
+  address: localhost
+recordPath: cassette.jsonl
+replayPath: bug.jsonl
+`,
+			expectedProblems: []string{
+				"CONFIG:2: todoist.token: the Todoist API token is not set",
+				`CONFIG:1: logLevel: invalid value "verbose", only panic, fatal, error, warn, info, debug, trace are allowed`,
+				`CONFIG:3: todoist.transport: invalid value "graphql", only rest, sync are allowed`,
+				`CONFIG:4: todoist.nextActionLabel: label "@next" contains characters not allowed by Todoist (@"()|&!,\)`,
+				"CONFIG:33: replayPath: requests cannot be replayed while recording them to cassette.jsonl",
+				`CONFIG:31: server.address: "localhost" is not an address such as :9090`,
+				`CONFIG:7: jira.0.site: "example.atlassian.net" is not an http or https URL`,
+				"CONFIG:6: jira.0.username: the Jira username is not set",
+				"CONFIG:6: jira.0.token: the Jira API token is not set",
+				"CONFIG:8: jira.0.jql: the JQL query is empty",
+				`CONFIG:9: jira.0.labels.0: label "Work (Jira)" contains characters not allowed by Todoist (@"()|&!,\)`,
+				"CONFIG:11: jira.0.priorityMap.p0: unknown priority p0, only p1-p4 are allowed",
+				"CONFIG:13: jira.0.priorityMap.p2.0: Jira priority High is already mapped to p1",
+				"CONFIG:14: jira.1.name: another Jira instance is named work",
+				"CONFIG:19: jira.1.completionTransition: completionStatuses must include the status set by the transition",
+				`CONFIG:20: jira.1.dueDateSources.1: invalid value "sprints", only duedate, sprint, fixVersion are allowed`,
+				"CONFIG:20: jira.1.dueDateSources.2: due date source duedate is listed more than once",
+				`CONFIG:22: jira.1.fieldMappings.0.field: "customfield_10042." is not a field such as customfield_10042 or ` +
+					"customfield_10042.value",
+				`CONFIG:23: jira.1.fieldMappings.0.label: label "Customer (Jira)" contains characters not allowed by ` +
+					`Todoist (@"()|&!,\)`,
+				"CONFIG:24: jira.1.fieldMappings.1: exactly one of label, priorityMap and description must be set",
+				"CONFIG:29: jira.1.fieldMappings.2.priorityMap.p5: unknown priority p5, only p1-p4 are allowed",
+			},
+		},
+		{
+			name: "Invalid Jira flavor and certificates",
+			content: `todoist:
+  token: secret
+jira:
+  - site: https://jira.example.com
+    flavor: server
+    token: secret
+    jql: project = PRJ
+    caCertFile: missing.pem
+    clientCertFile: client.pem
+`,
+			expectedProblems: []string{
+				`CONFIG:5: jira.0.flavor: invalid value "server", only cloud, datacenter are allowed`,
+				"CONFIG:4: jira.0.username: the Jira username is not set",
+				"CONFIG:8: jira.0.caCertFile: error reading the CA bundle: open missing.pem: no such file or directory",
+				"CONFIG:9: jira.0.clientCertFile: clientCertFile and clientKeyFile must be set together",
+			},
+		},
+		{
+			name: "Invalid schedule",
+			content: `todoist:
+  token: secret
+schedule:
+  timeZone: Mars/Olympus_Mons
+  quietHours:
+    - days: [caturday]
+  processes:
+    jira: "*/5 8-18 * *"
+    labels: 1h
+`,
+			expectedProblems: []string{
+				"CONFIG:4: schedule.timeZone: unknown time zone Mars/Olympus_Mons",
+				`CONFIG:6: schedule.quietHours.0: unknown day "caturday"`,
+				`CONFIG:8: schedule.processes.jira: "*/5 8-18 * *" is neither an interval nor a cron expression: ` +
+					"expected exactly 5 fields, found 4: [*/5 8-18 * *]",
+				"CONFIG:9: schedule.processes.labels: unknown process labels, only jira and projects are allowed",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			writeFile(t, path, tc.content)
+
+			cfg, err := readConfig(path, tc.environ)
+			require.NoError(t, err)
+			setDefaults(&cfg)
+
+			var problems []string
+			for _, problem := range cfg.Validate() {
+				problems = append(problems, problem.String())
+			}
+			var expected []string
+			for _, problem := range tc.expectedProblems {
+				expected = append(expected, strings.Replace(problem, "CONFIG", path, 1))
+			}
+			assert.Equal(t, expected, problems)
+		})
+	}
+}
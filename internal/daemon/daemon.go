@@ -0,0 +1,189 @@
+package daemon
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/fabiocorneti/todoist-assistant/internal/config"
+	"github.com/fabiocorneti/todoist-assistant/internal/metrics"
+	"github.com/fabiocorneti/todoist-assistant/internal/process"
+	"github.com/fabiocorneti/todoist-assistant/internal/schedule"
+	"github.com/fabiocorneti/todoist-assistant/internal/server"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// ExitOK is returned when the daemon stops after a successful run.
+	ExitOK = 0
+	// ExitRunFailed is returned when the daemon stops after a run with failures.
+	ExitRunFailed = 1
+	// ExitRunCancelled is returned when a run had to be cancelled to stop the daemon.
+	ExitRunCancelled = 2
+)
+
+// Daemon runs the processes on their schedules until it receives SIGINT or SIGTERM; every run
+// uses the current configuration of the provider. Runs never overlap: processes that are due
+// together run in the same run, and processes that become due during a run wait for it to end.
+//
+// On the first stop signal an in-flight run is given cfg.ShutdownTimeout to complete before being
+// cancelled; a second stop signal cancels it immediately. SIGHUP reloads the configuration and
+// runs every process immediately, even in quiet hours, or as soon as the in-flight run ends.
+type Daemon struct {
+	provider *config.Provider
+	logger   *logrus.Logger
+	signals  chan os.Signal
+	status   *server.Status
+	runner   func(ctx context.Context, cfg config.Config, logger *logrus.Logger, jobs []string) *process.Summary
+}
+
+func NewDaemon(provider *config.Provider, logger *logrus.Logger) *Daemon {
+	return &Daemon{
+		provider: provider,
+		logger:   logger,
+		signals:  make(chan os.Signal, 1),
+		status:   server.NewStatus(),
+		runner:   process.RunJobs,
+	}
+}
+
+// Status returns the status of the runs, which tells whether the daemon is ready.
+func (d *Daemon) Status() *server.Status {
+	return d.status
+}
+
+// Run starts the daemon and returns the exit code once it has been stopped: ExitOK if it was
+// stopped between runs, otherwise the exit code of the run it interrupted.
+func (d *Daemon) Run() int {
+	signal.Notify(d.signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(d.signals)
+	defer d.flushLogs()
+
+	scheduler := schedule.NewScheduler()
+	runAll := false
+	for {
+		now := time.Now()
+		d.updateScheduler(scheduler, now)
+
+		jobs := scheduler.Due(now)
+		if runAll {
+			jobs = scheduler.All()
+			runAll = false
+			if scheduler.Quiet(now) {
+				d.logger.Warn("Running every process during quiet hours because of SIGHUP")
+			}
+		}
+		if len(jobs) > 0 {
+			exitCode, stopped, reload := d.runOnce(jobs)
+			if stopped {
+				return exitCode
+			}
+			scheduler.Ran(jobs, now)
+			if reload {
+				d.logger.Info("Reloading the configuration after SIGHUP and starting a run")
+				d.provider.ReloadAndLog(d.logger)
+				runAll = true
+			}
+			continue
+		}
+
+		// without jobs, only signals are waited for
+		wait := make(<-chan time.Time)
+		if next := scheduler.Next(); !next.IsZero() {
+			d.logger.Infof("Next run at %s", next.Format(time.RFC3339))
+			d.status.Scheduled(next)
+			wait = time.After(time.Until(next))
+		}
+		select {
+		case sig := <-d.signals:
+			if sig != syscall.SIGHUP {
+				d.logger.Infof("Received %s, stopping", sig)
+				return ExitOK
+			}
+			d.logger.Info("Received SIGHUP, reloading the configuration and starting a run")
+			d.provider.ReloadAndLog(d.logger)
+			runAll = true
+		case <-wait:
+		}
+	}
+}
+
+// updateScheduler updates the jobs of the scheduler from the current configuration, which may
+// have been reloaded.
+func (d *Daemon) updateScheduler(scheduler *schedule.Scheduler, now time.Time) {
+	cfg := d.provider.Get()
+	loc, err := cfg.Location()
+	if err == nil {
+		var quietHours []schedule.QuietHours
+		quietHours, err = cfg.QuietHours()
+		if err == nil {
+			err = scheduler.Update(cfg.Jobs(), loc, quietHours, now)
+		}
+	}
+	if err != nil {
+		d.logger.WithError(err).Error("Invalid schedule, keeping the previous one")
+	}
+}
+
+// runOnce runs the processes of the given jobs while handling signals; it returns the exit code
+// for the run, whether the daemon must stop and whether SIGHUP was received during the run.
+func (d *Daemon) runOnce(jobs []string) (int, bool, bool) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cfg := d.provider.Get()
+	start := time.Now()
+	d.status.RunStarted(start)
+	done := make(chan *process.Summary, 1)
+	go func() {
+		done <- d.runner(ctx, cfg, d.logger, jobs)
+	}()
+
+	stopping := false
+	cancelled := false
+	reload := false
+	var grace <-chan time.Time
+	for {
+		select {
+		case summary := <-done:
+			exitCode, result := ExitOK, metrics.ResultSucceeded
+			switch {
+			case cancelled:
+				exitCode, result = ExitRunCancelled, metrics.ResultCancelled
+			case summary.HasFailures():
+				exitCode, result = ExitRunFailed, metrics.ResultFailed
+			}
+			end := time.Now()
+			metrics.ObserveRun(end.Sub(start), result, end)
+			d.status.RunFinished(end, exitCode == ExitOK)
+			return exitCode, stopping, reload
+		case sig := <-d.signals:
+			switch {
+			case sig == syscall.SIGHUP:
+				d.logger.Info("Received SIGHUP while a run is in progress, reloading the configuration after it")
+				reload = true
+			case stopping:
+				d.logger.Infof("Received %s again, cancelling the current run", sig)
+				cancelled = true
+				cancel()
+			default:
+				d.logger.Infof("Received %s, waiting up to %s for the current run to complete",
+					sig, cfg.ShutdownTimeout)
+				stopping = true
+				grace = time.After(cfg.ShutdownTimeout)
+			}
+		case <-grace:
+			d.logger.Info("Shutdown timeout expired, cancelling the current run")
+			cancelled = true
+			cancel()
+		}
+	}
+}
+
+func (d *Daemon) flushLogs() {
+	if file, ok := d.logger.Out.(*os.File); ok {
+		file.Sync() //nolint:errcheck // syncing a terminal or pipe fails and is harmless
+	}
+}
@@ -0,0 +1,125 @@
+package daemon
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/fabiocorneti/todoist-assistant/internal/config"
+	"github.com/fabiocorneti/todoist-assistant/internal/process"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunOnce(t *testing.T) {
+	testCases := []struct {
+		name             string
+		signals          []os.Signal
+		runDuration      time.Duration
+		failRun          bool
+		expectedExitCode int
+		expectedStopped  bool
+		expectedReload   bool
+	}{
+		{
+			name:             "Successful run",
+			expectedExitCode: ExitOK,
+		},
+		{
+			name:             "Failed run",
+			failRun:          true,
+			expectedExitCode: ExitRunFailed,
+		},
+		{
+			name:             "Run completes within the shutdown timeout",
+			signals:          []os.Signal{syscall.SIGTERM},
+			runDuration:      10 * time.Millisecond,
+			expectedExitCode: ExitOK,
+			expectedStopped:  true,
+		},
+		{
+			name:             "Run cancelled after the shutdown timeout",
+			signals:          []os.Signal{syscall.SIGTERM},
+			runDuration:      time.Hour,
+			expectedExitCode: ExitRunCancelled,
+			expectedStopped:  true,
+		},
+		{
+			name:             "Run cancelled on second signal",
+			signals:          []os.Signal{syscall.SIGINT, syscall.SIGINT},
+			runDuration:      time.Hour,
+			expectedExitCode: ExitRunCancelled,
+			expectedStopped:  true,
+		},
+		{
+			name:             "SIGHUP does not stop the run",
+			signals:          []os.Signal{syscall.SIGHUP},
+			runDuration:      10 * time.Millisecond,
+			expectedExitCode: ExitOK,
+			expectedReload:   true,
+		},
+		{
+			name:             "Stop signal after SIGHUP",
+			signals:          []os.Signal{syscall.SIGHUP, syscall.SIGTERM},
+			runDuration:      10 * time.Millisecond,
+			expectedExitCode: ExitOK,
+			expectedStopped:  true,
+			expectedReload:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := config.Config{ShutdownTimeout: 50 * time.Millisecond}
+			logger := logrus.New()
+			d := NewDaemon(config.NewProvider(cfg), logger)
+			d.signals = make(chan os.Signal, len(tc.signals))
+			started := make(chan struct{})
+			d.runner = func(ctx context.Context, _ config.Config, _ *logrus.Logger, _ []string) *process.Summary {
+				summary := &process.Summary{}
+				close(started)
+				select {
+				case <-ctx.Done():
+				case <-time.After(tc.runDuration):
+				}
+				if tc.failRun {
+					summary.Failed = 1
+				}
+				return summary
+			}
+
+			signals := tc.signals
+			go func() {
+				<-started
+				for _, sig := range signals {
+					d.signals <- sig
+				}
+			}()
+
+			exitCode, stopped, reload := d.runOnce([]string{config.ProcessProjects})
+			assert.Equal(t, tc.expectedExitCode, exitCode)
+			assert.Equal(t, tc.expectedStopped, stopped)
+			assert.Equal(t, tc.expectedReload, reload)
+		})
+	}
+}
+
+func TestRunStoppedBetweenRuns(t *testing.T) {
+	cfg := config.Config{UpdateInterval: 60, ShutdownTimeout: 50 * time.Millisecond}
+	d := NewDaemon(config.NewProvider(cfg), logrus.New())
+	finished := make(chan struct{})
+	d.runner = func(context.Context, config.Config, *logrus.Logger, []string) *process.Summary {
+		defer close(finished)
+		return &process.Summary{Failed: 1}
+	}
+	go func() {
+		<-finished
+		// the daemon waits for the next run
+		time.Sleep(20 * time.Millisecond)
+		d.signals <- syscall.SIGTERM
+	}()
+
+	assert.Equal(t, ExitOK, d.Run(), "the failure of the last run does not fail the stop")
+}
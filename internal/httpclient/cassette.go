@@ -0,0 +1,320 @@
+package httpclient
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+const (
+	// redacted replaces the secrets found in recorded interactions.
+	redacted = "REDACTED"
+	// redactedEmail replaces the email addresses found in recorded interactions.
+	redactedEmail = "user@example.com"
+
+	maxCassetteLine = 16 * 1024 * 1024
+)
+
+var emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+
+// recordedHeaders are the only headers kept in a cassette, so that credentials and cookies are
+// never recorded.
+var recordedHeaders = []string{"Content-Type", "Retry-After"}
+
+// Cassette wraps the transport of the clients of an API to record or replay their requests, see
+// RateLimitedClient.UseCassette.
+type Cassette interface {
+	// Transport returns the transport to use for requests to api instead of next.
+	Transport(api string, next http.RoundTripper) http.RoundTripper
+	// Err returns an error if the cassette failed without failing the requests sent through it, or nil.
+	Err() error
+}
+
+// Interaction is a request and its response, recorded in a cassette.
+type Interaction struct {
+	API      string            `json:"api"`
+	Request  RecordedRequest   `json:"request"`
+	Response *RecordedResponse `json:"response,omitempty"`
+	Error    string            `json:"error,omitempty"`
+}
+
+// RecordedRequest is a sanitised request; URL is its path and query, without the host.
+type RecordedRequest struct {
+	Method  string      `json:"method"`
+	URL     string      `json:"url"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    string      `json:"body,omitempty"`
+}
+
+// RecordedResponse is a sanitised response.
+type RecordedResponse struct {
+	Status  int         `json:"status"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    string      `json:"body,omitempty"`
+}
+
+// Recorder is a Cassette appending the interactions of the requests sent through it to a file,
+// one JSON object per line. Credentials, the given secrets and email addresses are scrubbed from
+// the recorded interactions, so that a cassette can be attached to a bug report.
+//
+// A request is not failed by an interaction that cannot be recorded, since it was sent anyway: the
+// failures are returned by Err.
+type Recorder struct {
+	mu       sync.Mutex
+	path     string
+	replacer *strings.Replacer
+	failures []error
+}
+
+// NewRecorder creates a Recorder writing to the file at path; secrets are replaced with REDACTED
+// wherever they appear.
+func NewRecorder(path string, secrets ...string) *Recorder {
+	var pairs []string
+	for _, secret := range secrets {
+		if secret != "" {
+			pairs = append(pairs, secret, redacted)
+		}
+	}
+	return &Recorder{path: path, replacer: strings.NewReplacer(pairs...)}
+}
+
+// Transport returns a transport sending requests with next and recording them.
+func (r *Recorder) Transport(api string, next http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		var body []byte
+		if req.Body != nil && req.Body != http.NoBody {
+			var err error
+			if body, err = io.ReadAll(req.Body); err != nil {
+				return nil, err
+			}
+			req.Body.Close()
+			req = req.Clone(req.Context())
+			req.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		interaction := Interaction{
+			API: api,
+			Request: RecordedRequest{
+				Method:  req.Method,
+				URL:     requestURL(req.URL, r.scrub),
+				Headers: keepHeaders(req.Header),
+				Body:    r.scrub(string(body)),
+			},
+		}
+		resp, err := next.RoundTrip(req)
+		if err != nil {
+			interaction.Error = r.scrub(err.Error())
+			r.record(interaction)
+			return nil, err
+		}
+
+		respBody, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(respBody))
+		interaction.Response = &RecordedResponse{
+			Status:  resp.StatusCode,
+			Headers: keepHeaders(resp.Header),
+			Body:    r.scrub(string(respBody)),
+		}
+		r.record(interaction)
+		return resp, nil
+	})
+}
+
+// Err returns an error if some interactions could not be recorded, or nil.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch len(r.failures) {
+	case 0:
+		return nil
+	case 1:
+		return r.failures[0]
+	default:
+		return fmt.Errorf("%d requests were not recorded: %w", len(r.failures), r.failures[0])
+	}
+}
+
+// record writes an interaction to the cassette, keeping the error returned by Err if it fails.
+func (r *Recorder) record(interaction Interaction) {
+	if err := r.write(interaction); err != nil {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.failures = append(r.failures, err)
+	}
+}
+
+// scrub replaces secrets and email addresses in value.
+func (r *Recorder) scrub(value string) string {
+	return scrubEmails(r.replacer.Replace(value))
+}
+
+func scrubEmails(value string) string {
+	return emailPattern.ReplaceAllString(value, redactedEmail)
+}
+
+func (r *Recorder) write(interaction Interaction) error {
+	line, err := json.Marshal(interaction)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	file, err := os.OpenFile(r.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("error recording the request: %w", err)
+	}
+	defer file.Close()
+	if _, err = file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("error recording the request: %w", err)
+	}
+	return nil
+}
+
+// Replayer is a Cassette answering requests with the interactions recorded by a Recorder, without
+// sending them. A request is answered by the first interaction not replayed yet with the same API,
+// method, path and query; the host and the body of the request are ignored, so a cassette recorded
+// against a real server can be replayed against any site.
+type Replayer struct {
+	mu           sync.Mutex
+	interactions []Interaction
+	replayed     []bool
+}
+
+// LoadReplayer reads the cassette at path.
+func LoadReplayer(path string) (*Replayer, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading cassette: %w", err)
+	}
+	defer file.Close()
+
+	replayer := &Replayer{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, maxCassetteLine)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var interaction Interaction
+		if err = json.Unmarshal(scanner.Bytes(), &interaction); err != nil {
+			return nil, fmt.Errorf("error reading cassette %s at line %d: %w", path, line, err)
+		}
+		replayer.interactions = append(replayer.interactions, interaction)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading cassette: %w", err)
+	}
+	replayer.replayed = make([]bool, len(replayer.interactions))
+	return replayer, nil
+}
+
+// Transport returns a transport replaying the interactions recorded for api; next is not used.
+func (r *Replayer) Transport(api string, _ http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Body != nil {
+			io.Copy(io.Discard, req.Body) //nolint:errcheck // the body is not replayed
+			req.Body.Close()
+		}
+		interaction, ok := r.next(api, req.Method, requestURL(req.URL, scrubEmails))
+		if !ok {
+			return nil, fmt.Errorf("no recorded %s response for %s %s", api, req.Method, req.URL.RequestURI())
+		}
+		if interaction.Response == nil {
+			return nil, errors.New(interaction.Error)
+		}
+		resp := &http.Response{
+			Status:        fmt.Sprintf("%d %s", interaction.Response.Status, http.StatusText(interaction.Response.Status)),
+			StatusCode:    interaction.Response.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        http.Header{},
+			Body:          io.NopCloser(strings.NewReader(interaction.Response.Body)),
+			ContentLength: int64(len(interaction.Response.Body)),
+			Request:       req,
+		}
+		for name, values := range interaction.Response.Headers {
+			resp.Header[name] = append([]string(nil), values...)
+		}
+		return resp, nil
+	})
+}
+
+// Err returns nil, since replaying a request either answers it or fails it.
+func (r *Replayer) Err() error {
+	return nil
+}
+
+// Remaining returns the number of interactions not replayed yet.
+func (r *Replayer) Remaining() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	remaining := 0
+	for _, replayed := range r.replayed {
+		if !replayed {
+			remaining++
+		}
+	}
+	return remaining
+}
+
+func (r *Replayer) next(api, method, requestURL string) (Interaction, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, interaction := range r.interactions {
+		if !r.replayed[i] && interaction.API == api && interaction.Request.Method == method &&
+			interaction.Request.URL == requestURL {
+			r.replayed[i] = true
+			return interaction, true
+		}
+	}
+	return Interaction{}, false
+}
+
+// requestURL returns the path and the query of u scrubbed with scrub, with the query parameters
+// sorted so that requests match regardless of their order.
+func requestURL(u *url.URL, scrub func(string) string) string {
+	path := scrub(u.EscapedPath())
+	if u.RawQuery == "" {
+		return path
+	}
+	query := u.Query()
+	for _, values := range query {
+		for i := range values {
+			values[i] = scrub(values[i])
+		}
+	}
+	return path + "?" + query.Encode()
+}
+
+func keepHeaders(header http.Header) http.Header {
+	kept := http.Header{}
+	for _, name := range recordedHeaders {
+		if values := header.Values(name); len(values) > 0 {
+			kept[name] = append([]string(nil), values...)
+		}
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	return kept
+}
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,118 @@
+package httpclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type cassetteRequest struct {
+	method string
+	path   string
+	body   string
+}
+
+func sendCassetteRequests(t *testing.T, client *RateLimitedClient, site string,
+	requests []cassetteRequest) []string {
+	var responses []string
+	for _, r := range requests {
+		var body io.Reader
+		if r.body != "" {
+			body = strings.NewReader(r.body)
+		}
+		req, err := http.NewRequestWithContext(context.Background(), r.method, site+r.path, body)
+		require.NoError(t, err)
+		req.SetBasicAuth("jane@corp.example", "s3cr3t")
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		content, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		resp.Body.Close()
+		responses = append(responses, resp.Status+" "+resp.Header.Get("Content-Type")+" "+string(content))
+	}
+	return responses
+}
+
+func TestCassetteRecordReplay(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := io.ReadAll(r.Body) //nolint:errcheck // echoed back
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=s3cr3t")
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		user, _, _ := r.BasicAuth()
+		io.WriteString(w, `{"call":`+strconv.Itoa(calls)+`,"user":"`+user+`","query":"`+ //nolint:errcheck // test
+			r.URL.Query().Get("jql")+`","body":"`+string(body)+`"}`)
+	}))
+	defer server.Close()
+
+	requests := []cassetteRequest{
+		{method: http.MethodGet, path: "/search?maxResults=1&jql=assignee+%3D+jane%40corp.example"},
+		{method: http.MethodGet, path: "/search?jql=assignee+%3D+jane%40corp.example&maxResults=1"},
+		{method: http.MethodPost, path: "/tasks", body: "token s3cr3t"},
+		{method: http.MethodGet, path: "/missing"},
+	}
+
+	path := filepath.Join(t.TempDir(), "cassette.jsonl")
+	recorded := NewRateLimitedClient("test", Limit{}, RetryPolicy{}, 0)
+	recorded.UseCassette(NewRecorder(path, "s3cr3t"))
+	expected := sendCassetteRequests(t, recorded, server.URL, requests)
+	assert.Contains(t, expected[0], "jane@corp.example")
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, len(requests), strings.Count(string(content), "\n"))
+	assert.NotContains(t, string(content), "s3cr3t")
+	assert.NotContains(t, string(content), "jane")
+	assert.NotContains(t, string(content), server.URL)
+	assert.Contains(t, string(content), "user@example.com")
+
+	replayer, err := LoadReplayer(path)
+	require.NoError(t, err)
+	replayed := NewRateLimitedClient("test", Limit{}, RetryPolicy{}, 0)
+	replayed.UseCassette(replayer)
+	actual := sendCassetteRequests(t, replayed, "http://example.invalid", requests)
+	assert.Equal(t, len(requests), calls)
+	assert.Equal(t, 0, replayer.Remaining())
+	for i := range expected {
+		assert.Equal(t, strings.ReplaceAll(strings.ReplaceAll(expected[i], "jane@corp.example", "user@example.com"),
+			"s3cr3t", "REDACTED"), actual[i])
+	}
+
+	// every interaction is replayed once
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, "http://example.invalid/missing", nil)
+	require.NoError(t, err)
+	_, err = replayed.Do(req)
+	assert.ErrorContains(t, err, "no recorded test response for GET /missing")
+}
+
+func TestRecorderWriteFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "created") //nolint:errcheck // test
+	}))
+	defer server.Close()
+
+	recorder := NewRecorder(filepath.Join(t.TempDir(), "missing", "cassette.jsonl"))
+	client := NewRateLimitedClient("test", Limit{}, RetryPolicy{}, 0)
+	client.UseCassette(recorder)
+	requests := []cassetteRequest{
+		{method: http.MethodPost, path: "/tasks"},
+		{method: http.MethodPost, path: "/tasks"},
+	}
+	responses := sendCassetteRequests(t, client, server.URL, requests)
+	assert.Equal(t, []string{"200 OK text/plain; charset=utf-8 created", "200 OK text/plain; charset=utf-8 created"},
+		responses, "the requests were sent")
+	assert.ErrorContains(t, recorder.Err(), "2 requests were not recorded: error recording the request")
+}
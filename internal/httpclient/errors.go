@@ -0,0 +1,98 @@
+package httpclient
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const maxBodyExcerpt = 512
+
+// APIError is returned when an API responds with a non-successful status code.
+type APIError struct {
+	Endpoint   string
+	StatusCode int
+	RequestID  string
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	message := fmt.Sprintf("%s returned HTTP status %d", e.Endpoint, e.StatusCode)
+	if e.RequestID != "" {
+		message += " (request " + e.RequestID + ")"
+	}
+	if e.Body != "" {
+		message += ": " + e.Body
+	}
+	return message
+}
+
+// NotFound returns true if the requested resource does not exist.
+func (e *APIError) NotFound() bool {
+	return e.StatusCode == http.StatusNotFound
+}
+
+// Unauthorized returns true if the credentials were rejected.
+func (e *APIError) Unauthorized() bool {
+	return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+}
+
+// RateLimited returns true if the request was rejected because of rate limiting.
+func (e *APIError) RateLimited() bool {
+	return e.StatusCode == http.StatusTooManyRequests
+}
+
+// ServerError returns true if the API failed to handle a valid request.
+func (e *APIError) ServerError() bool {
+	return e.StatusCode >= http.StatusInternalServerError
+}
+
+// CheckResponse returns an APIError if the response status is not 2xx; the body is
+// consumed and closed in that case.
+func CheckResponse(resp *http.Response, endpoint string) error {
+	if resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices {
+		return nil
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxBodyExcerpt)) //nolint:errcheck // the excerpt is best effort
+	requestID := resp.Header.Get(RequestIDHeader)
+	if requestID == "" && resp.Request != nil {
+		requestID = resp.Request.Header.Get(RequestIDHeader)
+	}
+	return &APIError{
+		Endpoint:   endpoint,
+		StatusCode: resp.StatusCode,
+		RequestID:  requestID,
+		Body:       strings.TrimSpace(string(body)),
+	}
+}
+
+// ErrorCategory classifies an error for reporting: not_found, unauthorized, rate_limited,
+// server_error, client_error or other for errors that are not an APIError.
+func ErrorCategory(err error) string {
+	var apiError *APIError
+	if !errors.As(err, &apiError) {
+		return "other"
+	}
+	switch {
+	case apiError.NotFound():
+		return "not_found"
+	case apiError.Unauthorized():
+		return "unauthorized"
+	case apiError.RateLimited():
+		return "rate_limited"
+	case apiError.ServerError():
+		return "server_error"
+	default:
+		return "client_error"
+	}
+}
+
+// IsNotFound returns true if err is an APIError for a missing resource.
+func IsNotFound(err error) bool {
+	var apiError *APIError
+	return errors.As(err, &apiError) && apiError.NotFound()
+}
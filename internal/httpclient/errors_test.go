@@ -0,0 +1,75 @@
+package httpclient
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckResponse(t *testing.T) {
+	testCases := []struct {
+		name             string
+		statusCode       int
+		body             string
+		expectedError    string
+		expectedCategory string
+	}{
+		{
+			name:       "Success",
+			statusCode: http.StatusNoContent,
+		},
+		{
+			name:             "Not found",
+			statusCode:       http.StatusNotFound,
+			body:             "Task not found\n",
+			expectedError:    "GET tasks/{id} returned HTTP status 404 (request abc): Task not found",
+			expectedCategory: "not_found",
+		},
+		{
+			name:             "Unauthorized",
+			statusCode:       http.StatusUnauthorized,
+			expectedError:    "GET tasks/{id} returned HTTP status 401 (request abc)",
+			expectedCategory: "unauthorized",
+		},
+		{
+			name:             "Rate limited",
+			statusCode:       http.StatusTooManyRequests,
+			expectedError:    "GET tasks/{id} returned HTTP status 429 (request abc)",
+			expectedCategory: "rate_limited",
+		},
+		{
+			name:             "Server error",
+			statusCode:       http.StatusBadGateway,
+			body:             strings.Repeat("x", 1000),
+			expectedError:    "GET tasks/{id} returned HTTP status 502 (request abc): " + strings.Repeat("x", maxBodyExcerpt),
+			expectedCategory: "server_error",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := &http.Response{
+				StatusCode: tc.statusCode,
+				Header:     http.Header{RequestIDHeader: []string{"abc"}},
+				Body:       io.NopCloser(strings.NewReader(tc.body)),
+			}
+
+			err := CheckResponse(resp, "GET tasks/{id}")
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tc.expectedError)
+			wrapped := fmt.Errorf("wrapped: %w", err)
+			assert.Equal(t, tc.expectedCategory, ErrorCategory(wrapped))
+			assert.Equal(t, tc.statusCode == http.StatusNotFound, IsNotFound(wrapped))
+		})
+	}
+
+	assert.Equal(t, "other", ErrorCategory(errors.New("connection refused")))
+}
@@ -0,0 +1,246 @@
+package httpclient
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/fabiocorneti/todoist-assistant/internal/metrics"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// RequestIDHeader marks a non-idempotent request that the server deduplicates, so that it can be retried.
+	RequestIDHeader = "X-Request-Id"
+
+	defaultMaxAttempts    = 4
+	defaultInitialBackoff = time.Second
+	defaultMaxBackoff     = 30 * time.Second
+)
+
+type endpointKey struct{}
+
+// WithEndpoint returns a context for requests to the given endpoint, such as GET tasks/{id},
+// which identifies them in metrics without the IDs in their path.
+func WithEndpoint(ctx context.Context, endpoint string) context.Context {
+	return context.WithValue(ctx, endpointKey{}, endpoint)
+}
+
+func endpointOf(req *http.Request) string {
+	if endpoint, ok := req.Context().Value(endpointKey{}).(string); ok {
+		return endpoint
+	}
+	return req.Method + " other"
+}
+
+// Limit is the number of requests allowed in an interval; a zero Limit disables rate limiting.
+type Limit struct {
+	Requests int
+	Interval time.Duration
+}
+
+// RetryPolicy configures how failed requests are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts for a request, including the first one.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry; it doubles on every retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between retries; a longer Retry-After is not waited for.
+	MaxBackoff time.Duration
+}
+
+// DefaultRetryPolicy returns the retry policy used when none is configured.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    defaultMaxAttempts,
+		InitialBackoff: defaultInitialBackoff,
+		MaxBackoff:     defaultMaxBackoff,
+	}
+}
+
+// RateLimitedClient is a wrapper around http.Client that enforces rate limits and retries
+// requests failing with transient errors.
+type RateLimitedClient struct {
+	api     string
+	client  *http.Client
+	limiter *rate.Limiter
+	retry   RetryPolicy
+	sleep   func(ctx context.Context, d time.Duration) error
+}
+
+// NewRateLimitedClient creates a new RateLimitedClient for the named API; timeout limits each
+// attempt of a request and is disabled when zero.
+func NewRateLimitedClient(api string, limit Limit, retry RetryPolicy, timeout time.Duration) *RateLimitedClient {
+	limiter := rate.NewLimiter(rate.Inf, 0)
+	if limit.Requests > 0 {
+		limiter = rate.NewLimiter(rate.Every(limit.Interval/time.Duration(limit.Requests)), limit.Requests)
+	}
+	if retry.MaxAttempts <= 0 {
+		retry.MaxAttempts = 1
+	}
+	rlc := &RateLimitedClient{
+		api:     api,
+		client:  &http.Client{Timeout: timeout},
+		limiter: limiter,
+		retry:   retry,
+		sleep:   sleep,
+	}
+	return rlc
+}
+
+// UseTLS makes the client connect with the given TLS configuration, e.g. to trust a private
+// certificate authority or to present a client certificate; it must be called before UseCassette.
+func (rlc *RateLimitedClient) UseTLS(tlsConfig *tls.Config) {
+	transport := &http.Transport{Proxy: http.ProxyFromEnvironment}
+	if defaultTransport, ok := http.DefaultTransport.(*http.Transport); ok {
+		transport = defaultTransport.Clone()
+	}
+	transport.TLSClientConfig = tlsConfig
+	rlc.client.Transport = transport
+}
+
+// UseCassette makes the client send its requests through the transport of cassette, e.g. to record
+// them or to replay recorded responses.
+func (rlc *RateLimitedClient) UseCassette(cassette Cassette) {
+	next := rlc.client.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	rlc.client.Transport = cassette.Transport(rlc.api, next)
+}
+
+// Do sends an HTTP request and returns an HTTP response, respecting the rate limit.
+//
+// Requests that are idempotent or carry a RequestIDHeader are retried with jittered exponential
+// backoff on network errors, 429 and 5xx gateway errors, honouring the Retry-After header.
+func (rlc *RateLimitedClient) Do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	retryable := isRetryable(req)
+	endpoint := endpointOf(req)
+
+	for attempt := 1; ; attempt++ {
+		waitStart := time.Now()
+		err := rlc.limiter.Wait(ctx)
+		if err != nil {
+			return nil, err
+		}
+		metrics.ObserveRateLimiterWait(rlc.api, time.Since(waitStart))
+
+		attemptReq := req
+		if attempt > 1 {
+			attemptReq, err = rewind(req)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		start := time.Now()
+		resp, err := rlc.client.Do(attemptReq)
+		status := 0
+		if resp != nil {
+			status = resp.StatusCode
+		}
+		metrics.ObserveRequest(rlc.api, endpoint, status, time.Since(start))
+		if !retryable || attempt >= rlc.retry.MaxAttempts || !shouldRetry(resp, err) {
+			return resp, err
+		}
+
+		delay := rlc.backoff(attempt)
+		if resp != nil {
+			if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+				if retryAfter > rlc.retry.MaxBackoff {
+					return resp, nil
+				}
+				delay = retryAfter
+			}
+			io.Copy(io.Discard, resp.Body) //nolint:errcheck // the body is discarded before retrying
+			resp.Body.Close()
+		}
+
+		if err = rlc.sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// backoff returns the delay before the given retry, with jitter between half and the full delay.
+func (rlc *RateLimitedClient) backoff(attempt int) time.Duration {
+	delay := rlc.retry.InitialBackoff << (attempt - 1)
+	if delay <= 0 || delay > rlc.retry.MaxBackoff {
+		delay = rlc.retry.MaxBackoff
+	}
+	half := int64(delay / 2) //nolint:gomnd // jitter between half and the full delay
+	if half <= 0 {
+		return delay
+	}
+	return time.Duration(half + rand.Int63n(half+1)) //nolint:gosec // jitter does not need a secure source
+}
+
+func isRetryable(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+	default:
+		return req.Header.Get(RequestIDHeader) != "" && (req.Body == nil || req.GetBody != nil)
+	}
+}
+
+func shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+func rewind(req *http.Request) (*http.Request, error) {
+	clone := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		clone.Body = body
+	}
+	return clone, nil
+}
+
+// parseRetryAfter parses a Retry-After header expressed either in seconds or as an HTTP date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		delay := time.Until(date)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+	return 0, false
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
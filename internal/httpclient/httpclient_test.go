@@ -0,0 +1,150 @@
+package httpclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDoRetries(t *testing.T) {
+	testCases := []struct {
+		name             string
+		method           string
+		requestID        string
+		statuses         []int
+		retryAfter       string
+		expectedStatus   int
+		expectedAttempts int
+		expectedDelays   []time.Duration
+	}{
+		{
+			name:             "Retry GET on service unavailable",
+			method:           http.MethodGet,
+			statuses:         []int{http.StatusServiceUnavailable, http.StatusOK},
+			expectedStatus:   http.StatusOK,
+			expectedAttempts: 2,
+		},
+		{
+			name:             "Give up after max attempts",
+			method:           http.MethodGet,
+			statuses:         []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway, http.StatusOK},
+			expectedStatus:   http.StatusBadGateway,
+			expectedAttempts: 3,
+		},
+		{
+			name:             "Do not retry client errors",
+			method:           http.MethodGet,
+			statuses:         []int{http.StatusNotFound, http.StatusOK},
+			expectedStatus:   http.StatusNotFound,
+			expectedAttempts: 1,
+		},
+		{
+			name:             "Do not retry POST without request ID",
+			method:           http.MethodPost,
+			statuses:         []int{http.StatusServiceUnavailable, http.StatusOK},
+			expectedStatus:   http.StatusServiceUnavailable,
+			expectedAttempts: 1,
+		},
+		{
+			name:             "Retry POST with request ID honouring Retry-After",
+			method:           http.MethodPost,
+			requestID:        "1234",
+			statuses:         []int{http.StatusTooManyRequests, http.StatusOK},
+			retryAfter:       "2",
+			expectedStatus:   http.StatusOK,
+			expectedAttempts: 2,
+			expectedDelays:   []time.Duration{2 * time.Second},
+		},
+		{
+			name:             "Do not wait for Retry-After longer than max backoff",
+			method:           http.MethodGet,
+			statuses:         []int{http.StatusTooManyRequests, http.StatusOK},
+			retryAfter:       "3600",
+			expectedStatus:   http.StatusTooManyRequests,
+			expectedAttempts: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			attempts := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodPost {
+					body, err := io.ReadAll(r.Body)
+					assert.NoError(t, err)
+					assert.Equal(t, "payload", string(body))
+				}
+				if tc.retryAfter != "" {
+					w.Header().Set("Retry-After", tc.retryAfter)
+				}
+				w.WriteHeader(tc.statuses[attempts])
+				attempts++
+			}))
+			defer server.Close()
+
+			client := NewRateLimitedClient("test", Limit{}, RetryPolicy{
+				MaxAttempts:    3,
+				InitialBackoff: time.Millisecond,
+				MaxBackoff:     time.Minute,
+			}, 0)
+			var delays []time.Duration
+			client.sleep = func(_ context.Context, d time.Duration) error {
+				delays = append(delays, d)
+				return nil
+			}
+
+			req, err := http.NewRequestWithContext(context.Background(), tc.method, server.URL,
+				strings.NewReader("payload"))
+			assert.NoError(t, err)
+			if tc.requestID != "" {
+				req.Header.Set(RequestIDHeader, tc.requestID)
+			}
+
+			resp, err := client.Do(req)
+			assert.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tc.expectedStatus, resp.StatusCode)
+			assert.Equal(t, tc.expectedAttempts, attempts)
+			if tc.expectedDelays != nil {
+				assert.Equal(t, tc.expectedDelays, delays)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	client := NewRateLimitedClient("test", Limit{}, RetryPolicy{
+		MaxAttempts:    10,
+		InitialBackoff: time.Second,
+		MaxBackoff:     5 * time.Second,
+	}, 0)
+
+	for attempt, maxDelay := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second} {
+		delay := client.backoff(attempt + 1)
+		assert.GreaterOrEqual(t, delay, maxDelay/2)
+		assert.LessOrEqual(t, delay, maxDelay)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	delay, ok := parseRetryAfter("10")
+	assert.True(t, ok)
+	assert.Equal(t, 10*time.Second, delay)
+
+	_, ok = parseRetryAfter("")
+	assert.False(t, ok)
+
+	_, ok = parseRetryAfter("soon")
+	assert.False(t, ok)
+
+	delay, ok = parseRetryAfter(time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	assert.True(t, ok)
+	assert.Greater(t, delay, 59*time.Minute)
+}
@@ -0,0 +1,70 @@
+package jira
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/fabiocorneti/todoist-assistant/internal/config"
+)
+
+// displayKeys are the keys of the value shown for an object, such as an option or a user, if the
+// path of a field mapping ends on it.
+var displayKeys = []string{"value", "displayName", "name"}
+
+// mappedFields returns the IDs of the fields referenced by the field mappings of an instance.
+func mappedFields(jiraConfig config.JiraConfig) []string {
+	var ids []string
+	for _, mapping := range jiraConfig.FieldMappings {
+		ids = appendField(ids, mapping.FieldID())
+	}
+	return ids
+}
+
+// FieldValues returns the values of the field at path, such as customfield_10042 or
+// customfield_10042.child.value, among the custom fields of the issue. Every element of an array
+// is a value, and an object is shown by its value, displayName or name, so that options, cascading
+// selects and user pickers can be referenced by their ID alone; empty values are omitted.
+func (i *Issue) FieldValues(path string) []string {
+	segments := strings.Split(path, ".")
+	raw, ok := i.CustomFields[segments[0]]
+	if !ok {
+		return nil
+	}
+	var value any
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil
+	}
+	return collectValues(nil, value, segments[1:])
+}
+
+func collectValues(values []string, value any, path []string) []string {
+	switch v := value.(type) {
+	case []any:
+		for _, element := range v {
+			values = collectValues(values, element, path)
+		}
+	case map[string]any:
+		if len(path) > 0 {
+			return collectValues(values, v[path[0]], path[1:])
+		}
+		for _, key := range displayKeys {
+			if shown, ok := v[key]; ok {
+				return collectValues(values, shown, nil)
+			}
+		}
+	case string:
+		if len(path) == 0 && strings.TrimSpace(v) != "" {
+			values = append(values, strings.TrimSpace(v))
+		}
+	case float64:
+		if len(path) == 0 {
+			values = append(values, strconv.FormatFloat(v, 'f', -1, 64))
+		}
+	case bool:
+		if len(path) == 0 {
+			values = append(values, strconv.FormatBool(v))
+		}
+	}
+	return values
+}
@@ -0,0 +1,78 @@
+package jira
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/fabiocorneti/todoist-assistant/internal/config"
+	"github.com/fabiocorneti/todoist-assistant/internal/httpclient"
+	"github.com/fabiocorneti/todoist-assistant/internal/jira/jiratest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIssueFieldValues(t *testing.T) {
+	issue := Issue{CustomFields: map[string]json.RawMessage{
+		"customfield_10016": json.RawMessage(`5.5`),
+		"customfield_10042": json.RawMessage(`{"id":"10100","value":"Acme"}`),
+		"customfield_10043": json.RawMessage(`[{"value":"Backend"},{"value":"Payments"}]`),
+		"customfield_10044": json.RawMessage(`{"value":"EU","child":{"value":"Italy"}}`),
+		"customfield_10045": json.RawMessage(`{"accountId":"5b10","displayName":"Jane Doe"}`),
+		"customfield_10046": json.RawMessage(`"  "`),
+		"customfield_10047": json.RawMessage(`true`),
+	}}
+
+	testCases := []struct {
+		path     string
+		expected []string
+	}{
+		{path: "customfield_10016", expected: []string{"5.5"}},
+		{path: "customfield_10042", expected: []string{"Acme"}},
+		{path: "customfield_10042.id", expected: []string{"10100"}},
+		{path: "customfield_10043", expected: []string{"Backend", "Payments"}},
+		{path: "customfield_10044", expected: []string{"EU"}},
+		{path: "customfield_10044.child", expected: []string{"Italy"}},
+		{path: "customfield_10045", expected: []string{"Jane Doe"}},
+		{path: "customfield_10045.emailAddress"},
+		{path: "customfield_10046"},
+		{path: "customfield_10047", expected: []string{"true"}},
+		{path: "customfield_99999"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			assert.Equal(t, tc.expected, issue.FieldValues(tc.path))
+		})
+	}
+}
+
+func TestFetchJiraIssuesFieldMappings(t *testing.T) {
+	server := jiratest.NewServer(t, "user@example.com", "secret")
+	server.AddIssue(jiratest.Issue{Key: "PRJ-1", Summary: "One", Status: "To Do", CustomFields: map[string]any{
+		"customfield_10042": map[string]string{"value": "Acme"},
+		"customfield_10050": map[string]string{"value": "Sev1"},
+	}})
+	jiraConfig := config.JiraConfig{
+		Site:     server.URL,
+		Username: "user@example.com",
+		Token:    "secret",
+		JQL:      "project = PRJ",
+		FieldMappings: []config.FieldMapping{
+			{Field: "customfield_10042.value", Label: "Jira/Customer"},
+			{Field: "customfield_10042", Description: "Customer"},
+		},
+	}
+	client, err := NewHTTPClient(jiraConfig, httpclient.RetryPolicy{}, time.Second)
+	require.NoError(t, err)
+
+	issues, err := FetchJiraIssues(context.Background(), client, jiraConfig)
+	require.NoError(t, err)
+	require.Len(t, issues, 1)
+	// only the referenced fields are requested
+	assert.Equal(t, map[string]json.RawMessage{"customfield_10042": json.RawMessage(`{"value":"Acme"}`)},
+		issues[0].CustomFields)
+	assert.Equal(t, []string{"Acme"}, issues[0].FieldValues("customfield_10042.value"))
+	assert.Empty(t, issues[0].FieldValues("customfield_10050"))
+}
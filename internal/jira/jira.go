@@ -1,20 +1,36 @@
 package jira
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/fabiocorneti/todoist-assistant/internal/config"
+	"github.com/fabiocorneti/todoist-assistant/internal/httpclient"
 )
 
 const (
-	fields = "key,summary,status,labels,components,priority"
+	fields         = "key,summary,status,labels,components,priority,duedate,fixVersions"
+	searchEndpoint = "GET search"
+
+	getTransitionsEndpoint  = "GET transitions"
+	postTransitionsEndpoint = "POST transitions"
+
+	cloudAPIPath      = "/rest/api/3"
+	dataCenterAPIPath = "/rest/api/2"
 )
 
+// sprintAttribute matches the start of an attribute of a sprint returned as a string by Jira Data
+// Center, such as com.atlassian.greenhopper.service.sprint.Sprint@1a2b[id=1,state=ACTIVE,name=...].
+var sprintAttribute = regexp.MustCompile(`(?:^|,)(\w+)=`)
+
 type Issue struct {
 	Key    string `json:"key"`
 	Fields struct {
@@ -29,51 +45,198 @@ type Issue struct {
 		Priority struct {
 			Name string `json:"name"`
 		} `json:"priority"`
+		// DueDate is the due date of the issue, such as 2024-03-05, or empty.
+		DueDate     string    `json:"duedate"`
+		FixVersions []Version `json:"fixVersions"`
 	} `json:"fields"`
+	// Sprints are decoded from the sprint field of the instance, and only if requested.
+	Sprints []Sprint `json:"sprints,omitempty"`
+	// CustomFields are the values of the fields referenced by the field mappings of the instance,
+	// by field ID; fields without a value are omitted.
+	CustomFields map[string]json.RawMessage `json:"customFields,omitempty"`
+}
+
+// Version is a fix version of an issue; ReleaseDate is empty if the version has no release date.
+type Version struct {
+	Name        string `json:"name"`
+	ReleaseDate string `json:"releaseDate"`
+}
+
+// Sprint is a sprint of an issue; its State is active, closed or future.
+type Sprint struct {
+	Name    string `json:"name"`
+	State   string `json:"state"`
+	EndDate string `json:"endDate"`
+}
+
+// DueDate returns the due date of the issue from the first of the given sources having one, in
+// the YYYY-MM-DD format; it returns an empty string if none of them has a due date.
+func (i *Issue) DueDate(sources []string) string {
+	for _, source := range sources {
+		var date string
+		switch source {
+		case config.DueDateSourceDueDate:
+			date = i.Fields.DueDate
+		case config.DueDateSourceSprint:
+			date = i.sprintEndDate()
+		case config.DueDateSourceFixVersion:
+			date = i.releaseDate()
+		}
+		if date != "" {
+			return date
+		}
+	}
+	return ""
 }
 
-func FetchJiraIssues(jiraConfig config.JiraConfig) ([]Issue, error) {
+// sprintEndDate returns the local date at which the active sprint of the issue ends.
+func (i *Issue) sprintEndDate() string {
+	for _, sprint := range i.Sprints {
+		if sprint.State != "active" || sprint.EndDate == "" {
+			continue
+		}
+		end, err := time.Parse(time.RFC3339, sprint.EndDate)
+		if err != nil {
+			continue
+		}
+		return end.Local().Format(time.DateOnly)
+	}
+	return ""
+}
+
+// releaseDate returns the earliest release date of the fix versions of the issue.
+func (i *Issue) releaseDate() string {
+	earliest := ""
+	for _, version := range i.Fields.FixVersions {
+		if version.ReleaseDate != "" && (earliest == "" || version.ReleaseDate < earliest) {
+			earliest = version.ReleaseDate
+		}
+	}
+	return earliest
+}
+
+// searchFields returns the fields requested to search the issues of an instance.
+func searchFields(jiraConfig config.JiraConfig) string {
+	requested := strings.Split(fields, ",")
+	if requestsSprints(jiraConfig) {
+		requested = appendField(requested, jiraConfig.SprintFieldID())
+	}
+	for _, id := range mappedFields(jiraConfig) {
+		requested = appendField(requested, id)
+	}
+	return strings.Join(requested, ",")
+}
+
+func appendField(requested []string, id string) []string {
+	for _, field := range requested {
+		if field == id {
+			return requested
+		}
+	}
+	return append(requested, id)
+}
+
+// requestsSprints returns whether the sprint field is requested, which is only the case if sprints
+// are a source of due dates.
+func requestsSprints(jiraConfig config.JiraConfig) bool {
+	for _, source := range jiraConfig.DueDateSources {
+		if source == config.DueDateSourceSprint {
+			return true
+		}
+	}
+	return false
+}
+
+// NewHTTPClient returns a client suitable for FetchJiraIssues and TransitionIssue on an instance,
+// trusting its certificate authorities and presenting its client certificate if configured.
+func NewHTTPClient(jiraConfig config.JiraConfig, retry httpclient.RetryPolicy,
+	requestTimeout time.Duration) (*httpclient.RateLimitedClient, error) {
+	client := httpclient.NewRateLimitedClient("jira", httpclient.Limit{}, retry, requestTimeout)
+	tlsConfig, err := jiraConfig.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		client.UseTLS(tlsConfig)
+	}
+	return client, nil
+}
+
+// BrowseURL returns the URL of the page of an issue on the site of an instance.
+func BrowseURL(jiraConfig config.JiraConfig, key string) string {
+	return strings.TrimSuffix(jiraConfig.Site, "/") + "/browse/" + key
+}
+
+// apiURL returns the URL of the REST API of an instance, version 3 on Jira Cloud and version 2,
+// the latest available, on Jira Data Center.
+func apiURL(jiraConfig config.JiraConfig) string {
+	if jiraConfig.DataCenter() {
+		return strings.TrimSuffix(jiraConfig.Site, "/") + dataCenterAPIPath
+	}
+	return strings.TrimSuffix(jiraConfig.Site, "/") + cloudAPIPath
+}
+
+// authenticate sets the credentials of an instance on a request: a personal access token as bearer
+// token or, with a username, basic authentication.
+func authenticate(req *http.Request, jiraConfig config.JiraConfig) {
+	if jiraConfig.BearerAuth() {
+		req.Header.Set("Authorization", "Bearer "+jiraConfig.Token)
+		return
+	}
+	req.SetBasicAuth(jiraConfig.Username, jiraConfig.Token)
+}
+
+func FetchJiraIssues(ctx context.Context, client *httpclient.RateLimitedClient,
+	jiraConfig config.JiraConfig) ([]Issue, error) {
 	var allIssues []Issue
 	startAt := 0
 	maxResults := 50
+	ctx = httpclient.WithEndpoint(ctx, searchEndpoint)
 
 	for {
 		encodedJQL := url.QueryEscape(jiraConfig.JQL)
 
-		requestURL := fmt.Sprintf("%s/rest/api/3/search?jql=%s&startAt=%d&maxResults=%d&fields=%s",
-			jiraConfig.Site, encodedJQL, startAt, maxResults, fields)
-		req, _ := http.NewRequestWithContext(context.TODO(), http.MethodGet, requestURL, nil)
-		req.SetBasicAuth(jiraConfig.Username, jiraConfig.Token)
+		requestURL := fmt.Sprintf("%s/search?jql=%s&startAt=%d&maxResults=%d&fields=%s",
+			apiURL(jiraConfig), encodedJQL, startAt, maxResults, searchFields(jiraConfig))
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+		authenticate(req, jiraConfig)
 
-		client := &http.Client{}
 		resp, err := client.Do(req)
 		if err != nil {
 			return nil, err
 		}
 
-		if resp.StatusCode != http.StatusOK {
-			return nil, fmt.Errorf("error from Jira API: %s", resp.Status)
+		if err = httpclient.CheckResponse(resp, searchEndpoint); err != nil {
+			return nil, err
 		}
 
 		body, err := io.ReadAll(io.Reader(resp.Body))
+		resp.Body.Close()
 		if err != nil {
 			return nil, err
 		}
 		var response struct {
-			Issues     []Issue `json:"issues"`
-			Total      int     `json:"total"`
-			MaxResults int     `json:"maxResults"`
-			StartAt    int     `json:"startAt"`
+			Issues     []json.RawMessage `json:"issues"`
+			Total      int               `json:"total"`
+			MaxResults int               `json:"maxResults"`
+			StartAt    int               `json:"startAt"`
 		}
 		err = json.Unmarshal(body, &response)
 		if err != nil {
 			return nil, err
 		}
-		resp.Body.Close()
 
-		allIssues = append(allIssues, response.Issues...)
+		for _, raw := range response.Issues {
+			issue, err := decodeIssue(raw, jiraConfig)
+			if err != nil {
+				return nil, err
+			}
+			allIssues = append(allIssues, issue)
+		}
 
-		if startAt+len(response.Issues) >= response.Total {
+		// an empty page ends the search even if the total says otherwise, e.g. when issues are
+		// moved out of the results while paginating
+		if len(response.Issues) == 0 || startAt+len(response.Issues) >= response.Total {
 			break
 		}
 		startAt += len(response.Issues)
@@ -81,3 +244,168 @@ func FetchJiraIssues(jiraConfig config.JiraConfig) ([]Issue, error) {
 
 	return allIssues, nil
 }
+
+// decodeIssue decodes an issue returned by the search, including its sprints and the fields of its
+// field mappings if they were requested.
+func decodeIssue(raw json.RawMessage, jiraConfig config.JiraConfig) (Issue, error) {
+	var issue Issue
+	if err := json.Unmarshal(raw, &issue); err != nil {
+		return Issue{}, err
+	}
+	mapped := mappedFields(jiraConfig)
+	if !requestsSprints(jiraConfig) && len(mapped) == 0 {
+		return issue, nil
+	}
+	var custom struct {
+		Fields map[string]json.RawMessage `json:"fields"`
+	}
+	if err := json.Unmarshal(raw, &custom); err != nil {
+		return Issue{}, err
+	}
+
+	for _, id := range mapped {
+		if value, ok := custom.Fields[id]; ok && string(value) != "null" {
+			if issue.CustomFields == nil {
+				issue.CustomFields = make(map[string]json.RawMessage)
+			}
+			issue.CustomFields[id] = value
+		}
+	}
+	if !requestsSprints(jiraConfig) {
+		return issue, nil
+	}
+	sprintField := jiraConfig.SprintFieldID()
+	if value, ok := custom.Fields[sprintField]; ok && string(value) != "null" {
+		sprints, err := decodeSprints(value)
+		if err != nil {
+			return Issue{}, fmt.Errorf("error decoding the sprints of issue %s from field %s: %w",
+				issue.Key, sprintField, err)
+		}
+		issue.Sprints = sprints
+	}
+	return issue, nil
+}
+
+// decodeSprints decodes the value of the sprint field, a list of objects on Jira Cloud and of strings
+// on older versions of Jira Data Center.
+func decodeSprints(value json.RawMessage) ([]Sprint, error) {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(value, &raw); err != nil {
+		return nil, err
+	}
+	sprints := make([]Sprint, 0, len(raw))
+	for _, item := range raw {
+		var text string
+		if err := json.Unmarshal(item, &text); err == nil {
+			sprint, err := parseSprint(text)
+			if err != nil {
+				return nil, err
+			}
+			sprints = append(sprints, sprint)
+			continue
+		}
+		var sprint Sprint
+		if err := json.Unmarshal(item, &sprint); err != nil {
+			return nil, err
+		}
+		sprints = append(sprints, sprint)
+	}
+	return sprints, nil
+}
+
+// parseSprint parses a sprint returned as a string by Jira Data Center; its attributes are
+// separated by commas, which the name may contain too, and missing values are <null>.
+func parseSprint(text string) (Sprint, error) {
+	start, end := strings.Index(text, "["), strings.LastIndex(text, "]")
+	if start < 0 || end < start {
+		return Sprint{}, fmt.Errorf("unexpected sprint %q", text)
+	}
+	attributes := text[start+1 : end]
+	bounds := sprintAttribute.FindAllStringSubmatchIndex(attributes, -1)
+	var sprint Sprint
+	for i, bound := range bounds {
+		valueEnd := len(attributes)
+		if i+1 < len(bounds) {
+			valueEnd = bounds[i+1][0]
+		}
+		value := attributes[bound[1]:valueEnd]
+		if value == "<null>" {
+			value = ""
+		}
+		switch attributes[bound[2]:bound[3]] {
+		case "name":
+			sprint.Name = value
+		case "state":
+			sprint.State = strings.ToLower(value)
+		case "endDate":
+			sprint.EndDate = value
+		}
+	}
+	return sprint, nil
+}
+
+// Transition is a transition of the workflow of an issue.
+type Transition struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	To   struct {
+		Name string `json:"name"`
+	} `json:"to"`
+}
+
+// TransitionIssue runs the transition with the given name, matched regardless of case, on an issue.
+// It returns an error if the transition is not available from the current status of the issue.
+func TransitionIssue(ctx context.Context, client *httpclient.RateLimitedClient, jiraConfig config.JiraConfig,
+	key, name string) error {
+	requestURL := fmt.Sprintf("%s/issue/%s/transitions", apiURL(jiraConfig), url.PathEscape(key))
+
+	req, _ := http.NewRequestWithContext(httpclient.WithEndpoint(ctx, getTransitionsEndpoint), http.MethodGet,
+		requestURL, nil)
+	authenticate(req, jiraConfig)
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	if err = httpclient.CheckResponse(resp, getTransitionsEndpoint); err != nil {
+		return err
+	}
+	var response struct {
+		Transitions []Transition `json:"transitions"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&response)
+	resp.Body.Close()
+	if err != nil {
+		return err
+	}
+
+	var transition *Transition
+	available := make([]string, 0, len(response.Transitions))
+	for i := range response.Transitions {
+		if strings.EqualFold(response.Transitions[i].Name, name) {
+			transition = &response.Transitions[i]
+		}
+		available = append(available, response.Transitions[i].Name)
+	}
+	if transition == nil {
+		return fmt.Errorf("transition %s is not available for issue %s, only %s", name, key,
+			strings.Join(available, ", "))
+	}
+
+	body, err := json.Marshal(map[string]any{"transition": map[string]string{"id": transition.ID}})
+	if err != nil {
+		return err
+	}
+	req, _ = http.NewRequestWithContext(httpclient.WithEndpoint(ctx, postTransitionsEndpoint), http.MethodPost,
+		requestURL, bytes.NewReader(body))
+	authenticate(req, jiraConfig)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = client.Do(req)
+	if err != nil {
+		return err
+	}
+	if err = httpclient.CheckResponse(resp, postTransitionsEndpoint); err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
@@ -0,0 +1,302 @@
+package jira
+
+import (
+	"context"
+	"crypto/x509"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/fabiocorneti/todoist-assistant/internal/config"
+	"github.com/fabiocorneti/todoist-assistant/internal/httpclient"
+	"github.com/fabiocorneti/todoist-assistant/internal/jira/jiratest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchJiraIssues(t *testing.T) {
+	testCases := []struct {
+		name          string
+		jql           string
+		token         string
+		maxResults    int
+		setup         func(server *jiratest.Server)
+		expectedKeys  []string
+		expectedPages int
+		expectedError bool
+	}{
+		{
+			name:          "All issues in one page",
+			jql:           "project = PRJ ORDER BY key",
+			expectedKeys:  []string{"PRJ-1", "PRJ-2", "PRJ-3", "PRJ-4", "PRJ-5"},
+			expectedPages: 1,
+		},
+		{
+			name:          "Issues in several pages",
+			jql:           "project = PRJ",
+			maxResults:    2,
+			expectedKeys:  []string{"PRJ-1", "PRJ-2", "PRJ-3", "PRJ-4", "PRJ-5"},
+			expectedPages: 3,
+		},
+		{
+			name:          "Filtered issues",
+			jql:           `project = PRJ AND status not in (Done, "Won't Do") AND labels = backend`,
+			expectedKeys:  []string{"PRJ-1", "PRJ-3"},
+			expectedPages: 1,
+		},
+		{
+			name: "Unavailable server is retried",
+			jql:  "project = PRJ AND status = Done",
+			setup: func(server *jiratest.Server) {
+				server.InjectError(http.StatusServiceUnavailable, 1)
+			},
+			expectedKeys:  []string{"PRJ-4"},
+			expectedPages: 2,
+		},
+		{
+			name:          "Wrong token",
+			jql:           "project = PRJ",
+			token:         "wrong",
+			expectedError: true,
+		},
+		{
+			name:          "Unsupported JQL",
+			jql:           "assignee = currentUser()",
+			expectedError: true,
+			expectedPages: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := jiratest.NewServer(t, "user@example.com", "secret")
+			server.AddIssue(jiratest.Issue{Key: "PRJ-1", Summary: "One", Status: "To Do", Labels: []string{"backend"}})
+			server.AddIssue(jiratest.Issue{Key: "PRJ-2", Summary: "Two", Status: "In Progress"})
+			server.AddIssue(jiratest.Issue{Key: "PRJ-3", Summary: "Three", Status: "To Do", Labels: []string{"backend"}})
+			server.AddIssue(jiratest.Issue{Key: "PRJ-4", Summary: "Four", Status: "Done", Labels: []string{"backend"}})
+			server.AddIssue(jiratest.Issue{Key: "PRJ-5", Summary: "Five", Status: "Won't Do", Labels: []string{"backend"}})
+			server.AddIssue(jiratest.Issue{Key: "OPS-1", Summary: "Other", Status: "To Do"})
+			if tc.maxResults > 0 {
+				server.SetMaxResults(tc.maxResults)
+			}
+			if tc.setup != nil {
+				tc.setup(server)
+			}
+			token := tc.token
+			if token == "" {
+				token = "secret"
+			}
+
+			jiraConfig := config.JiraConfig{
+				Site:     server.URL,
+				Username: "user@example.com",
+				Token:    token,
+				JQL:      tc.jql,
+			}
+			client, err := NewHTTPClient(jiraConfig, httpclient.RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond},
+				time.Second)
+			require.NoError(t, err)
+			issues, err := FetchJiraIssues(context.Background(), client, jiraConfig)
+			assert.Len(t, server.Queries(), tc.expectedPages)
+			if tc.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			keys := make([]string, 0, len(issues))
+			for _, issue := range issues {
+				keys = append(keys, issue.Key)
+			}
+			assert.Equal(t, tc.expectedKeys, keys)
+		})
+	}
+}
+
+func TestIssueDueDate(t *testing.T) {
+	var issue Issue
+	issue.Fields.DueDate = "2024-03-20"
+	issue.Fields.FixVersions = []Version{
+		{Name: "2.0"},
+		{Name: "1.2", ReleaseDate: "2024-04-02"},
+		{Name: "1.1", ReleaseDate: "2024-03-27"},
+	}
+	issue.Sprints = []Sprint{
+		{Name: "Sprint 1", State: "closed", EndDate: "2024-03-01T12:00:00.000Z"},
+		{Name: "Sprint 2", State: "active", EndDate: "2024-03-15T12:00:00.000Z"},
+	}
+	noDates := Issue{}
+
+	testCases := []struct {
+		name     string
+		issue    Issue
+		sources  []string
+		expected string
+	}{
+		{
+			name:     "Due date",
+			issue:    issue,
+			sources:  []string{config.DueDateSourceDueDate, config.DueDateSourceSprint},
+			expected: "2024-03-20",
+		},
+		{
+			name:     "End of the active sprint",
+			issue:    issue,
+			sources:  []string{config.DueDateSourceSprint, config.DueDateSourceDueDate},
+			expected: "2024-03-15",
+		},
+		{
+			name:     "Earliest release of the fix versions",
+			issue:    issue,
+			sources:  []string{config.DueDateSourceFixVersion},
+			expected: "2024-03-27",
+		},
+		{
+			name:     "Fallback to the next source",
+			issue:    Issue{Sprints: issue.Sprints},
+			sources:  []string{config.DueDateSourceDueDate, config.DueDateSourceFixVersion, config.DueDateSourceSprint},
+			expected: "2024-03-15",
+		},
+		{
+			name:    "No due date",
+			issue:   noDates,
+			sources: []string{config.DueDateSourceDueDate, config.DueDateSourceSprint, config.DueDateSourceFixVersion},
+		},
+		{
+			name:  "No sources",
+			issue: issue,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.issue.DueDate(tc.sources))
+		})
+	}
+}
+
+func TestFetchJiraIssuesSprints(t *testing.T) {
+	server := jiratest.NewServer(t, "user@example.com", "secret")
+	server.AddIssue(jiratest.Issue{
+		Key: "PRJ-1", Summary: "One", Status: "To Do", DueDate: "2024-03-20",
+		FixVersions: []jiratest.Version{{Name: "1.1", ReleaseDate: "2024-03-27"}},
+		Sprints:     []jiratest.Sprint{{Name: "Sprint 2", State: "active", EndDate: "2024-03-15T12:00:00.000Z"}},
+	})
+	server.AddIssue(jiratest.Issue{Key: "PRJ-2", Summary: "Two", Status: "To Do"})
+	jiraConfig := config.JiraConfig{
+		Site:     server.URL,
+		Username: "user@example.com",
+		Token:    "secret",
+		JQL:      "project = PRJ ORDER BY key",
+	}
+	client, err := NewHTTPClient(jiraConfig, httpclient.RetryPolicy{}, time.Second)
+	require.NoError(t, err)
+
+	issues, err := FetchJiraIssues(context.Background(), client, jiraConfig)
+	require.NoError(t, err)
+	require.Len(t, issues, 2)
+	assert.Equal(t, "2024-03-20", issues[0].Fields.DueDate)
+	assert.Equal(t, []Version{{Name: "1.1", ReleaseDate: "2024-03-27"}}, issues[0].Fields.FixVersions)
+	assert.Empty(t, issues[0].Sprints, "sprints are only requested if they are a source of due dates")
+
+	jiraConfig.DueDateSources = []string{config.DueDateSourceSprint}
+	issues, err = FetchJiraIssues(context.Background(), client, jiraConfig)
+	require.NoError(t, err)
+	require.Len(t, issues, 2)
+	assert.Equal(t, []Sprint{{Name: "Sprint 2", State: "active", EndDate: "2024-03-15T12:00:00.000Z"}},
+		issues[0].Sprints)
+	assert.Empty(t, issues[1].Sprints)
+	assert.Equal(t, "2024-03-15", issues[0].DueDate(jiraConfig.DueDateSources))
+}
+
+func TestDataCenter(t *testing.T) {
+	certFile, keyFile, clientCAs := jiratest.ClientCertificate(t)
+	testCases := []struct {
+		name          string
+		clientCAs     bool
+		caCertFile    bool
+		clientCert    bool
+		username      string
+		expectedError bool
+	}{
+		{
+			name:       "Personal access token",
+			caCertFile: true,
+		},
+		{
+			name:       "Client certificate",
+			clientCAs:  true,
+			caCertFile: true,
+			clientCert: true,
+		},
+		{
+			name:          "Missing client certificate",
+			clientCAs:     true,
+			caCertFile:    true,
+			expectedError: true,
+		},
+		{
+			name:          "Untrusted server",
+			expectedError: true,
+		},
+		{
+			name:          "Basic authentication",
+			caCertFile:    true,
+			username:      "user",
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var pool *x509.CertPool
+			if tc.clientCAs {
+				pool = clientCAs
+			}
+			server := jiratest.NewDataCenterServer(t, "pat", pool)
+			server.AddIssue(jiratest.Issue{
+				Key: "PRJ-1", Summary: "One", Status: "To Do",
+				Sprints: []jiratest.Sprint{
+					{Name: "Sprint 1", State: "closed", EndDate: "2024-03-01T12:00:00.000Z"},
+					{Name: "Sprint 2, the second", State: "active", EndDate: "2024-03-15T12:00:00.000Z"},
+					{Name: "Sprint 3", State: "future"},
+				},
+			})
+			server.AddTransition("Done", "Done")
+			jiraConfig := config.JiraConfig{
+				Site:           server.URL + "/",
+				Flavor:         config.FlavorDataCenter,
+				Username:       tc.username,
+				Token:          "pat",
+				JQL:            "project = PRJ",
+				DueDateSources: []string{config.DueDateSourceSprint},
+			}
+			if tc.caCertFile {
+				jiraConfig.CACertFile = server.CACertFile(t)
+			}
+			if tc.clientCert {
+				jiraConfig.ClientCertFile, jiraConfig.ClientKeyFile = certFile, keyFile
+			}
+			client, err := NewHTTPClient(jiraConfig, httpclient.RetryPolicy{}, time.Second)
+			require.NoError(t, err)
+
+			issues, err := FetchJiraIssues(context.Background(), client, jiraConfig)
+			if tc.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, issues, 1)
+			assert.Equal(t, []Sprint{
+				{Name: "Sprint 1", State: "closed", EndDate: "2024-03-01T12:00:00.000Z"},
+				{Name: "Sprint 2, the second", State: "active", EndDate: "2024-03-15T12:00:00.000Z"},
+				{Name: "Sprint 3", State: "future"},
+			}, issues[0].Sprints)
+			assert.Equal(t, "2024-03-15", issues[0].DueDate(jiraConfig.DueDateSources))
+			assert.Equal(t, server.URL+"/browse/PRJ-1", BrowseURL(jiraConfig, "PRJ-1"))
+
+			require.NoError(t, TransitionIssue(context.Background(), client, jiraConfig, "PRJ-1", "done"))
+			issue, _ := server.Issue("PRJ-1")
+			assert.Equal(t, "Done", issue.Status)
+		})
+	}
+}
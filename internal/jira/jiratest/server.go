@@ -0,0 +1,517 @@
+// Package jiratest provides an in-memory fake of the Jira Cloud and Data Center search and transitions
+// APIs for tests.
+package jiratest
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/fabiocorneti/todoist-assistant/internal/config"
+)
+
+const (
+	cloudAPIPath      = "/rest/api/3"
+	dataCenterAPIPath = "/rest/api/2"
+	searchPath        = "/search"
+	issuePrefix       = "/issue/"
+	transitionsSuffix = "/transitions"
+	defaultMaxResults = 50
+)
+
+// Issue is an issue stored by the fake server.
+type Issue struct {
+	Key        string
+	Summary    string
+	Status     string
+	Priority   string
+	Labels     []string
+	Components []string
+	// DueDate is the due date of the issue, such as 2024-03-05.
+	DueDate     string
+	FixVersions []Version
+	// Sprints are returned in the config.DefaultSprintField custom field, if it is requested.
+	Sprints []Sprint
+	// CustomFields are the values of other fields by ID, returned if they are requested.
+	CustomFields map[string]any
+}
+
+// Version is a fix version of an issue.
+type Version struct {
+	Name        string `json:"name"`
+	ReleaseDate string `json:"releaseDate,omitempty"`
+}
+
+// Sprint is a sprint of an issue; State is active, closed or future, and EndDate is in the RFC
+// 3339 format. A Data Center server returns sprints as strings, like older versions of Jira Server.
+type Sprint struct {
+	Name    string `json:"name"`
+	State   string `json:"state"`
+	EndDate string `json:"endDate,omitempty"`
+}
+
+// project returns the project key of the issue, e.g. PRJ for PRJ-1.
+func (i Issue) project() string {
+	if index := strings.LastIndex(i.Key, "-"); index > 0 {
+		return i.Key[:index]
+	}
+	return i.Key
+}
+
+// Transition is a workflow transition, available on every issue whose status is not To.
+type Transition struct {
+	ID   string
+	Name string
+	To   string
+}
+
+type searchIssue struct {
+	Key    string         `json:"key"`
+	Fields map[string]any `json:"fields"`
+}
+
+type named struct {
+	Name string `json:"name"`
+}
+
+type failure struct {
+	status    int
+	remaining int
+}
+
+// Server is a fake of GET /rest/api/3/search and of the transitions of /rest/api/3/issue/{key} backed
+// by an httptest.Server, or of their /rest/api/2 equivalents for Data Center. Requests must use basic
+// authentication with the username and token of the server, or the token as bearer token if the
+// server has no username; search results are paginated with startAt and maxResults, and the jql
+// parameter is evaluated with Filter.
+type Server struct {
+	// URL is the URL to use as the Jira site.
+	URL string
+
+	server     *httptest.Server
+	username   string
+	token      string
+	apiPath    string
+	dataCenter bool
+
+	mu          sync.Mutex
+	issues      []Issue
+	transitions []Transition
+	maxResults  int
+	failures    []*failure
+	queries     []string
+}
+
+// NewServer starts a server accepting the given credentials, without issues; the server is
+// closed when the test ends.
+func NewServer(t testing.TB, username, token string) *Server {
+	s := &Server{username: username, token: token, apiPath: cloudAPIPath, maxResults: defaultMaxResults}
+	s.server = httptest.NewServer(s)
+	s.URL = s.server.URL
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+// NewDataCenterServer starts a server behaving like Jira Data Center, without issues: it serves
+// version 2 of the API over TLS with a certificate returned by CACertFile, accepts the token as a
+// personal access token and returns sprints as strings. If clientCAs is not nil, clients must
+// present a certificate signed by one of them. The server is closed when the test ends.
+func NewDataCenterServer(t testing.TB, token string, clientCAs *x509.CertPool) *Server {
+	s := &Server{token: token, apiPath: dataCenterAPIPath, dataCenter: true, maxResults: defaultMaxResults}
+	s.server = httptest.NewUnstartedServer(s)
+	if clientCAs != nil {
+		s.server.TLS = &tls.Config{
+			MinVersion: tls.VersionTLS12,
+			ClientAuth: tls.RequireAndVerifyClientCert,
+			ClientCAs:  clientCAs,
+		}
+	}
+	s.server.StartTLS()
+	s.URL = s.server.URL
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+// AddIssue adds an issue, or replaces the issue with the same key.
+func (s *Server) AddIssue(issue Issue) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.issues {
+		if s.issues[i].Key == issue.Key {
+			s.issues[i] = issue
+			return
+		}
+	}
+	s.issues = append(s.issues, issue)
+}
+
+// Issue returns the issue with the given key.
+func (s *Server) Issue(key string) (Issue, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, issue := range s.issues {
+		if issue.Key == key {
+			return issue, true
+		}
+	}
+	return Issue{}, false
+}
+
+// AddTransition adds a transition with the given name, moving issues to the status to.
+func (s *Server) AddTransition(name, to string) Transition {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	transition := Transition{ID: strconv.Itoa(len(s.transitions) + 1), Name: name, To: to}
+	s.transitions = append(s.transitions, transition)
+	return transition
+}
+
+// SetMaxResults caps the number of issues returned in a page, like Jira does for large requests.
+func (s *Server) SetMaxResults(maxResults int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxResults = maxResults
+}
+
+// InjectError makes the next count requests fail with status.
+func (s *Server) InjectError(status, count int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, &failure{status: status, remaining: count})
+}
+
+// Queries returns the JQL of the search requests received so far, one per page.
+func (s *Server) Queries() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.queries...)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.authorized(r) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	apiSearch, apiIssue := s.apiPath+searchPath, s.apiPath+issuePrefix
+	if r.Method == http.MethodGet && r.URL.Path == apiSearch {
+		s.queries = append(s.queries, r.URL.Query().Get("jql"))
+	}
+	if len(s.failures) > 0 {
+		f := s.failures[0]
+		if f.remaining--; f.remaining <= 0 {
+			s.failures = s.failures[1:]
+		}
+		http.Error(w, http.StatusText(f.status), f.status)
+		return
+	}
+
+	key := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, apiIssue), transitionsSuffix)
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == apiSearch:
+		s.search(w, r)
+	case strings.HasPrefix(r.URL.Path, apiIssue) && strings.HasSuffix(r.URL.Path, transitionsSuffix):
+		s.handleTransitions(w, r, key)
+	default:
+		http.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// authorized returns whether a request carries the credentials of the server.
+func (s *Server) authorized(r *http.Request) bool {
+	if s.username == "" {
+		return r.Header.Get("Authorization") == "Bearer "+s.token
+	}
+	username, token, ok := r.BasicAuth()
+	return ok && username == s.username && token == s.token
+}
+
+func (s *Server) search(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	matches, err := Filter(s.issues, query.Get("jql"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string][]string{"errorMessages": {err.Error()}})
+		return
+	}
+	startAt, _ := strconv.Atoi(query.Get("startAt"))       //nolint:errcheck // defaults to 0
+	maxResults, _ := strconv.Atoi(query.Get("maxResults")) //nolint:errcheck // defaults to the cap
+	if maxResults <= 0 || maxResults > s.maxResults {
+		maxResults = s.maxResults
+	}
+
+	page := []searchIssue{}
+	for i := startAt; i < len(matches) && i < startAt+maxResults; i++ {
+		page = append(page, toSearch(matches[i], strings.Split(query.Get("fields"), ","), s.dataCenter))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"startAt":    startAt,
+		"maxResults": maxResults,
+		"total":      len(matches),
+		"issues":     page,
+	})
+}
+
+func (s *Server) handleTransitions(w http.ResponseWriter, r *http.Request, key string) {
+	var issue *Issue
+	for i := range s.issues {
+		if s.issues[i].Key == key {
+			issue = &s.issues[i]
+		}
+	}
+	if issue == nil {
+		writeJSON(w, http.StatusNotFound, map[string][]string{"errorMessages": {"Issue does not exist"}})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		transitions := []map[string]any{}
+		for _, transition := range s.transitions {
+			if transition.To != issue.Status {
+				transitions = append(transitions, map[string]any{
+					"id": transition.ID, "name": transition.Name, "to": named{Name: transition.To},
+				})
+			}
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"transitions": transitions})
+	case http.MethodPost:
+		var request struct {
+			Transition struct {
+				ID string `json:"id"`
+			} `json:"transition"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string][]string{"errorMessages": {err.Error()}})
+			return
+		}
+		for _, transition := range s.transitions {
+			if transition.ID == request.Transition.ID && transition.To != issue.Status {
+				issue.Status = transition.To
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		writeJSON(w, http.StatusBadRequest, map[string][]string{"errorMessages": {"Transition is not valid"}})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// Filter returns the issues matching a JQL-ish query: clauses such as status = Done,
+// priority != Low, labels in (a, b) or component not in ("Front End") joined by AND, on the
+// key, project, summary, status, priority, labels and component fields. An ORDER BY clause is
+// ignored and an empty query matches all the issues.
+func Filter(issues []Issue, jql string) ([]Issue, error) {
+	if index := strings.Index(strings.ToUpper(jql), "ORDER BY"); index >= 0 {
+		jql = jql[:index]
+	}
+	var clauses []clause
+	if strings.TrimSpace(jql) != "" {
+		for _, text := range splitAnd(jql) {
+			parsed, err := parseClause(text)
+			if err != nil {
+				return nil, err
+			}
+			clauses = append(clauses, parsed)
+		}
+	}
+
+	var matches []Issue
+	for _, issue := range issues {
+		matched := true
+		for _, c := range clauses {
+			if !c.matches(issue) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			matches = append(matches, issue)
+		}
+	}
+	return matches, nil
+}
+
+type clause struct {
+	field  string
+	negate bool
+	values []string
+}
+
+func (c clause) matches(issue Issue) bool {
+	var actual []string
+	switch c.field {
+	case "key":
+		actual = []string{issue.Key}
+	case "project":
+		actual = []string{issue.project()}
+	case "summary":
+		actual = []string{issue.Summary}
+	case "status":
+		actual = []string{issue.Status}
+	case "priority":
+		actual = []string{issue.Priority}
+	case "labels":
+		actual = issue.Labels
+	case "component":
+		actual = issue.Components
+	}
+	found := false
+	for _, value := range actual {
+		for _, expected := range c.values {
+			if strings.EqualFold(value, expected) {
+				found = true
+			}
+		}
+	}
+	return found != c.negate
+}
+
+var fields = map[string]bool{
+	"key": true, "project": true, "summary": true, "status": true, "priority": true, "labels": true, "component": true,
+}
+
+func parseClause(text string) (clause, error) {
+	tokens := strings.Fields(text)
+	if len(tokens) < 3 { //nolint:gomnd // field, operator and value
+		return clause{}, fmt.Errorf("error in the JQL query: expected a clause, got %q", text)
+	}
+	c := clause{field: strings.ToLower(tokens[0])}
+	if !fields[c.field] {
+		return clause{}, fmt.Errorf("field '%s' does not exist or is not supported", tokens[0])
+	}
+
+	rest := strings.TrimSpace(strings.TrimPrefix(text, tokens[0]))
+	lower := strings.ToLower(rest)
+	switch {
+	case strings.HasPrefix(lower, "!="):
+		c.negate = true
+		c.values = []string{unquote(rest[2:])}
+	case strings.HasPrefix(lower, "="):
+		c.values = []string{unquote(rest[1:])}
+	case strings.HasPrefix(lower, "not in "):
+		c.negate = true
+		c.values = parseList(rest[len("not in "):])
+	case strings.HasPrefix(lower, "in "):
+		c.values = parseList(rest[len("in "):])
+	default:
+		return clause{}, fmt.Errorf("error in the JQL query: unsupported operator in %q", text)
+	}
+	return c, nil
+}
+
+func parseList(text string) []string {
+	text = strings.TrimSpace(text)
+	text = strings.TrimSuffix(strings.TrimPrefix(text, "("), ")")
+	var values []string
+	for _, value := range strings.Split(text, ",") {
+		values = append(values, unquote(value))
+	}
+	return values
+}
+
+func unquote(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// splitAnd splits a query on the AND keyword outside of quotes.
+func splitAnd(jql string) []string {
+	var clauses []string
+	var quote byte
+	start := 0
+	for i := 0; i < len(jql); i++ {
+		switch {
+		case quote != 0:
+			if jql[i] == quote {
+				quote = 0
+			}
+		case jql[i] == '"' || jql[i] == '\'':
+			quote = jql[i]
+		case i+5 <= len(jql) && strings.EqualFold(jql[i:i+5], " and "):
+			clauses = append(clauses, jql[start:i])
+			start = i + 5
+			i += 4
+		}
+	}
+	return append(clauses, jql[start:])
+}
+
+// toSearch returns an issue as returned by the search; the sprint field and the custom fields are
+// only returned if they are requested, like in Jira, and sprints are strings on Data Center.
+func toSearch(issue Issue, requested []string, dataCenter bool) searchIssue {
+	components := []named{}
+	for _, component := range issue.Components {
+		components = append(components, named{Name: component})
+	}
+	var dueDate any
+	if issue.DueDate != "" {
+		dueDate = issue.DueDate
+	}
+	result := searchIssue{
+		Key: issue.Key,
+		Fields: map[string]any{
+			"summary":     issue.Summary,
+			"status":      named{Name: issue.Status},
+			"priority":    named{Name: issue.Priority},
+			"labels":      append([]string{}, issue.Labels...),
+			"components":  components,
+			"duedate":     dueDate,
+			"fixVersions": append([]Version{}, issue.FixVersions...),
+		},
+	}
+	for _, field := range requested {
+		if field == config.DefaultSprintField {
+			var sprints any
+			switch {
+			case len(issue.Sprints) > 0 && dataCenter:
+				sprints = sprintStrings(issue.Sprints)
+			case len(issue.Sprints) > 0:
+				sprints = issue.Sprints
+			}
+			result.Fields[field] = sprints
+		}
+		if value, ok := issue.CustomFields[field]; ok {
+			result.Fields[field] = value
+		}
+	}
+	return result
+}
+
+// sprintStrings returns sprints in the format of the sprint field of older versions of Jira Server.
+func sprintStrings(sprints []Sprint) []string {
+	values := make([]string, 0, len(sprints))
+	for i, sprint := range sprints {
+		endDate := sprint.EndDate
+		if endDate == "" {
+			endDate = "<null>"
+		}
+		values = append(values, fmt.Sprintf("com.atlassian.greenhopper.service.sprint.Sprint@%x[id=%d,rapidViewId=1,"+
+			"state=%s,name=%s,startDate=<null>,endDate=%s,completeDate=<null>,sequence=%d]",
+			i+0x1f2e, i+1, strings.ToUpper(sprint.State), sprint.Name, endDate, i+1))
+	}
+	return values
+}
+
+// CACertFile writes the certificate of a Data Center server to a PEM file and returns its path.
+func (s *Server) CACertFile(t testing.TB) string {
+	return writePEM(t, "ca.pem", "CERTIFICATE", s.server.Certificate().Raw)
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value) //nolint:errcheck // the client sees a truncated response
+}
@@ -0,0 +1,120 @@
+// Package metrics holds the Prometheus metrics of the assistant.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "todoist_assistant"
+
+// Results of a run.
+const (
+	ResultSucceeded = "succeeded"
+	ResultFailed    = "failed"
+	ResultCancelled = "cancelled"
+)
+
+// Actions on the Todoist tasks linked to Jira issues, and on the issues of completed tasks.
+const (
+	TaskCreated       = "created"
+	TaskCompleted     = "completed"
+	TaskRelabelled    = "relabelled"
+	TaskRetitled      = "retitled"
+	TaskRescheduled   = "rescheduled"
+	TaskDescribed     = "described"
+	IssueTransitioned = "transitioned"
+)
+
+// statusError is the status of requests that failed without a response.
+const statusError = "error"
+
+var (
+	registry = prometheus.NewRegistry()
+
+	runDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "run_duration_seconds",
+		Help:      "Duration of the runs by result.",
+		Buckets:   []float64{1, 5, 15, 30, 60, 120, 300, 600, 1800},
+	}, []string{"result"})
+
+	lastSuccessfulRun = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "last_successful_run_timestamp_seconds",
+		Help:      "Time at which the last successful run ended.",
+	})
+
+	jiraTasks = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "jira_tasks_total",
+		Help:      "Todoist tasks created, completed and relabelled for Jira issues, by Jira instance.",
+	}, []string{"jira_site", "action"})
+
+	apiRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "api_requests_total",
+		Help:      "HTTP requests sent to the Todoist and Jira APIs, including retries.",
+	}, []string{"api", "endpoint", "status"})
+
+	apiRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "api_request_duration_seconds",
+		Help:      "Latency of the HTTP requests sent to the Todoist and Jira APIs.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"api", "endpoint", "status"})
+
+	rateLimiterWait = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "rate_limiter_wait_seconds",
+		Help:      "Time spent waiting for the rate limiter before sending a request.",
+		Buckets:   []float64{0.001, 0.01, 0.1, 0.5, 1, 2, 5, 10, 30},
+	}, []string{"api"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		runDuration, lastSuccessfulRun, jiraTasks, apiRequests, apiRequestDuration, rateLimiterWait,
+	)
+}
+
+// Handler returns the handler serving the metrics in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry})
+}
+
+// ObserveRun records a run that ended at end with the given result.
+func ObserveRun(duration time.Duration, result string, end time.Time) {
+	runDuration.WithLabelValues(result).Observe(duration.Seconds())
+	if result == ResultSucceeded {
+		lastSuccessfulRun.Set(float64(end.Unix()))
+	}
+}
+
+// CountJiraTask counts an action on the task linked to an issue of the Jira instance at site.
+func CountJiraTask(site, action string) {
+	jiraTasks.WithLabelValues(site, action).Inc()
+}
+
+// ObserveRequest records a request sent to an API endpoint, such as GET tasks/{id}; status is
+// the HTTP status code, or 0 if no response was received.
+func ObserveRequest(api, endpoint string, status int, duration time.Duration) {
+	statusLabel := statusError
+	if status > 0 {
+		statusLabel = strconv.Itoa(status)
+	}
+	apiRequests.WithLabelValues(api, endpoint, statusLabel).Inc()
+	apiRequestDuration.WithLabelValues(api, endpoint, statusLabel).Observe(duration.Seconds())
+}
+
+// ObserveRateLimiterWait records the time a request to an API waited for the rate limiter.
+func ObserveRateLimiterWait(api string, wait time.Duration) {
+	rateLimiterWait.WithLabelValues(api).Observe(wait.Seconds())
+}
@@ -1,74 +1,128 @@
 package process
 
 import (
+	"context"
 	"fmt"
-	"regexp"
 	"strings"
+	"time"
 
 	"github.com/fabiocorneti/todoist-assistant/internal/config"
+	"github.com/fabiocorneti/todoist-assistant/internal/httpclient"
 	"github.com/fabiocorneti/todoist-assistant/internal/jira"
+	"github.com/fabiocorneti/todoist-assistant/internal/metrics"
+	"github.com/fabiocorneti/todoist-assistant/internal/state"
 	"github.com/fabiocorneti/todoist-assistant/internal/todoist"
 	"github.com/fabiocorneti/todoist-assistant/internal/utils"
 	"github.com/sirupsen/logrus"
 )
 
 const (
-	jiraMatches = 2
+	// completedTasksLookback is how far back completed tasks are looked for when transitioning the
+	// issues of completed tasks; older links are treated as links to deleted tasks.
+	completedTasksLookback = 7 * 24 * time.Hour
 )
 
 type JiraProcess struct {
 	config        config.Config
-	logger        *logrus.Logger
+	logger        *logrus.Entry
 	todoistClient *todoist.Client
 	projects      []todoist.Project
+	store         *state.Store
+	cassette      httpclient.Cassette
+	// jiraClient is the client of the instance being processed.
+	jiraClient *httpclient.RateLimitedClient
+	// links are the issues synced by the run, recorded in the store by RecordLinks.
+	links *[]jiraLink
 }
 
-func NewJiraProcess(cfg config.Config, logger *logrus.Logger,
-	todoistClient *todoist.Client, projects []todoist.Project) *JiraProcess {
+// jiraLink is the state of an issue synced with its task, to be recorded in the store.
+type jiraLink struct {
+	site  string
+	key   string
+	issue state.JiraIssue
+}
+
+func NewJiraProcess(cfg config.Config, logger *logrus.Entry,
+	todoistClient *todoist.Client, projects []todoist.Project, store *state.Store) *JiraProcess {
 	process := JiraProcess{
 		config:        cfg,
 		logger:        logger,
 		todoistClient: todoistClient,
 		projects:      projects,
+		store:         store,
+		links:         &[]jiraLink{},
 	}
 	return &process
 }
 
-func (process JiraProcess) ProcessJiraInstances() {
+// UseCassette makes the Jira clients send their requests through cassette.
+func (process *JiraProcess) UseCassette(cassette httpclient.Cassette) {
+	process.cassette = cassette
+}
+
+// newJiraClient returns a client for the requests to a Jira instance.
+func (process JiraProcess) newJiraClient(jiraConfig config.JiraConfig) (*httpclient.RateLimitedClient, error) {
+	client, err := jira.NewHTTPClient(jiraConfig, process.config.RetryPolicy(), process.config.RequestTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("error configuring the Jira client: %w", err)
+	}
+	if process.cassette != nil {
+		client.UseCassette(process.cassette)
+	}
+	return client, nil
+}
+
+func (process JiraProcess) ProcessJiraInstances(ctx context.Context, summary *Summary) {
 	var err error
 
 	processedTasks := make(map[string]todoist.Task)
-	regexPattern := `\[([A-Z0-9-]+)\] .+\]\(.+\)$`
-	re := regexp.MustCompile(regexPattern)
 
 	var tasks []todoist.Task
 
 	process.logger.Info("Fetching Todoist tasks")
-	tasks, err = process.todoistClient.GetAllTasks()
+	tasks, err = process.todoistClient.GetAllTasks(ctx)
 	if err != nil {
-		process.logger.Errorf("Error fetching Todoist tasks: %v", err)
+		summary.fail(process.logger, ItemRun, "Todoist tasks", fmt.Errorf("error fetching Todoist tasks: %w", err))
 		return
 	}
 
+	activeTasks := make(map[string]todoist.Task)
 	process.logger.Debug("Finding tasks already linked to Jira issues")
 	for _, task := range tasks {
-		match := re.FindStringSubmatch(task.Content)
-		if len(match) == jiraMatches {
-			processedTasks[match[1]] = task
+		activeTasks[task.ID] = task
+		if key, _, _, linked := utils.ParseTodoistTaskContent(task.Content); linked {
+			processedTasks[key] = task
 		}
 	}
 	process.logger.Debug("Issues already in Todoist:")
 	for key := range processedTasks {
 		process.logger.Debug(key)
 	}
+	completedTasks := process.getCompletedTaskIDs(ctx, summary)
 
 	for _, jiraConfig := range process.config.Jira {
-		process.processJiraInstance(jiraConfig, &processedTasks)
-		process.logger.Infof("Finished processing Jira instance %s", jiraConfig.Site)
+		if ctx.Err() != nil {
+			summary.fail(process.logger, ItemRun, "Jira instances", ctx.Err())
+			return
+		}
+		instance := process.with(fieldJiraSite, jiraConfig.Site)
+		instance.jiraClient, err = instance.newJiraClient(jiraConfig)
+		if err != nil {
+			summary.fail(instance.logger, ItemJiraInstance, jiraConfig.Site, err)
+			continue
+		}
+		err = instance.processJiraInstance(ctx, jiraConfig, &processedTasks, activeTasks, completedTasks, summary)
+		if err != nil {
+			summary.fail(instance.logger, ItemJiraInstance, jiraConfig.Site, err)
+			continue
+		}
+		instance.logger.Infof("Finished processing Jira instance %s", jiraConfig.Site)
 	}
 }
 
-func (process JiraProcess) processJiraInstance(jiraConfig config.JiraConfig, processedTasks *map[string]todoist.Task) {
+func (process JiraProcess) processJiraInstance(ctx context.Context, jiraConfig config.JiraConfig,
+	processedTasks *map[string]todoist.Task, activeTasks map[string]todoist.Task, completedTasks map[string]bool,
+	summary *Summary) error {
 	var err error
 	var jiraIssues []jira.Issue
 
@@ -76,87 +130,322 @@ func (process JiraProcess) processJiraInstance(jiraConfig config.JiraConfig, pro
 	if jiraConfig.Project != "" {
 		targetProjectID, err = process.todoistClient.FindProjectID(process.projects, jiraConfig.Project)
 		if err != nil {
-			process.logger.Fatalf("An error occurred when finding the target project for instance %s: %v", jiraConfig.Site, err)
-			return
+			return fmt.Errorf("error finding the target project: %w", err)
 		}
 	}
 
 	process.logger.Infof("Fetching issues from Jira instance %s", jiraConfig.Site)
-	jiraIssues, err = jira.FetchJiraIssues(jiraConfig)
+	jiraIssues, err = jira.FetchJiraIssues(ctx, process.jiraClient, jiraConfig)
 	if err != nil {
-		process.logger.Fatalf("Error fetching Jira issues: %v", err)
-		return
+		return fmt.Errorf("error fetching Jira issues: %w", err)
 	}
 
 	for _, issue := range jiraIssues {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 		arg := issue
-		process.processJiraIssue(jiraConfig, &arg, processedTasks, targetProjectID)
+		issueProcess := process.with(fieldIssueKey, issue.Key)
+		if issueProcess.transitionCompletedIssue(ctx, jiraConfig, &arg, activeTasks, completedTasks, summary) {
+			continue
+		}
+		issueProcess.linkStoredTask(jiraConfig, &arg, processedTasks, activeTasks)
+		processed, err := issueProcess.processJiraIssue(ctx, jiraConfig, &arg, processedTasks, targetProjectID)
+		switch {
+		case err != nil:
+			if task, linked := (*processedTasks)[issue.Key]; linked {
+				issueProcess = issueProcess.with(fieldTaskID, task.ID)
+			}
+			summary.fail(issueProcess.logger, ItemJiraIssue, issue.Key, err)
+		case processed:
+			summary.succeed()
+		default:
+			summary.skip()
+		}
 	}
+	return nil
 }
 
-func (process JiraProcess) processJiraIssue(jiraConfig config.JiraConfig, issue *jira.Issue,
-	processedTasks *map[string]todoist.Task, targetProjectID string) {
-	var err error
-	task := process.getOrCreateTask(jiraConfig, issue, processedTasks, targetProjectID)
+// getCompletedTaskIDs returns the IDs of the tasks completed recently if a Jira instance transitions
+// the issues of completed tasks; it returns nil if none does or if the tasks cannot be fetched.
+func (process JiraProcess) getCompletedTaskIDs(ctx context.Context, summary *Summary) map[string]bool {
+	transitions := false
+	for _, jiraConfig := range process.config.Jira {
+		transitions = transitions || jiraConfig.CompletionTransition != ""
+	}
+	if !transitions {
+		return nil
+	}
 
-	if task == nil {
+	process.logger.Info("Fetching completed Todoist tasks")
+	completed, err := process.todoistClient.GetCompletedTasks(ctx, time.Now().Add(-completedTasksLookback))
+	if err != nil {
+		summary.fail(process.logger, ItemRun, "Todoist completed tasks",
+			fmt.Errorf("error fetching completed Todoist tasks: %w", err))
+		return nil
+	}
+	ids := make(map[string]bool, len(completed))
+	for _, task := range completed {
+		ids[task.TaskID] = true
+	}
+	return ids
+}
+
+// transitionCompletedIssue runs the completion transition of the instance on an issue whose linked
+// task was completed in Todoist since the last run; it returns false if the issue must be synced as
+// usual. If the completed tasks could not be fetched, the issue is skipped and its link is kept, so
+// that its task is not created again before the issue can be transitioned.
+func (process JiraProcess) transitionCompletedIssue(ctx context.Context, jiraConfig config.JiraConfig,
+	issue *jira.Issue, activeTasks map[string]todoist.Task, completedTasks map[string]bool, summary *Summary) bool {
+	if jiraConfig.CompletionTransition == "" || utils.Contains(jiraConfig.CompletionStatuses, issue.Fields.Status.Name) {
+		return false
+	}
+	stored, exists := process.store.JiraIssue(jiraConfig.Site, issue.Key)
+	if !exists {
+		return false
+	}
+	if _, active := activeTasks[stored.TaskID]; active {
+		return false
+	}
+	process = process.with(fieldTaskID, stored.TaskID)
+	if completedTasks == nil {
+		process.logger.Debugf("Not syncing Jira issue [%s] until completed tasks can be fetched", issue.Key)
+		summary.skip()
+		return true
+	}
+	if !completedTasks[stored.TaskID] {
+		return false
+	}
+
+	if process.config.DryRun {
+		process.logger.Infof("Would run transition %s on Jira issue [%s], whose task was completed",
+			jiraConfig.CompletionTransition, issue.Key)
+		summary.succeed()
+		return true
+	}
+	err := jira.TransitionIssue(ctx, process.jiraClient, jiraConfig, issue.Key, jiraConfig.CompletionTransition)
+	if err != nil {
+		summary.fail(process.logger, ItemJiraIssue, issue.Key,
+			fmt.Errorf("error running transition %s: %w", jiraConfig.CompletionTransition, err))
+		return true
+	}
+	process.logger.Infof("Ran transition %s on Jira issue [%s], whose task was completed",
+		jiraConfig.CompletionTransition, issue.Key)
+	process.countTask(jiraConfig, metrics.IssueTransitioned)
+	process.store.RemoveJiraIssue(jiraConfig.Site, issue.Key)
+	summary.succeed()
+	return true
+}
+
+// linkStoredTask uses the task recorded in the state store for an issue, which is found even if
+// its content no longer matches the issue key; stale links to inactive tasks are removed.
+func (process JiraProcess) linkStoredTask(jiraConfig config.JiraConfig, issue *jira.Issue,
+	processedTasks *map[string]todoist.Task, activeTasks map[string]todoist.Task) {
+	stored, exists := process.store.JiraIssue(jiraConfig.Site, issue.Key)
+	if !exists {
+		return
+	}
+	task, active := activeTasks[stored.TaskID]
+	if !active {
+		process.logger.WithField(fieldTaskID, stored.TaskID).Debugf("Task %s linked to Jira issue [%s] is no longer active", stored.TaskID, issue.Key)
+		process.store.RemoveJiraIssue(jiraConfig.Site, issue.Key)
 		return
 	}
+	(*processedTasks)[issue.Key] = task
+}
+
+// processJiraIssue syncs an issue with its Todoist task; it returns false if there was nothing to do.
+// The task of an issue that did not change since the last run is still brought back to the state
+// expected from the issue, e.g. if one of its labels was removed in Todoist.
+func (process JiraProcess) processJiraIssue(ctx context.Context, jiraConfig config.JiraConfig, issue *jira.Issue,
+	processedTasks *map[string]todoist.Task, targetProjectID string) (bool, error) {
+	hash, err := issueHash(jiraConfig, issue)
+	if err != nil {
+		return false, fmt.Errorf("error hashing issue: %w", err)
+	}
+	updated, err := process.updateTaskContent(ctx, jiraConfig, issue, processedTasks)
+	if err != nil {
+		return false, err
+	}
+	stored, exists := process.store.JiraIssue(jiraConfig.Site, issue.Key)
+	unchanged := exists && stored.Hash == hash
+	if unchanged {
+		process.logger.Debugf("Jira issue [%s] has not changed since the last run, checking its task", issue.Key)
+	}
+
+	_, linked := (*processedTasks)[issue.Key]
+	task, err := process.getOrCreateTask(ctx, jiraConfig, issue, processedTasks, targetProjectID)
+	if err != nil {
+		return false, err
+	}
+
+	if task == nil {
+		// The issue is completed: the linked task has been completed, or there was none to create.
+		process.store.RemoveJiraIssue(jiraConfig.Site, issue.Key)
+		return linked, nil
+	}
+	process = process.with(fieldTaskID, task.ID)
 
 	taskPriority, err := process.getPriority(jiraConfig, issue)
 	if err != nil {
-		process.logger.Fatalf(err.Error())
-		return
+		return false, err
+	}
+	prioritized, err := process.setTaskPriority(ctx, task, taskPriority)
+	if err != nil {
+		return false, err
+	}
+
+	relabelled, err := process.processLabels(ctx, jiraConfig, issue, task)
+	if err != nil {
+		return false, err
 	}
-	process.setTaskPriority(task, taskPriority)
 
-	process.processLabels(jiraConfig, issue, task)
+	described, err := process.processDescription(ctx, jiraConfig, issue, task)
+	if err != nil {
+		return false, err
+	}
+
+	dueDate := issue.DueDate(jiraConfig.DueDateSources)
+	rescheduled, err := process.setTaskDueDate(ctx, jiraConfig, task, dueDate, stored.DueDate)
+	if err != nil {
+		return false, err
+	}
+
+	*process.links = append(*process.links, jiraLink{site: jiraConfig.Site, key: issue.Key,
+		issue: state.JiraIssue{TaskID: task.ID, Hash: hash, DueDate: dueDate}})
+	return !unchanged || updated || prioritized || relabelled || described || rescheduled, nil
+}
+
+// RecordLinks records in the store the tasks linked to the issues synced by the process, once the
+// changes to the tasks have been sent to Todoist and the IDs of the created tasks are known. An
+// issue whose task has changes that Todoist rejected or that could not be sent keeps its previous
+// state, so that it is synced again by the next run.
+func (process JiraProcess) RecordLinks() {
+	for _, link := range *process.links {
+		taskID, ok := process.todoistClient.SyncedTaskID(link.issue.TaskID)
+		if !ok {
+			linkProcess := process.with(fieldJiraSite, link.site).with(fieldTaskID, link.issue.TaskID)
+			linkProcess.logger.Debugf("Not recording the task of Jira issue [%s], whose changes were not applied",
+				link.key)
+			continue
+		}
+		link.issue.TaskID = taskID
+		process.store.SetJiraIssue(link.site, link.key, link.issue)
+	}
+	*process.links = nil
 }
 
-func (process JiraProcess) getOrCreateTask(jiraConfig config.JiraConfig, issue *jira.Issue,
-	processedTasks *map[string]todoist.Task, targetProjectID string) *todoist.Task {
+// updateTaskContent rewrites the link to an issue in the content of its task if the summary or the
+// browse URL of the issue changed, keeping the text added by the user around the link; it returns
+// whether the content was updated. Tasks of completed issues, and tasks linked through the state
+// store whose content no longer has a link, are left as they are.
+func (process JiraProcess) updateTaskContent(ctx context.Context, jiraConfig config.JiraConfig, issue *jira.Issue,
+	processedTasks *map[string]todoist.Task) (bool, error) {
+	task, linked := (*processedTasks)[issue.Key]
+	if !linked || utils.Contains(jiraConfig.CompletionStatuses, issue.Fields.Status.Name) {
+		return false, nil
+	}
+	_, prefix, suffix, ok := utils.ParseTodoistTaskContent(task.Content)
+	if !ok {
+		return false, nil
+	}
+	content := prefix + utils.FormatTodoistTaskContent(jiraConfig, *issue) + suffix
+	if content == task.Content {
+		return false, nil
+	}
+
+	process = process.with(fieldTaskID, task.ID)
+	if err := process.todoistClient.UpdateTaskContent(ctx, task.ID, content); err != nil {
+		return false, fmt.Errorf("error updating the content of task %s: %w", task.Content, err)
+	}
+	process.logger.Infof("Updated the content of task %s to %s", task.Content, content)
+	process.countTask(jiraConfig, metrics.TaskRetitled)
+	task.Content = content
+	(*processedTasks)[issue.Key] = task
+	return true, nil
+}
+
+func (process JiraProcess) getOrCreateTask(ctx context.Context, jiraConfig config.JiraConfig, issue *jira.Issue,
+	processedTasks *map[string]todoist.Task, targetProjectID string) (*todoist.Task, error) {
 	if _, exists := (*processedTasks)[issue.Key]; !exists {
 		if utils.Contains(jiraConfig.CompletionStatuses, issue.Fields.Status.Name) {
 			process.logger.Debugf("Skipping completed issue [%s] %s", issue.Key, issue.Fields.Summary)
-			return nil
+			return nil, nil
 		}
 		taskContent := utils.FormatTodoistTaskContent(jiraConfig, *issue)
-		task, err := process.todoistClient.CreateTask(taskContent, targetProjectID)
+		task, err := process.todoistClient.CreateTask(ctx, taskContent, targetProjectID)
 		if err != nil {
-			process.logger.Fatalf("Error creating Todoist task: %v", err)
-			return nil
+			return nil, fmt.Errorf("error creating Todoist task: %w", err)
 		}
-		process.logger.Infof("Created Todoist task: %v", taskContent)
-		return task
+		process.logger.WithField(fieldTaskID, task.ID).Infof("Created Todoist task: %v", taskContent)
+		process.countTask(jiraConfig, metrics.TaskCreated)
+		return task, nil
 	}
 
 	task := (*processedTasks)[issue.Key]
+	process = process.with(fieldTaskID, task.ID)
 	process.logger.Debugf("Todoist task already exists for Jira issue [%s]", issue.Key)
 	if utils.Contains(jiraConfig.CompletionStatuses, issue.Fields.Status.Name) {
 		process.logger.Infof("Completing task %s", task.Content)
-		err := process.todoistClient.CompleteTask(task.ID)
+		err := process.todoistClient.CompleteTask(ctx, task.ID)
+		if httpclient.IsNotFound(err) {
+			process.logger.Infof("Task %s was already deleted", task.Content)
+			return nil, nil
+		}
 		if err != nil {
-			process.logger.Fatalf("Error completing task %s: %v", task.Content, err)
-			return nil
+			return nil, fmt.Errorf("error completing task %s: %w", task.Content, err)
 		}
 		process.logger.Infof("Completed task %s", task.Content)
+		process.countTask(jiraConfig, metrics.TaskCompleted)
+		return nil, nil
 	}
-	return &task
+	return &task, nil
 }
 
-func (process JiraProcess) setTaskPriority(task *todoist.Task, priority int) {
-	process.logger.Debugf("Task %s priority: %d", task.Content, *task.Priority)
-	if task.Priority != &priority {
-		process.logger.Debugf("Setting priority to %d for task %s", priority, task.Content)
-		err := process.todoistClient.SetTaskPriority(task.ID, priority)
-		if err != nil {
-			process.logger.Fatalf("Error setting priority for task %s: %v", task.Content, err)
-			return
-		}
+// setTaskPriority sets the priority of a task unless it already has it; it returns whether the
+// priority was set.
+func (process JiraProcess) setTaskPriority(ctx context.Context, task *todoist.Task, priority int) (bool, error) {
+	if task.Priority != nil && *task.Priority == priority {
+		process.logger.Debugf("Task %s already has priority %d", task.Content, priority)
+		return false, nil
+	}
+	process.logger.Debugf("Setting priority to %d for task %s", priority, task.Content)
+	if err := process.todoistClient.SetTaskPriority(ctx, task.ID, priority); err != nil {
+		return false, fmt.Errorf("error setting priority for task %s: %w", task.Content, err)
+	}
+	return true, nil
+}
+
+// setTaskDueDate sets the due date of a task to the due date of its issue when the latter changes
+// since the last sync, so that a task rescheduled in Todoist keeps its date until the issue is
+// rescheduled in Jira. The time of a task due at a given time on the same date is kept, and the due
+// date of a task is removed with the due date of its issue only if it was not changed in Todoist.
+// It returns whether the due date was changed.
+func (process JiraProcess) setTaskDueDate(ctx context.Context, cfg config.JiraConfig, task *todoist.Task,
+	dueDate, lastDueDate string) (bool, error) {
+	if dueDate == lastDueDate && lastDueDate != "" {
+		return false, nil
+	}
+	current := task.Due.Day()
+	if dueDate == current || (dueDate == "" && current != lastDueDate) {
+		return false, nil
 	}
+	if err := process.todoistClient.SetTaskDueDate(ctx, task.ID, dueDate); err != nil {
+		return false, fmt.Errorf("error setting the due date of task %s: %w", task.Content, err)
+	}
+	if dueDate == "" {
+		process.logger.Infof("Removed the due date of task %s", task.Content)
+	} else {
+		process.logger.Infof("Set the due date of task %s to %s", task.Content, dueDate)
+	}
+	process.countTask(cfg, metrics.TaskRescheduled)
+	return true, nil
 }
 
-func (process JiraProcess) processLabels(cfg config.JiraConfig, issue *jira.Issue, task *todoist.Task) {
+// processLabels replaces the labels set from the issue on its task; it returns whether they were
+// replaced.
+func (process JiraProcess) processLabels(ctx context.Context, cfg config.JiraConfig, issue *jira.Issue,
+	task *todoist.Task) (bool, error) {
 	labelsToAdd := process.collectLabelsToAdd(cfg, issue)
 
 	labelMap := make(map[string]bool)
@@ -164,7 +453,7 @@ func (process JiraProcess) processLabels(cfg config.JiraConfig, issue *jira.Issu
 		labelMap[label] = true
 	}
 	for _, label := range task.Labels {
-		if strings.Index(label, "Jira/") == 0 {
+		if isManagedLabel(cfg, label) {
 			if !utils.Contains(labelsToAdd, label) {
 				delete(labelMap, label)
 			}
@@ -180,16 +469,90 @@ func (process JiraProcess) processLabels(cfg config.JiraConfig, issue *jira.Issu
 		newLabels = append(newLabels, label)
 	}
 
-	if len(newLabels) > 0 {
-		if utils.HaveSameElements(newLabels, task.Labels) {
-			process.logger.Debugf("No need to sync Jira labels for task %s", task.Content)
-		} else {
-			err := process.todoistClient.ReplaceTaskLabels(task.ID, newLabels)
-			if err != nil {
-				process.logger.Fatalf("Error syncing Jira labels for task %s: %v", task.Content, err)
-				return
-			}
+	if len(newLabels) == 0 {
+		return false, nil
+	}
+	if utils.HaveSameElements(newLabels, task.Labels) {
+		process.logger.Debugf("No need to sync Jira labels for task %s", task.Content)
+		return false, nil
+	}
+	if err := process.todoistClient.ReplaceTaskLabels(ctx, task.ID, newLabels); err != nil {
+		return false, fmt.Errorf("error syncing Jira labels for task %s: %w", task.Content, err)
+	}
+	process.countTask(cfg, metrics.TaskRelabelled)
+	return true, nil
+}
+
+// isManagedLabel returns whether a label is set from the issues, and removed when they no longer
+// have it: labels synced from Jira labels and components, and labels mapped from fields.
+func isManagedLabel(cfg config.JiraConfig, label string) bool {
+	if strings.HasPrefix(label, "Jira/") {
+		return true
+	}
+	for _, mapping := range cfg.FieldMappings {
+		if mapping.Label != "" && strings.HasPrefix(label, mapping.Label+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// processDescription writes the values of the fields mapped to the description of a task, one line
+// per field such as "Story points: 5" at the end of the description. Lines of fields that no longer
+// have a value are removed, and the rest of the description is kept. It returns whether the
+// description was updated.
+func (process JiraProcess) processDescription(ctx context.Context, cfg config.JiraConfig, issue *jira.Issue,
+	task *todoist.Task) (bool, error) {
+	var names, lines []string
+	for _, mapping := range cfg.FieldMappings {
+		if mapping.Description == "" {
+			continue
+		}
+		names = append(names, mapping.Description+": ")
+		if values := issue.FieldValues(mapping.Field); len(values) > 0 {
+			lines = append(lines, mapping.Description+": "+strings.Join(values, ", "))
+		}
+	}
+	if len(names) == 0 {
+		return false, nil
+	}
+
+	var kept []string
+	for _, line := range strings.Split(task.Description, "\n") {
+		managed := false
+		for _, name := range names {
+			managed = managed || strings.HasPrefix(line, name)
 		}
+		if !managed {
+			kept = append(kept, line)
+		}
+	}
+	for len(kept) > 0 && strings.TrimSpace(kept[len(kept)-1]) == "" {
+		kept = kept[:len(kept)-1]
+	}
+	description := strings.Join(append(kept, lines...), "\n")
+	if description == task.Description {
+		return false, nil
+	}
+
+	if err := process.todoistClient.UpdateTaskDescription(ctx, task.ID, description); err != nil {
+		return false, fmt.Errorf("error updating the description of task %s: %w", task.Content, err)
+	}
+	process.logger.Debugf("Updated the description of task %s", task.Content)
+	process.countTask(cfg, metrics.TaskDescribed)
+	return true, nil
+}
+
+// with returns a copy of the process whose log entries carry the given field.
+func (process JiraProcess) with(field string, value any) JiraProcess {
+	process.logger = process.logger.WithField(field, value)
+	return process
+}
+
+// countTask counts an action on a task in the metrics, unless the action was only planned.
+func (process JiraProcess) countTask(jiraConfig config.JiraConfig, action string) {
+	if !process.config.DryRun {
+		metrics.CountJiraTask(jiraConfig.Site, action)
 	}
 }
 
@@ -207,21 +570,49 @@ func (process JiraProcess) collectLabelsToAdd(cfg config.JiraConfig, issue *jira
 			labelsToAdd = append(labelsToAdd, fmt.Sprintf("Jira/Component/%s", component.Name))
 		}
 	}
+	for _, mapping := range cfg.FieldMappings {
+		if mapping.Label == "" {
+			continue
+		}
+		for _, value := range issue.FieldValues(mapping.Field) {
+			labelsToAdd = append(labelsToAdd, mapping.Label+"/"+value)
+		}
+	}
 
 	return labelsToAdd
 }
 
+// getPriority returns the priority of the task of an issue: the priority mapped from the first field
+// mapping with a priority map matching the values of its field, or else the one mapped from the Jira
+// priority.
 func (process JiraProcess) getPriority(cfg config.JiraConfig, issue *jira.Issue) (int, error) {
-	for priority, jiraPriorityNames := range cfg.PriorityMap {
-		for _, name := range jiraPriorityNames {
-			if issue.Fields.Priority.Name == name {
-				taskPriority, err := process.config.ToAPIPriority(priority)
-				if err != nil {
-					return 0, err
-				}
-				return taskPriority, nil
-			}
+	for _, mapping := range cfg.FieldMappings {
+		if priority, ok := mapPriority(mapping.PriorityMap, issue.FieldValues(mapping.Field)); ok {
+			return process.config.ToAPIPriority(priority)
 		}
 	}
+	if priority, ok := mapPriority(cfg.PriorityMap, []string{issue.Fields.Priority.Name}); ok {
+		return process.config.ToAPIPriority(priority)
+	}
 	return 1, nil
 }
+
+// mapPriority returns the highest priority, such as p1, to which one of the values is mapped.
+func mapPriority(priorityMap map[string][]string, values []string) (string, bool) {
+	highest := ""
+	for priority, names := range priorityMap {
+		for _, name := range names {
+			if utils.Contains(values, name) && (highest == "" || priority < highest) {
+				highest = priority
+			}
+		}
+	}
+	return highest, highest != ""
+}
+
+// issueHash returns a hash of the issue and of the configuration used to sync it, so that a
+// change in either triggers a new sync.
+func issueHash(cfg config.JiraConfig, issue *jira.Issue) (string, error) {
+	return state.Hash(issue, cfg.Labels, cfg.SyncJiraLabels, cfg.SyncJiraComponents, cfg.PriorityMap,
+		cfg.CompletionStatuses, cfg.DueDateSources, cfg.FieldMappings)
+}
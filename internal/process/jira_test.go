@@ -0,0 +1,596 @@
+package process
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fabiocorneti/todoist-assistant/internal/config"
+	"github.com/fabiocorneti/todoist-assistant/internal/jira/jiratest"
+	"github.com/fabiocorneti/todoist-assistant/internal/todoist/todoisttest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testJiraUsername = "user@example.com"
+	testJiraToken    = "jira-secret"
+)
+
+// expectedTask is the expected state of the Todoist task linked to a Jira issue.
+type expectedTask struct {
+	labels    []string
+	priority  int
+	completed bool
+	// content is the content of the task if it is not just the link to the issue; JIRA is replaced
+	// with the URL of the fake Jira server.
+	content     string
+	description string
+}
+
+func TestRunProcessJira(t *testing.T) {
+	testCases := []struct {
+		name             string
+		issues           []jiratest.Issue
+		tasks            []todoisttest.Task
+		configure        func(jiraConfig *config.JiraConfig)
+		setup            func(jiraServer *jiratest.Server, todoistServer *todoisttest.Server)
+		expected         map[string]expectedTask
+		expectedFailures int
+	}{
+		{
+			name: "Tasks are created for open issues",
+			issues: []jiratest.Issue{
+				{Key: "PRJ-1", Summary: "Fix the login", Status: "To Do"},
+				{Key: "PRJ-2", Summary: "Write the docs", Status: "Done"},
+				{Key: "OPS-1", Summary: "Another project", Status: "To Do"},
+			},
+			configure: func(jiraConfig *config.JiraConfig) {
+				jiraConfig.Labels = []string{"Work"}
+			},
+			expected: map[string]expectedTask{
+				"PRJ-1": {labels: []string{"Work"}, priority: 1},
+			},
+		},
+		{
+			name: "Issues are fetched in several pages",
+			issues: []jiratest.Issue{
+				{Key: "PRJ-1", Summary: "One", Status: "To Do"},
+				{Key: "PRJ-2", Summary: "Two", Status: "To Do"},
+				{Key: "PRJ-3", Summary: "Three", Status: "To Do"},
+			},
+			setup: func(jiraServer *jiratest.Server, _ *todoisttest.Server) {
+				jiraServer.SetMaxResults(1)
+			},
+			expected: map[string]expectedTask{
+				"PRJ-1": {priority: 1},
+				"PRJ-2": {priority: 1},
+				"PRJ-3": {priority: 1},
+			},
+		},
+		{
+			name: "Tasks of completed issues are completed",
+			issues: []jiratest.Issue{
+				{Key: "PRJ-1", Summary: "Fix the login", Status: "Done"},
+				{Key: "PRJ-2", Summary: "Write the docs", Status: "In Review"},
+			},
+			// JIRA is replaced with the URL of the fake Jira server
+			tasks: []todoisttest.Task{
+				{Content: "[[PRJ-1] Fix the login](JIRA/browse/PRJ-1)"},
+				{Content: "[[PRJ-2] Write the docs](JIRA/browse/PRJ-2)"},
+			},
+			expected: map[string]expectedTask{
+				"PRJ-1": {completed: true},
+				"PRJ-2": {priority: 1},
+			},
+		},
+		{
+			name: "Jira labels and components are synced",
+			issues: []jiratest.Issue{
+				{
+					Key:        "PRJ-1",
+					Summary:    "Fix the login",
+					Status:     "To Do",
+					Labels:     []string{"security"},
+					Components: []string{"Backend", "Auth"},
+				},
+			},
+			tasks: []todoisttest.Task{
+				{
+					Content: "[[PRJ-1] Fix the login](JIRA/browse/PRJ-1)",
+					Labels:  []string{"Personal", "Jira/Label/stale", "Jira/Component/Backend"},
+				},
+			},
+			configure: func(jiraConfig *config.JiraConfig) {
+				jiraConfig.SyncJiraLabels = true
+				jiraConfig.SyncJiraComponents = true
+			},
+			expected: map[string]expectedTask{
+				"PRJ-1": {
+					labels:   []string{"Personal", "Jira/Label/security", "Jira/Component/Backend", "Jira/Component/Auth"},
+					priority: 1,
+				},
+			},
+		},
+		{
+			name: "Jira priorities are mapped",
+			issues: []jiratest.Issue{
+				{Key: "PRJ-1", Summary: "Outage", Status: "To Do", Priority: "Highest"},
+				{Key: "PRJ-2", Summary: "Bug", Status: "To Do", Priority: "Medium"},
+				{Key: "PRJ-3", Summary: "Typo", Status: "To Do", Priority: "Lowest"},
+			},
+			configure: func(jiraConfig *config.JiraConfig) {
+				jiraConfig.PriorityMap = map[string][]string{
+					"p1": {"Highest", "High"},
+					"p3": {"Medium"},
+				}
+			},
+			expected: map[string]expectedTask{
+				"PRJ-1": {priority: 4},
+				"PRJ-2": {priority: 2},
+				"PRJ-3": {priority: 1},
+			},
+		},
+		{
+			name: "Tasks of renamed issues are retitled",
+			issues: []jiratest.Issue{
+				{Key: "PRJ-1", Summary: "Fix the login page", Status: "To Do"},
+				{Key: "PRJ-2", Summary: "Write the docs", Status: "To Do"},
+			},
+			tasks: []todoisttest.Task{
+				{Content: "Urgent: [[PRJ-1] Fix the login](JIRA/browse/PRJ-1) by Friday", Priority: 1},
+				{Content: "[[PRJ-2] Write the docs](JIRA/browse/PRJ-2)", Priority: 1},
+			},
+			expected: map[string]expectedTask{
+				"PRJ-1": {priority: 1, content: "Urgent: [[PRJ-1] Fix the login page](JIRA/browse/PRJ-1) by Friday"},
+				"PRJ-2": {priority: 1},
+			},
+		},
+		{
+			name: "Custom fields are mapped",
+			issues: []jiratest.Issue{
+				{Key: "PRJ-1", Summary: "Fix the login", Status: "To Do", CustomFields: map[string]any{
+					"customfield_10042": map[string]string{"value": "Acme"},
+					"customfield_10050": map[string]string{"value": "Sev1"},
+					"customfield_10016": 5,
+				}},
+				{Key: "PRJ-2", Summary: "Write the docs", Status: "To Do", CustomFields: map[string]any{
+					"customfield_10042": []map[string]string{{"value": "Acme"}, {"value": "Globex"}},
+					"customfield_10016": 8,
+				}},
+			},
+			tasks: []todoisttest.Task{
+				{
+					Content:     "[[PRJ-2] Write the docs](JIRA/browse/PRJ-2)",
+					Description: "Ask Jane first\nStory points: 3\n",
+					Labels:      []string{"Jira/Customer/Initech", "Personal"},
+					Priority:    1,
+				},
+			},
+			configure: func(jiraConfig *config.JiraConfig) {
+				jiraConfig.FieldMappings = []config.FieldMapping{
+					{Field: "customfield_10042", Label: "Jira/Customer"},
+					{Field: "customfield_10050.value", PriorityMap: map[string][]string{"p1": {"Sev1"}}},
+					{Field: "customfield_10016", Description: "Story points"},
+				}
+			},
+			expected: map[string]expectedTask{
+				"PRJ-1": {labels: []string{"Jira/Customer/Acme"}, priority: 4, description: "Story points: 5"},
+				"PRJ-2": {
+					labels:      []string{"Jira/Customer/Acme", "Jira/Customer/Globex", "Personal"},
+					priority:    1,
+					description: "Ask Jane first\nStory points: 8",
+				},
+			},
+		},
+		{
+			name: "Unavailable Jira is retried",
+			issues: []jiratest.Issue{
+				{Key: "PRJ-1", Summary: "Fix the login", Status: "To Do"},
+			},
+			setup: func(jiraServer *jiratest.Server, _ *todoisttest.Server) {
+				jiraServer.InjectError(http.StatusServiceUnavailable, 1)
+			},
+			expected: map[string]expectedTask{
+				"PRJ-1": {priority: 1},
+			},
+		},
+		{
+			name: "Failing Jira instance is reported",
+			issues: []jiratest.Issue{
+				{Key: "PRJ-1", Summary: "Fix the login", Status: "To Do"},
+			},
+			setup: func(jiraServer *jiratest.Server, _ *todoisttest.Server) {
+				jiraServer.InjectError(http.StatusInternalServerError, 1)
+			},
+			expected:         map[string]expectedTask{},
+			expectedFailures: 1,
+		},
+		{
+			name: "Misconfigured Jira client is reported",
+			issues: []jiratest.Issue{
+				{Key: "PRJ-1", Summary: "Fix the login", Status: "To Do"},
+			},
+			configure: func(jiraConfig *config.JiraConfig) {
+				jiraConfig.CACertFile = filepath.Join(t.TempDir(), "missing.pem")
+			},
+			expected:         map[string]expectedTask{},
+			expectedFailures: 1,
+		},
+		{
+			name: "Failing Todoist update is reported for the issue",
+			issues: []jiratest.Issue{
+				{Key: "PRJ-1", Summary: "Fix the login", Status: "To Do"},
+				{Key: "PRJ-2", Summary: "Write the docs", Status: "To Do"},
+			},
+			setup: func(_ *jiratest.Server, todoistServer *todoisttest.Server) {
+				todoistServer.InjectError("POST tasks", http.StatusInternalServerError, 1)
+			},
+			expected: map[string]expectedTask{
+				"PRJ-2": {priority: 1},
+			},
+			expectedFailures: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			jiraServer := jiratest.NewServer(t, testJiraUsername, testJiraToken)
+			for _, issue := range tc.issues {
+				jiraServer.AddIssue(issue)
+			}
+			todoistServer := todoisttest.NewServer(t, testToken)
+			for _, task := range tc.tasks {
+				task.Content = strings.ReplaceAll(task.Content, "JIRA", jiraServer.URL)
+				todoistServer.AddTask(task)
+			}
+			if tc.setup != nil {
+				tc.setup(jiraServer, todoistServer)
+			}
+
+			cfg := testConfig(t, todoistServer)
+			jiraConfig := config.JiraConfig{
+				Site:               jiraServer.URL,
+				Username:           testJiraUsername,
+				Token:              testJiraToken,
+				JQL:                "project = PRJ ORDER BY key",
+				CompletionStatuses: []string{"Done"},
+			}
+			if tc.configure != nil {
+				tc.configure(&jiraConfig)
+			}
+			cfg.Jira = []config.JiraConfig{jiraConfig}
+
+			summary := RunJobs(context.Background(), cfg, testLogger(), []string{cfg.JiraJob(0)})
+			assert.Equal(t, tc.expectedFailures, summary.Failed, summary.Failures)
+
+			tasks := map[string]todoisttest.Task{}
+			for _, task := range todoistServer.Tasks() {
+				tasks[task.Content] = task
+			}
+			require.Len(t, tasks, len(tc.expected), tasks)
+			for key, expected := range tc.expected {
+				var issue jiratest.Issue
+				for _, candidate := range tc.issues {
+					if candidate.Key == key {
+						issue = candidate
+					}
+				}
+				content := "[[" + key + "] " + issue.Summary + "](" + jiraServer.URL + "/browse/" + key + ")"
+				if expected.content != "" {
+					content = strings.ReplaceAll(expected.content, "JIRA", jiraServer.URL)
+				}
+				task, ok := tasks[content]
+				require.True(t, ok, "no task for %s", key)
+				assert.Equal(t, expected.completed, task.Completed, key)
+				if !expected.completed {
+					assert.ElementsMatch(t, expected.labels, task.Labels, key)
+					assert.Equal(t, expected.priority, task.Priority, key)
+					assert.Equal(t, expected.description, task.Description, key)
+				}
+			}
+		})
+	}
+}
+
+func TestRunProcessReplay(t *testing.T) {
+	jiraServer := jiratest.NewServer(t, testJiraUsername, testJiraToken)
+	jiraServer.AddIssue(jiratest.Issue{
+		Key: "PRJ-1", Summary: "Fix the login", Status: "To Do", Labels: []string{"security"},
+	})
+	jiraServer.AddIssue(jiratest.Issue{Key: "PRJ-2", Summary: "Write the docs", Status: "Done"})
+	todoistServer := todoisttest.NewServer(t, testToken)
+	todoistServer.AddTask(todoisttest.Task{Content: "[[PRJ-2] Write the docs](" + jiraServer.URL + "/browse/PRJ-2)"})
+
+	runConfig := func(site, apiURL string) config.Config {
+		cfg := testConfig(t, todoistServer)
+		cfg.Todoist.APIURL = apiURL
+		cfg.Jira = []config.JiraConfig{{
+			Site:               site,
+			Username:           testJiraUsername,
+			Token:              testJiraToken,
+			JQL:                "project = PRJ",
+			CompletionStatuses: []string{"Done"},
+			SyncJiraLabels:     true,
+		}}
+		return cfg
+	}
+
+	cassette := filepath.Join(t.TempDir(), "cassette.jsonl")
+	cfg := runConfig(jiraServer.URL, todoistServer.URL)
+	cfg.RecordPath = cassette
+	recorded := RunProcess(context.Background(), cfg, testLogger())
+	require.Zero(t, recorded.Failed, recorded.Failures)
+	require.Positive(t, recorded.Succeeded)
+
+	content, err := os.ReadFile(cassette)
+	require.NoError(t, err)
+	assert.NotContains(t, string(content), testToken)
+	assert.NotContains(t, string(content), testJiraUsername)
+
+	// nothing listens on the sites of the replayed run, which only gets the recorded responses
+	cfg = runConfig("http://jira.invalid", "http://todoist.invalid")
+	cfg.ReplayPath = cassette
+	replayed := RunProcess(context.Background(), cfg, testLogger())
+	assert.Zero(t, replayed.Failed, replayed.Failures)
+	assert.Equal(t, recorded.Succeeded, replayed.Succeeded)
+	assert.Equal(t, recorded.Skipped, replayed.Skipped)
+}
+
+func TestRunProcessJiraTransition(t *testing.T) {
+	testCases := []struct {
+		name             string
+		transition       string
+		setup            func(todoistServer *todoisttest.Server)
+		expectedStatus   string
+		expectedTasks    int
+		expectedFailures int
+	}{
+		{
+			name:           "Issue of a completed task is transitioned",
+			transition:     "Resolve",
+			expectedStatus: "Done",
+			expectedTasks:  1,
+		},
+		{
+			name:           "Task is created again without a transition",
+			expectedStatus: "To Do",
+			expectedTasks:  2,
+		},
+		{
+			name:             "Unavailable transition is reported",
+			transition:       "Close",
+			expectedStatus:   "To Do",
+			expectedTasks:    1,
+			expectedFailures: 1,
+		},
+		{
+			name:       "Issue is skipped if completed tasks cannot be fetched",
+			transition: "Resolve",
+			setup: func(todoistServer *todoisttest.Server) {
+				todoistServer.InjectError("GET /sync/v9/completed/get_all", http.StatusInternalServerError, 1)
+			},
+			expectedStatus:   "To Do",
+			expectedTasks:    1,
+			expectedFailures: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			jiraServer := jiratest.NewServer(t, testJiraUsername, testJiraToken)
+			jiraServer.AddIssue(jiratest.Issue{Key: "PRJ-1", Summary: "Fix the login", Status: "To Do"})
+			jiraServer.AddTransition("Start", "In Progress")
+			jiraServer.AddTransition("Resolve", "Done")
+			todoistServer := todoisttest.NewServer(t, testToken)
+
+			cfg := testConfig(t, todoistServer)
+			cfg.Jira = []config.JiraConfig{{
+				Site:                 jiraServer.URL,
+				Username:             testJiraUsername,
+				Token:                testJiraToken,
+				JQL:                  "project = PRJ",
+				CompletionStatuses:   []string{"Done"},
+				CompletionTransition: tc.transition,
+			}}
+			jobs := []string{cfg.JiraJob(0)}
+
+			summary := RunJobs(context.Background(), cfg, testLogger(), jobs)
+			require.Zero(t, summary.Failed, summary.Failures)
+			tasks := todoistServer.Tasks()
+			require.Len(t, tasks, 1)
+			todoistServer.Complete(tasks[0].ID)
+			if tc.setup != nil {
+				tc.setup(todoistServer)
+			}
+
+			summary = RunJobs(context.Background(), cfg, testLogger(), jobs)
+			assert.Equal(t, tc.expectedFailures, summary.Failed, summary.Failures)
+			issue, _ := jiraServer.Issue("PRJ-1")
+			assert.Equal(t, tc.expectedStatus, issue.Status)
+			assert.Len(t, todoistServer.Tasks(), tc.expectedTasks)
+
+			// the task is not created again by later runs, which retry failed transitions
+			RunJobs(context.Background(), cfg, testLogger(), jobs)
+			assert.Len(t, todoistServer.Tasks(), tc.expectedTasks)
+		})
+	}
+}
+
+func TestRunProcessJiraDueDate(t *testing.T) {
+	sprints := []jiratest.Sprint{{Name: "Sprint 2", State: "active", EndDate: "2024-03-15T12:00:00.000Z"}}
+	testCases := []struct {
+		name        string
+		issue       jiratest.Issue
+		sources     []string
+		change      func(jiraServer *jiratest.Server, todoistServer *todoisttest.Server, issue jiratest.Issue, taskID string)
+		expectedDue string
+	}{
+		{
+			name:        "Task is due on the due date of its issue",
+			issue:       jiratest.Issue{DueDate: "2024-03-20", Sprints: sprints},
+			sources:     []string{config.DueDateSourceDueDate, config.DueDateSourceSprint},
+			expectedDue: "2024-03-20",
+		},
+		{
+			name:        "End of the active sprint is a fallback",
+			issue:       jiratest.Issue{Sprints: sprints},
+			sources:     []string{config.DueDateSourceDueDate, config.DueDateSourceSprint},
+			expectedDue: "2024-03-15",
+		},
+		{
+			name:    "Due dates are not synced without sources",
+			issue:   jiratest.Issue{DueDate: "2024-03-20"},
+			sources: nil,
+		},
+		{
+			name:    "Rescheduled issue reschedules its task",
+			issue:   jiratest.Issue{DueDate: "2024-03-20"},
+			sources: []string{config.DueDateSourceDueDate},
+			change: func(jiraServer *jiratest.Server, _ *todoisttest.Server, issue jiratest.Issue, _ string) {
+				issue.DueDate = "2024-03-22"
+				jiraServer.AddIssue(issue)
+			},
+			expectedDue: "2024-03-22",
+		},
+		{
+			name:    "Task rescheduled in Todoist keeps its date",
+			issue:   jiratest.Issue{DueDate: "2024-03-20"},
+			sources: []string{config.DueDateSourceDueDate},
+			change: func(jiraServer *jiratest.Server, todoistServer *todoisttest.Server, issue jiratest.Issue,
+				taskID string) {
+				todoistServer.Reschedule(taskID, "2024-03-18")
+				issue.Summary = "Fix the login page"
+				jiraServer.AddIssue(issue)
+			},
+			expectedDue: "2024-03-18",
+		},
+		{
+			name:    "Due date removed in Jira is removed from the task",
+			issue:   jiratest.Issue{DueDate: "2024-03-20"},
+			sources: []string{config.DueDateSourceDueDate},
+			change: func(jiraServer *jiratest.Server, _ *todoisttest.Server, issue jiratest.Issue, _ string) {
+				issue.DueDate = ""
+				jiraServer.AddIssue(issue)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			issue := tc.issue
+			issue.Key, issue.Summary, issue.Status = "PRJ-1", "Fix the login", "To Do"
+			jiraServer := jiratest.NewServer(t, testJiraUsername, testJiraToken)
+			jiraServer.AddIssue(issue)
+			todoistServer := todoisttest.NewServer(t, testToken)
+
+			cfg := testConfig(t, todoistServer)
+			cfg.Jira = []config.JiraConfig{{
+				Site:               jiraServer.URL,
+				Username:           testJiraUsername,
+				Token:              testJiraToken,
+				JQL:                "project = PRJ",
+				CompletionStatuses: []string{"Done"},
+				DueDateSources:     tc.sources,
+			}}
+			jobs := []string{cfg.JiraJob(0)}
+
+			summary := RunJobs(context.Background(), cfg, testLogger(), jobs)
+			require.Zero(t, summary.Failed, summary.Failures)
+			tasks := todoistServer.Tasks()
+			require.Len(t, tasks, 1)
+			if tc.change != nil {
+				tc.change(jiraServer, todoistServer, issue, tasks[0].ID)
+				summary = RunJobs(context.Background(), cfg, testLogger(), jobs)
+				require.Zero(t, summary.Failed, summary.Failures)
+			}
+
+			task, ok := todoistServer.Task(tasks[0].ID)
+			require.True(t, ok)
+			assert.Equal(t, tc.expectedDue, task.Due)
+		})
+	}
+}
+
+func TestRunProcessJiraUnchangedIssue(t *testing.T) {
+	jiraServer := jiratest.NewServer(t, testJiraUsername, testJiraToken)
+	jiraServer.AddIssue(jiratest.Issue{Key: "PRJ-1", Summary: "Fix the login", Status: "To Do"})
+	todoistServer := todoisttest.NewServer(t, testToken)
+
+	cfg := testConfig(t, todoistServer)
+	cfg.Jira = []config.JiraConfig{{
+		Site:     jiraServer.URL,
+		Username: testJiraUsername,
+		Token:    testJiraToken,
+		JQL:      "project = PRJ",
+		Labels:   []string{"Work"},
+	}}
+	jobs := []string{cfg.JiraJob(0)}
+
+	summary := RunJobs(context.Background(), cfg, testLogger(), jobs)
+	require.Zero(t, summary.Failed, summary.Failures)
+	tasks := todoistServer.Tasks()
+	require.Len(t, tasks, 1)
+	todoistServer.Relabel(tasks[0].ID, []string{"Personal"})
+
+	summary = RunJobs(context.Background(), cfg, testLogger(), jobs)
+	require.Zero(t, summary.Failed, summary.Failures)
+	assert.Equal(t, 1, summary.Succeeded, "the task of the unchanged issue is synced")
+	task, _ := todoistServer.Task(tasks[0].ID)
+	assert.ElementsMatch(t, []string{"Personal", "Work"}, task.Labels)
+
+	summary = RunJobs(context.Background(), cfg, testLogger(), jobs)
+	assert.Equal(t, 1, summary.Skipped, "there is nothing to do")
+}
+
+func TestRunProcessJiraUnchangedPriority(t *testing.T) {
+	jiraServer := jiratest.NewServer(t, testJiraUsername, testJiraToken)
+	jiraServer.AddIssue(jiratest.Issue{Key: "PRJ-1", Summary: "Fix the login", Status: "To Do"})
+	todoistServer := todoisttest.NewServer(t, testToken)
+
+	cfg := testConfig(t, todoistServer)
+	cfg.Jira = []config.JiraConfig{{
+		Site:     jiraServer.URL,
+		Username: testJiraUsername,
+		Token:    testJiraToken,
+		JQL:      "project = PRJ",
+	}}
+
+	summary := RunJobs(context.Background(), cfg, testLogger(), []string{cfg.JiraJob(0)})
+	require.Zero(t, summary.Failed, summary.Failures)
+	require.Len(t, todoistServer.Tasks(), 1)
+	assert.Equal(t, 1, todoistServer.Tasks()[0].Priority)
+	assert.NotContains(t, todoistServer.Requests(), "POST tasks/{id}", "the task already has the priority of its issue")
+}
+
+func TestUndoJiraDueTime(t *testing.T) {
+	jiraServer := jiratest.NewServer(t, testJiraUsername, testJiraToken)
+	jiraServer.AddIssue(jiratest.Issue{Key: "PRJ-1", Summary: "Fix the login", Status: "To Do", DueDate: "2024-03-20"})
+	todoistServer := todoisttest.NewServer(t, testToken)
+	task := todoistServer.AddTask(todoisttest.Task{
+		Content: "[[PRJ-1] Fix the login](" + jiraServer.URL + "/browse/PRJ-1)",
+		Due:     "2024-03-18T10:00:00",
+	})
+
+	cfg := testConfig(t, todoistServer)
+	cfg.Jira = []config.JiraConfig{{
+		Site:           jiraServer.URL,
+		Username:       testJiraUsername,
+		Token:          testJiraToken,
+		JQL:            "project = PRJ",
+		DueDateSources: []string{config.DueDateSourceDueDate},
+	}}
+
+	summary := RunJobs(context.Background(), cfg, testLogger(), []string{cfg.JiraJob(0)})
+	require.Zero(t, summary.Failed, summary.Failures)
+	rescheduled, _ := todoistServer.Task(task.ID)
+	require.Equal(t, "2024-03-20", rescheduled.Due)
+
+	undo := Undo(context.Background(), cfg, testLogger(), summary.RunID)
+	require.False(t, undo.HasFailures(), undo.Failures)
+	restored, _ := todoistServer.Task(task.ID)
+	assert.Equal(t, "2024-03-18T10:00:00", restored.Due, "the time of the task is restored")
+}
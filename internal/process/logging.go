@@ -0,0 +1,40 @@
+package process
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Fields attached to the log entries of a run, so that everything that happened in a run or to an
+// issue, task or project can be searched for.
+const (
+	fieldRunID     = "run_id"
+	fieldProcess   = "process"
+	fieldJiraSite  = "jira_site"
+	fieldIssueKey  = "issue_key"
+	fieldTaskID    = "task_id"
+	fieldProjectID = "project_id"
+)
+
+const runIDSuffixBytes = 3
+
+// newRunID returns the ID of a run started at start; IDs sort by start time.
+func newRunID(start time.Time) string {
+	suffix := make([]byte, runIDSuffixBytes)
+	if _, err := rand.Read(suffix); err != nil {
+		return start.UTC().Format("20060102T150405.000")
+	}
+	return start.UTC().Format("20060102T150405") + "-" + hex.EncodeToString(suffix)
+}
+
+// fieldsOf returns a copy of the fields of entry.
+func fieldsOf(entry *logrus.Entry) logrus.Fields {
+	fields := make(logrus.Fields, len(entry.Data))
+	for key, value := range entry.Data {
+		fields[key] = value
+	}
+	return fields
+}
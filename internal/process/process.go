@@ -1,29 +1,201 @@
 package process
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/fabiocorneti/todoist-assistant/internal/config"
+	"github.com/fabiocorneti/todoist-assistant/internal/httpclient"
+	"github.com/fabiocorneti/todoist-assistant/internal/state"
 	"github.com/fabiocorneti/todoist-assistant/internal/todoist"
 	"github.com/sirupsen/logrus"
 )
 
-func RunProcess(cfg config.Config, logger *logrus.Logger) {
+// RunProcess runs every process once; errors on single items are collected in the returned
+// summary and do not stop the run. The run is stopped when ctx is cancelled or after cfg.RunTimeout.
+func RunProcess(ctx context.Context, cfg config.Config, logger *logrus.Logger) *Summary {
+	var jobs []string
+	for _, job := range cfg.Jobs() {
+		jobs = append(jobs, job.Name)
+	}
+	return RunJobs(ctx, cfg, logger, jobs)
+}
+
+// RunJobs is like RunProcess but only runs the processes of the named jobs, see config.Config.Jobs.
+// Every log entry of the run carries the ID of the run, which is also set in the summary.
+func RunJobs(ctx context.Context, cfg config.Config, baseLogger *logrus.Logger, jobs []string) *Summary {
 	start := time.Now()
-	todoistClient := todoist.NewTodoistClient(cfg.Todoist.Token, cfg.IsTest())
+	summary := &Summary{RunID: newRunID(start)}
+	logger := baseLogger.WithField(fieldRunID, summary.RunID)
+
+	selected := make(map[string]bool, len(jobs))
+	for _, job := range jobs {
+		selected[job] = true
+	}
+	var jiraInstances []config.JiraConfig
+	for i, jiraCfg := range cfg.Jira {
+		if selected[cfg.JiraJob(i)] {
+			jiraInstances = append(jiraInstances, jiraCfg)
+		}
+	}
+	cfg.Jira = jiraInstances
+	logger.Debugf("Running jobs: %s", strings.Join(jobs, ", "))
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.RunTimeout)
+	defer cancel()
+
+	defer summary.Log(logger)
+
+	cassette, err := OpenCassette(cfg)
+	if err != nil {
+		summary.fail(logger, ItemRun, "HTTP cassette", err)
+		return summary
+	}
+	defer checkCassette(logger, summary, cassette)
+	todoistClient, err := newTodoistClient(cfg, logger, summary.RunID, cassette)
+	if err != nil {
+		summary.fail(logger, ItemRun, "Todoist client", err)
+		return summary
+	}
+
+	store, err := state.Open(cfg.StatePath)
+	if err != nil {
+		summary.fail(logger, ItemRun, cfg.StatePath, fmt.Errorf("error opening state store: %w", err))
+		return summary
+	}
+	todoistClient.RestoreSyncState(store.TodoistSync())
 
 	logger.Debug("Getting projects")
-	projects, err := todoistClient.GetProjects()
+	projects, err := todoistClient.GetProjects(ctx)
 	if err != nil {
-		logger.Fatalf("Error fetching Todoist projects: %v", err)
+		summary.fail(logger, ItemRun, "Todoist projects", fmt.Errorf("error fetching Todoist projects: %w", err))
+		return summary
 	}
 
+	var jiraProcess *JiraProcess
 	if len(cfg.Jira) > 0 {
-		jiraProcess := NewJiraProcess(cfg, logger, todoistClient, projects)
-		jiraProcess.ProcessJiraInstances()
+		jiraLogger := logger.WithField(fieldProcess, config.ProcessJira)
+		jiraProcess = NewJiraProcess(cfg, jiraLogger, todoistClient, projects, store)
+		if cassette != nil {
+			jiraProcess.UseCassette(cassette)
+		}
+		jiraProcess.ProcessJiraInstances(ctx, summary)
+	}
+
+	if selected[config.ProcessProjects] {
+		projectsLogger := logger.WithField(fieldProcess, config.ProcessProjects)
+		projectsProcess := NewProjectsProcess(cfg, projectsLogger, todoistClient, projects, store)
+		projectsProcess.ProcessProjects(ctx, summary)
 	}
 
-	projectsProcess := NewProjectsProcess(cfg, logger, todoistClient, projects)
-	projectsProcess.ProcessProjects()
+	if cfg.DryRun {
+		if err = todoistClient.Plan().Write(os.Stdout, cfg.PlanFormat); err != nil {
+			logger.Errorf("Error printing change plan: %v", err)
+		}
+		logger.Infof("Completed dry run in %f seconds", time.Since(start).Seconds())
+		return summary
+	}
+
+	flushed := flushTodoist(ctx, cfg, logger, summary, todoistClient)
+	if jiraProcess != nil {
+		jiraProcess.RecordLinks()
+	}
+	// the cache of a failed flush has changes that were not applied
+	if syncState, ok := todoistClient.SyncState(); ok && flushed {
+		store.SetTodoistSync(syncState)
+	}
+	if err = store.Save(); err != nil {
+		summary.fail(logger, ItemRun, cfg.StatePath, fmt.Errorf("error saving state store: %w", err))
+	}
 	logger.Infof("Completed update in %f seconds", time.Since(start).Seconds())
+	return summary
+}
+
+// flushTodoist sends the pending changes of the Todoist client of a run and reports the changes
+// that could not be sent or audited; it returns false if some changes could not be sent.
+func flushTodoist(ctx context.Context, cfg config.Config, logger *logrus.Entry, summary *Summary,
+	todoistClient *todoist.Client) bool {
+	err := todoistClient.Flush(ctx)
+	if err != nil {
+		failFlush(logger, summary, err)
+	}
+	if auditErr := todoistClient.AuditError(); auditErr != nil {
+		summary.fail(logger, ItemRun, cfg.AuditPath, auditErr)
+	}
+	return err == nil
+}
+
+// failFlush reports the changes that could not be sent when flushing the Todoist client: every
+// command rejected by the Sync API is a failure of the task it changed.
+func failFlush(logger *logrus.Entry, summary *Summary, err error) {
+	errs := []error{err}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	}
+	for _, err := range errs {
+		var commandError *todoist.CommandError
+		if errors.As(err, &commandError) {
+			summary.fail(logger.WithField(fieldTaskID, commandError.TaskID), ItemTask, commandError.TaskID, err)
+			continue
+		}
+		summary.fail(logger, ItemRun, "Todoist changes", fmt.Errorf("error sending pending Todoist changes: %w", err))
+	}
+}
+
+// newTodoistClient creates the Todoist client of a run, which records its writes in the audit log
+// or, in dry-run mode, only plans them; cassette is used if not nil.
+func newTodoistClient(cfg config.Config, logger *logrus.Entry, runID string,
+	cassette httpclient.Cassette) (*todoist.Client, error) {
+	todoistClient, err := todoist.NewTodoistClient(cfg.Todoist.APIURL, cfg.Todoist.Token, cfg.Todoist.Transport,
+		cfg.RetryPolicy(), cfg.RequestTimeout, cfg.IsTest())
+	if err != nil {
+		return nil, fmt.Errorf("error creating Todoist client: %w", err)
+	}
+	if cassette != nil {
+		todoistClient.UseCassette(cassette)
+	}
+
+	if cfg.DryRun {
+		logger.Info("Dry-run mode enabled, no changes will be sent to Todoist")
+		todoistClient.EnableDryRun()
+	} else {
+		todoistClient.EnableAudit(todoist.NewAuditLog(cfg.AuditPath, runID))
+	}
+	return todoistClient, nil
+}
+
+// OpenCassette returns the cassette configured with recordPath or replayPath, or nil if requests
+// are neither recorded nor replayed. Recorded interactions are scrubbed of the configured tokens
+// and usernames. Requests cannot be replayed with the Sync API transport, whose commands carry
+// random IDs that the recorded responses refer to.
+func OpenCassette(cfg config.Config) (httpclient.Cassette, error) {
+	switch {
+	case cfg.ReplayPath != "" && cfg.Todoist.Transport == todoist.TransportSync:
+		return nil, fmt.Errorf("requests cannot be replayed with the %s Todoist transport", todoist.TransportSync)
+	case cfg.ReplayPath != "":
+		return httpclient.LoadReplayer(cfg.ReplayPath)
+	case cfg.RecordPath != "":
+		secrets := []string{cfg.Todoist.Token}
+		for _, jiraConfig := range cfg.Jira {
+			secrets = append(secrets, jiraConfig.Token, jiraConfig.Username)
+		}
+		return httpclient.NewRecorder(cfg.RecordPath, secrets...), nil
+	default:
+		return nil, nil
+	}
+}
+
+// checkCassette reports the failures of the cassette of a run that did not fail its requests, such
+// as requests that could not be recorded; cassette may be nil.
+func checkCassette(logger *logrus.Entry, summary *Summary, cassette httpclient.Cassette) {
+	if cassette == nil {
+		return
+	}
+	if err := cassette.Err(); err != nil {
+		summary.fail(logger, ItemRun, "HTTP cassette", err)
+	}
 }
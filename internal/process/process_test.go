@@ -0,0 +1,204 @@
+package process
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fabiocorneti/todoist-assistant/internal/config"
+	"github.com/fabiocorneti/todoist-assistant/internal/todoist/todoisttest"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testToken = "secret"
+
+// testConfig returns a configuration using the fake Todoist server and files in a temporary directory.
+func testConfig(t *testing.T, server *todoisttest.Server) config.Config {
+	dir := t.TempDir()
+	cfg := config.Config{
+		UpdateInterval: 5,
+		StatePath:      filepath.Join(dir, "state.json"),
+		AuditPath:      filepath.Join(dir, "audit.jsonl"),
+		RunTimeout:     time.Minute,
+		RequestTimeout: 5 * time.Second,
+		Retry: config.RetryConfig{
+			MaxAttempts:    3,
+			InitialBackoff: time.Millisecond,
+			MaxBackoff:     time.Second,
+		},
+	}
+	cfg.Todoist.APIURL = server.URL
+	cfg.Todoist.Token = testToken
+	cfg.Todoist.Transport = "rest"
+	cfg.Todoist.NextActionLabel = "Next Action"
+	cfg.Todoist.ProjectsLabelPrefix = "Projects"
+	return cfg
+}
+
+func testLogger() *logrus.Logger {
+	logger, _ := test.NewNullLogger()
+	return logger
+}
+
+func TestRunProcessProjects(t *testing.T) {
+	testCases := []struct {
+		name             string
+		setup            func(server *todoisttest.Server)
+		expectedLabels   map[string][]string
+		expectedFailures int
+	}{
+		{
+			name: "Project and next action labels",
+			expectedLabels: map[string][]string{
+				"Write the plan":   {"Projects/Garden", "Next Action"},
+				"Buy the seeds":    {"Projects/Garden"},
+				"Call the plumber": {"Projects/House"},
+			},
+		},
+		{
+			name: "Rate limited requests are retried",
+			setup: func(server *todoisttest.Server) {
+				server.RateLimit(2, 0)
+			},
+			expectedLabels: map[string][]string{
+				"Write the plan":   {"Projects/Garden", "Next Action"},
+				"Buy the seeds":    {"Projects/Garden"},
+				"Call the plumber": {"Projects/House"},
+			},
+		},
+		{
+			name: "Failed updates do not stop the run",
+			setup: func(server *todoisttest.Server) {
+				server.InjectError("POST tasks/{id}", http.StatusInternalServerError, 1)
+			},
+			// the next action moves to the following task
+			expectedLabels: map[string][]string{
+				"Write the plan":   {},
+				"Buy the seeds":    {"Projects/Garden", "Next Action"},
+				"Call the plumber": {"Projects/House"},
+			},
+			expectedFailures: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := todoisttest.NewServer(t, testToken)
+			parent := server.AddProject("Projects", "")
+			garden := server.AddProject("Garden", parent.ID)
+			house := server.AddProject("House", parent.ID)
+			server.AddTask(todoisttest.Task{ProjectID: garden.ID, Content: "Write the plan"})
+			server.AddTask(todoisttest.Task{ProjectID: garden.ID, Content: "Buy the seeds", Labels: []string{"Next Action"}})
+			server.AddTask(todoisttest.Task{ProjectID: house.ID, Content: "* Call the plumber"})
+			server.AddTask(todoisttest.Task{Content: "Inbox task"})
+			if tc.setup != nil {
+				tc.setup(server)
+			}
+
+			cfg := testConfig(t, server)
+			cfg.Todoist.ParentProjectName = "Projects"
+			cfg.Todoist.AssignProjectLabel = true
+			cfg.Todoist.AssignNextActionLabel = true
+
+			summary := RunProcess(context.Background(), cfg, testLogger())
+			assert.Equal(t, tc.expectedFailures, summary.Failed, summary.Failures)
+
+			labels := map[string][]string{}
+			for _, task := range server.Tasks() {
+				labels[task.Content] = task.Labels
+			}
+			assert.ElementsMatch(t, tc.expectedLabels["Write the plan"], labels["Write the plan"])
+			assert.ElementsMatch(t, tc.expectedLabels["Buy the seeds"], labels["Buy the seeds"])
+			assert.ElementsMatch(t, tc.expectedLabels["Call the plumber"], labels["* Call the plumber"])
+			assert.Empty(t, labels["Inbox task"])
+		})
+	}
+}
+
+func TestRunProcessNextActionLeftProject(t *testing.T) {
+	server := todoisttest.NewServer(t, testToken)
+	parent := server.AddProject("Projects", "")
+	garden := server.AddProject("Garden", parent.ID)
+	house := server.AddProject("House", parent.ID)
+	someday := server.AddProject("Someday", "")
+	plan := server.AddTask(todoisttest.Task{ProjectID: garden.ID, Content: "Write the plan"})
+	server.AddTask(todoisttest.Task{ProjectID: garden.ID, Content: "Buy the seeds"})
+	roof := server.AddTask(todoisttest.Task{ProjectID: house.ID, Content: "Fix the roof"})
+
+	cfg := testConfig(t, server)
+	cfg.Todoist.ParentProjectName = "Projects"
+	cfg.Todoist.AssignNextActionLabel = true
+
+	summary := RunProcess(context.Background(), cfg, testLogger())
+	require.Equal(t, 0, summary.Failed, summary.Failures)
+	task, _ := server.Task(plan.ID)
+	require.Equal(t, []string{"Next Action"}, task.Labels)
+
+	server.Move(plan.ID, someday.ID)
+	server.Complete(roof.ID)
+	summary = RunProcess(context.Background(), cfg, testLogger())
+	assert.Equal(t, 0, summary.Failed, summary.Failures)
+
+	labels := map[string][]string{}
+	for _, task := range server.Tasks() {
+		labels[task.Content] = task.Labels
+	}
+	assert.Empty(t, labels["Write the plan"], "the label is removed from a next action that left the projects")
+	assert.Equal(t, []string{"Next Action"}, labels["Buy the seeds"])
+	assert.Equal(t, []string{"Next Action"}, labels["Fix the roof"], "completed tasks are left as they are")
+}
+
+func TestRunProcessUnauthorized(t *testing.T) {
+	server := todoisttest.NewServer(t, testToken)
+	cfg := testConfig(t, server)
+	cfg.Todoist.Token = "wrong"
+
+	summary := RunProcess(context.Background(), cfg, testLogger())
+	require.Len(t, summary.Failures, 1)
+	assert.Equal(t, "unauthorized", summary.Failures[0].Category)
+	assert.Equal(t, []string{"GET projects"}, server.Requests())
+}
+
+func TestRunProcessInvalidTodoistClient(t *testing.T) {
+	server := todoisttest.NewServer(t, testToken)
+	cfg := testConfig(t, server)
+	cfg.Todoist.Transport = "graphql"
+
+	summary := RunProcess(context.Background(), cfg, testLogger())
+	require.Len(t, summary.Failures, 1)
+	assert.Equal(t, "Todoist client", summary.Failures[0].Item)
+	assert.Empty(t, server.Requests())
+}
+
+func TestUndo(t *testing.T) {
+	server := todoisttest.NewServer(t, testToken)
+	project := server.AddProject("Garden", "")
+	first := server.AddTask(todoisttest.Task{ProjectID: project.ID, Content: "Write the plan", Labels: []string{"Work"}})
+	second := server.AddTask(todoisttest.Task{ProjectID: project.ID, Content: "Buy the seeds",
+		Labels: []string{"Next Action"}})
+
+	cfg := testConfig(t, server)
+	cfg.Todoist.AssignProjectLabel = true
+	cfg.Todoist.AssignNextActionLabel = true
+
+	summary := RunProcess(context.Background(), cfg, testLogger())
+	require.False(t, summary.HasFailures(), summary.Failures)
+	task, _ := server.Task(first.ID)
+	assert.ElementsMatch(t, []string{"Work", "Projects/Garden", "Next Action"}, task.Labels)
+
+	undo := Undo(context.Background(), cfg, testLogger(), summary.RunID)
+	require.False(t, undo.HasFailures(), undo.Failures)
+	task, _ = server.Task(first.ID)
+	assert.Equal(t, []string{"Work"}, task.Labels)
+	task, _ = server.Task(second.ID)
+	assert.Equal(t, []string{"Next Action"}, task.Labels)
+
+	undo = Undo(context.Background(), cfg, testLogger(), "unknown")
+	assert.True(t, undo.HasFailures())
+	assert.Contains(t, undo.Failures[0].Error, "no writes recorded for run unknown")
+}
@@ -1,9 +1,13 @@
 package process
 
 import (
+	"context"
+	"fmt"
 	"strings"
 
 	"github.com/fabiocorneti/todoist-assistant/internal/config"
+	"github.com/fabiocorneti/todoist-assistant/internal/httpclient"
+	"github.com/fabiocorneti/todoist-assistant/internal/state"
 	"github.com/fabiocorneti/todoist-assistant/internal/todoist"
 	"github.com/fabiocorneti/todoist-assistant/internal/utils"
 	"github.com/sirupsen/logrus"
@@ -11,88 +15,168 @@ import (
 
 type ProjectsProcess struct {
 	config        config.Config
-	logger        *logrus.Logger
+	logger        *logrus.Entry
 	todoistClient *todoist.Client
 	projects      []todoist.Project
+	store         *state.Store
 }
 
-func NewProjectsProcess(cfg config.Config, logger *logrus.Logger,
-	todoistClient *todoist.Client, projects []todoist.Project) *ProjectsProcess {
+func NewProjectsProcess(cfg config.Config, logger *logrus.Entry,
+	todoistClient *todoist.Client, projects []todoist.Project, store *state.Store) *ProjectsProcess {
 	process := ProjectsProcess{
 		config:        cfg,
 		logger:        logger,
 		todoistClient: todoistClient,
 		projects:      projects,
+		store:         store,
 	}
 	return &process
 }
 
-func (process ProjectsProcess) ProcessProjects() {
+func (process ProjectsProcess) ProcessProjects(ctx context.Context, summary *Summary) {
 	var err error
 	var parentProjectID string
 	parentProjectID, err = process.getParentProjectID()
 	if err != nil {
-		process.logger.Fatalf("Error locating parent project: %v", err)
+		summary.fail(process.logger, ItemProject, process.config.Todoist.ParentProjectName, fmt.Errorf("error locating parent project: %w", err))
+		return
 	}
 
 	process.logger.Info("Processing projects")
+	// nextActions are the next actions of the processed projects; seen are the tasks of the
+	// processed projects, which is all of them unless fetched is false.
+	nextActions := make(map[string]string)
+	seen := make(map[string]bool)
+	fetched := true
 	for _, project := range process.projects {
+		if ctx.Err() != nil {
+			summary.fail(process.logger, ItemRun, "projects", ctx.Err())
+			return
+		}
 		projectCopy := project
 		if parentProjectID != "" && project.ParentID != parentProjectID {
 			continue
 		}
-		process.logger.Debugf("Getting tasks for project %s (%s)", project.ID, project.Name)
+		projectProcess := process.with(fieldProjectID, project.ID)
+		projectProcess.logger.Debugf("Getting tasks for project %s (%s)", project.ID, project.Name)
 		var tasks []todoist.Task
-		tasks, err = process.todoistClient.GetTasksForProject(project.ID)
+		tasks, err = process.todoistClient.GetTasksForProject(ctx, project.ID)
 		if err != nil {
-			process.logger.Fatalf("Error fetching Todoist tasks for project: %v", err)
-			break
+			summary.fail(projectProcess.logger, ItemProject, project.Name, fmt.Errorf("error fetching Todoist tasks for project: %w", err))
+			fetched = false
+			continue
 		}
 		setNextAction := true
+		nextActionID := ""
 		for _, task := range tasks {
+			seen[task.ID] = true
 			taskCopy := task
-			setNextAction = process.processTask(&projectCopy, &taskCopy, setNextAction)
+			taskProcess := projectProcess.with(fieldTaskID, task.ID)
+			previous := setNextAction
+			setNextAction, err = taskProcess.processTask(ctx, &projectCopy, &taskCopy, setNextAction)
+			switch {
+			case httpclient.IsNotFound(err):
+				taskProcess.logger.Debugf("Task %s was completed or deleted while processing", task.Content)
+				summary.skip()
+			case err != nil:
+				summary.fail(taskProcess.logger, ItemTask, task.Content, err)
+			default:
+				summary.succeed()
+			}
+			if previous && !setNextAction && process.config.Todoist.AssignNextActionLabel {
+				nextActionID = task.ID
+			}
 		}
-		process.logger.Infof("Completed processing of project %s (%s)", project.ID, project.Name)
+		nextActions[project.ID] = nextActionID
+		projectProcess.logger.Infof("Completed processing of project %s (%s)", project.ID, project.Name)
+	}
+	if process.config.Todoist.AssignNextActionLabel {
+		process.recordNextActions(ctx, summary, nextActions, seen, fetched)
 	}
 }
 
-func (process ProjectsProcess) processTask(project *todoist.Project, task *todoist.Task, setNextAction bool) bool {
+// processTask assigns labels to a task; it returns whether the next action still has to be set.
+func (process ProjectsProcess) processTask(ctx context.Context, project *todoist.Project, task *todoist.Task,
+	setNextAction bool) (bool, error) {
 	label := process.config.Todoist.ProjectsLabelPrefix + "/" + project.Name
 	if process.config.Todoist.AssignProjectLabel {
 		process.logger.Debugf("Processing project task %s", task.Content)
 		if !utils.Contains(task.Labels, label) {
-			err := process.todoistClient.AddLabelsToTask(task.ID, []string{label})
+			err := process.todoistClient.AddLabelsToTask(ctx, task.ID, []string{label})
 			if err != nil {
-				process.logger.Fatalf("Error adding project label to task %s", task.Content)
-				return false
+				return setNextAction, fmt.Errorf("error adding project label to task %s: %w", task.Content, err)
 			}
 		}
 	}
 	if !process.config.Todoist.AssignNextActionLabel {
-		return false
+		return false, nil
 	}
 	if !setNextAction && utils.Contains(task.Labels, process.config.Todoist.NextActionLabel) {
-		err := process.todoistClient.RemoveLabelsFromTask(task.ID, []string{process.config.Todoist.NextActionLabel})
+		err := process.todoistClient.RemoveLabelsFromTask(ctx, task.ID, []string{process.config.Todoist.NextActionLabel})
 		if err != nil {
-			process.logger.Fatalf("Error removing next action label from task %s: %v", task.Content, err)
-			return false
+			return false, fmt.Errorf("error removing next action label from task %s: %w", task.Content, err)
 		}
-		return false
+		return false, nil
 	}
 
 	// NOTE: do not set next action label on uncompletable tasks
 	if setNextAction && !strings.HasPrefix(task.Content, "* ") {
 		if !utils.Contains(task.Labels, process.config.Todoist.NextActionLabel) {
-			err := process.todoistClient.AddLabelsToTask(task.ID, []string{process.config.Todoist.NextActionLabel})
+			err := process.todoistClient.AddLabelsToTask(ctx, task.ID, []string{process.config.Todoist.NextActionLabel})
 			if err != nil {
-				process.logger.Fatalf("Error adding next action label to task %s", task.Content)
-				return false
+				return false, fmt.Errorf("error adding next action label to task %s: %w", task.Content, err)
+			}
+		}
+		return false, nil
+	}
+	return setNextAction, nil
+}
+
+// with returns a copy of the process whose log entries carry the given field.
+func (process ProjectsProcess) with(field string, value any) ProjectsProcess {
+	process.logger = process.logger.WithField(field, value)
+	return process
+}
+
+// recordNextActions stores the next action of each project. A previous next action that is not in
+// any of the projects, e.g. because it was moved to the inbox, is not reached by processTask, so its
+// label is removed here unless it was completed or deleted; if the tasks of some projects could not
+// be fetched, the previous next actions are kept until the next run, since they may be in those.
+func (process ProjectsProcess) recordNextActions(ctx context.Context, summary *Summary,
+	nextActions map[string]string, seen map[string]bool, fetched bool) {
+	label := process.config.Todoist.NextActionLabel
+	for _, project := range process.projects {
+		taskID, processed := nextActions[project.ID]
+		if !processed {
+			continue
+		}
+		projectProcess := process.with(fieldProjectID, project.ID)
+		previous := process.store.NextAction(project.ID)
+		if previous != "" && previous != taskID && !seen[previous] {
+			if !fetched {
+				continue
+			}
+			taskProcess := projectProcess.with(fieldTaskID, previous)
+			if process.config.DryRun {
+				taskProcess.logger.Infof("Would remove the next action label from task %s, the previous next action of "+
+					"project %s, unless it was completed or deleted", previous, project.Name)
+				continue
+			}
+			err := process.todoistClient.RemoveLabelsFromTask(ctx, previous, []string{label})
+			switch {
+			case httpclient.IsNotFound(err):
+				taskProcess.logger.Debugf("Previous next action of project %s was completed or deleted", project.Name)
+			case err != nil:
+				summary.fail(taskProcess.logger, ItemTask, previous,
+					fmt.Errorf("error removing next action label from task %s: %w", previous, err))
+				continue
+			default:
+				taskProcess.logger.Infof("Removed the next action label from task %s, which left project %s",
+					previous, project.Name)
 			}
 		}
-		return false
+		process.store.SetNextAction(project.ID, taskID)
 	}
-	return setNextAction
 }
 
 func (process ProjectsProcess) getParentProjectID() (string, error) {
@@ -0,0 +1,95 @@
+package process
+
+import (
+	"sync"
+
+	"github.com/fabiocorneti/todoist-assistant/internal/httpclient"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	ItemJiraInstance = "jira instance"
+	ItemJiraIssue    = "jira issue"
+	ItemProject      = "project"
+	ItemTask         = "task"
+	ItemRun          = "run"
+)
+
+// Failure is an item that could not be processed during a run.
+type Failure struct {
+	Kind     string `json:"kind"`
+	Item     string `json:"item"`
+	Category string `json:"category"`
+	Error    string `json:"error"`
+
+	// fields are the log fields of the item, such as its issue key.
+	fields logrus.Fields
+}
+
+// Summary collects the outcome of the items processed during a run.
+type Summary struct {
+	mu        sync.Mutex
+	RunID     string    `json:"runId"`
+	Succeeded int       `json:"succeeded"`
+	Failed    int       `json:"failed"`
+	Skipped   int       `json:"skipped"`
+	Failures  []Failure `json:"failures"`
+}
+
+func (s *Summary) succeed() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Succeeded++
+}
+
+func (s *Summary) skip() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Skipped++
+}
+
+// fail records a failure; entry is the logger of the item, whose fields are logged with the failure.
+func (s *Summary) fail(entry *logrus.Entry, kind, item string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Failed++
+	s.Failures = append(s.Failures, Failure{
+		Kind:     kind,
+		Item:     item,
+		Category: httpclient.ErrorCategory(err),
+		Error:    err.Error(),
+		fields:   fieldsOf(entry),
+	})
+}
+
+// HasFailures returns true if at least one item failed.
+func (s *Summary) HasFailures() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Failed > 0
+}
+
+// Log writes the summary and every failure to the logger.
+func (s *Summary) Log(logger *logrus.Entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, failure := range s.Failures {
+		logger.WithFields(failure.fields).WithFields(logrus.Fields{
+			"kind":     failure.Kind,
+			"item":     failure.Item,
+			"category": failure.Category,
+		}).Errorf("Failed to process %s %s: %s", failure.Kind, failure.Item, failure.Error)
+	}
+
+	entry := logger.WithFields(logrus.Fields{
+		"succeeded": s.Succeeded,
+		"failed":    s.Failed,
+		"skipped":   s.Skipped,
+	})
+	if s.Failed > 0 {
+		entry.Errorf("Run completed with %d failures", s.Failed)
+		return
+	}
+	entry.Info("Run completed")
+}
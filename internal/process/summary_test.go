@@ -0,0 +1,46 @@
+package process
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummaryLog(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	runLogger := logger.WithField(fieldRunID, "run")
+	issueLogger := runLogger.WithFields(logrus.Fields{fieldIssueKey: "PRJ-1", fieldTaskID: "42"})
+
+	summary := &Summary{RunID: "run"}
+	summary.succeed()
+	summary.fail(issueLogger, ItemJiraIssue, "PRJ-1", errors.New("boom"))
+	// fields added to the logger after the failure are not logged with it
+	issueLogger.Data[fieldTaskID] = "43"
+	summary.Log(runLogger)
+
+	entries := hook.AllEntries()
+	require.Len(t, entries, 2)
+	assert.Equal(t, logrus.ErrorLevel, entries[0].Level)
+	assert.Equal(t, "Failed to process jira issue PRJ-1: boom", entries[0].Message)
+	assert.Equal(t, "run", entries[0].Data[fieldRunID])
+	assert.Equal(t, "PRJ-1", entries[0].Data[fieldIssueKey])
+	assert.Equal(t, "42", entries[0].Data[fieldTaskID])
+	assert.Equal(t, "other", entries[0].Data["category"])
+
+	assert.Equal(t, "Run completed with 1 failures", entries[1].Message)
+	assert.Equal(t, "run", entries[1].Data[fieldRunID])
+	assert.NotContains(t, entries[1].Data, fieldIssueKey)
+}
+
+func TestNewRunID(t *testing.T) {
+	start := time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)
+	first := newRunID(start)
+	assert.Regexp(t, `^20240305T100000-[0-9a-f]{6}$`, first)
+	assert.NotEqual(t, first, newRunID(start))
+	assert.Less(t, first, newRunID(start.Add(time.Second)))
+}
@@ -0,0 +1,136 @@
+package process
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/fabiocorneti/todoist-assistant/internal/config"
+	"github.com/fabiocorneti/todoist-assistant/internal/httpclient"
+	"github.com/fabiocorneti/todoist-assistant/internal/todoist"
+	"github.com/sirupsen/logrus"
+)
+
+const processUndo = "undo"
+
+// Undo reverts the writes recorded in the audit log for the run with the given ID, newest first:
+// labels, priorities, contents, descriptions and due dates are restored, completed tasks are
+// reopened and created tasks are deleted. The undo is a run itself, whose writes are audited under
+// its own ID.
+func Undo(ctx context.Context, cfg config.Config, baseLogger *logrus.Logger, runID string) *Summary {
+	start := time.Now()
+	summary := &Summary{RunID: newRunID(start)}
+	logger := baseLogger.WithFields(logrus.Fields{fieldRunID: summary.RunID, fieldProcess: processUndo})
+	defer summary.Log(logger)
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.RunTimeout)
+	defer cancel()
+
+	entries, err := todoist.ReadAuditLog(cfg.AuditPath, runID)
+	if err != nil {
+		summary.fail(logger, ItemRun, cfg.AuditPath, err)
+		return summary
+	}
+	if len(entries) == 0 {
+		summary.fail(logger, ItemRun, runID, fmt.Errorf("no writes recorded for run %s in %s", runID, cfg.AuditPath))
+		return summary
+	}
+	logger.Infof("Undoing %d writes of run %s", len(entries), runID)
+
+	cassette, err := OpenCassette(cfg)
+	if err != nil {
+		summary.fail(logger, ItemRun, "HTTP cassette", err)
+		return summary
+	}
+	defer checkCassette(logger, summary, cassette)
+	todoistClient, err := newTodoistClient(cfg, logger, summary.RunID, cassette)
+	if err != nil {
+		summary.fail(logger, ItemRun, "Todoist client", err)
+		return summary
+	}
+	created := make(map[string]bool)
+	for _, entry := range entries {
+		if entry.Action == todoist.ActionCreateTask {
+			created[entry.TaskID] = true
+		}
+	}
+
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		if ctx.Err() != nil {
+			summary.fail(logger, ItemRun, runID, ctx.Err())
+			break
+		}
+		taskLogger := logger.WithField(fieldTaskID, entry.TaskID)
+		if created[entry.TaskID] && entry.Action != todoist.ActionCreateTask {
+			// the task is deleted anyway
+			summary.skip()
+			continue
+		}
+		undone, err := undoEntry(ctx, todoistClient, entry)
+		switch {
+		case httpclient.IsNotFound(err):
+			taskLogger.Infof("Task %s no longer exists, cannot undo %s", entry.Content, entry.Action)
+			summary.skip()
+		case err != nil:
+			summary.fail(taskLogger, ItemTask, entry.Content, fmt.Errorf("error undoing %s: %w", entry.Action, err))
+		case !undone:
+			taskLogger.Warnf("Cannot undo %s on task %s", entry.Action, entry.Content)
+			summary.skip()
+		default:
+			taskLogger.Debugf("Undid %s on task %s", entry.Action, entry.Content)
+			summary.succeed()
+		}
+	}
+
+	if cfg.DryRun {
+		if err = todoistClient.Plan().Write(os.Stdout, cfg.PlanFormat); err != nil {
+			logger.Errorf("Error printing change plan: %v", err)
+		}
+		return summary
+	}
+	flushTodoist(ctx, cfg, logger, summary, todoistClient)
+	logger.Infof("Completed undo in %f seconds", time.Since(start).Seconds())
+	return summary
+}
+
+// undoEntry applies the inverse of a write; it returns false if the write cannot be undone, e.g.
+// a deleted task or a priority that was not known before the write.
+func undoEntry(ctx context.Context, todoistClient *todoist.Client, entry todoist.AuditEntry) (bool, error) {
+	switch entry.Action {
+	case todoist.ActionCreateTask:
+		return true, todoistClient.DeleteTask(ctx, entry.TaskID)
+	case todoist.ActionCompleteTask:
+		return true, todoistClient.ReopenTask(ctx, entry.TaskID)
+	case todoist.ActionReopenTask:
+		return true, todoistClient.CompleteTask(ctx, entry.TaskID)
+	case todoist.ActionReplaceLabels, todoist.ActionAddLabels, todoist.ActionRemoveLabels:
+		if entry.Before == nil {
+			return false, nil
+		}
+		return true, todoistClient.ReplaceTaskLabels(ctx, entry.TaskID, entry.Before.Labels)
+	case todoist.ActionSetPriority:
+		if entry.Before == nil || entry.Before.Priority == 0 {
+			return false, nil
+		}
+		return true, todoistClient.SetTaskPriority(ctx, entry.TaskID, entry.Before.Priority)
+	case todoist.ActionUpdateContent:
+		if entry.Before == nil || entry.Before.Content == "" {
+			return false, nil
+		}
+		return true, todoistClient.UpdateTaskContent(ctx, entry.TaskID, entry.Before.Content)
+	case todoist.ActionUpdateDescription:
+		if entry.Before == nil {
+			return false, nil
+		}
+		return true, todoistClient.UpdateTaskDescription(ctx, entry.TaskID, entry.Before.Description)
+	case todoist.ActionSetDueDate:
+		if entry.Before == nil {
+			return false, nil
+		}
+		return true, todoistClient.SetTaskDueDate(ctx, entry.TaskID, entry.Before.DueDate)
+	default:
+		return false, nil
+	}
+}
@@ -0,0 +1,283 @@
+package schedule
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+const (
+	minutesPerHour = 60
+	// maxQuietWindows bounds the number of consecutive quiet windows skipped when looking for the
+	// end of quiet hours, e.g. a weekend followed by a night.
+	maxQuietWindows = 64
+)
+
+// Schedule returns the times at which a job runs.
+type Schedule interface {
+	// Next returns the first run time after t.
+	Next(t time.Time) time.Time
+}
+
+// every is a schedule running a job at a fixed interval.
+type every time.Duration
+
+func (e every) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(e))
+}
+
+// Parse parses a schedule, either an interval such as 5m or a cron expression with five fields
+// such as */5 8-18 * * mon-fri, evaluated in loc. Cron descriptors such as @hourly and
+// @every 1h are also accepted.
+func Parse(spec string, loc *time.Location) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if interval, err := time.ParseDuration(spec); err == nil {
+		if interval <= 0 {
+			return nil, fmt.Errorf("the interval %s must be greater than 0", spec)
+		}
+		return every(interval), nil
+	}
+	parsed, err := cron.ParseStandard(spec)
+	if err != nil {
+		return nil, fmt.Errorf("%q is neither an interval nor a cron expression: %w", spec, err)
+	}
+	if specSchedule, ok := parsed.(*cron.SpecSchedule); ok {
+		specSchedule.Location = loc
+	}
+	return parsed, nil
+}
+
+// QuietHours is a time window in which jobs do not run.
+type QuietHours struct {
+	// Days are the days on which the window starts; all days if empty.
+	Days []time.Weekday
+	// From and To are the start and end of the window in minutes from midnight; a window ending
+	// before it starts ends on the next day, and a window ending when it starts lasts all day.
+	From, To int
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// ParseQuietHours parses quiet hours from day names such as mon or monday and times of the day
+// such as 20:00; empty times mean midnight.
+func ParseQuietHours(days []string, from, to string) (QuietHours, error) {
+	var quietHours QuietHours
+	for _, day := range days {
+		name := strings.ToLower(strings.TrimSpace(day))
+		weekday, ok := weekdays[name]
+		if !ok && len(name) > 3 {
+			weekday, ok = weekdays[name[:3]]
+			ok = ok && strings.EqualFold(weekday.String(), name)
+		}
+		if !ok {
+			return quietHours, fmt.Errorf("unknown day %q", day)
+		}
+		quietHours.Days = append(quietHours.Days, weekday)
+	}
+
+	var err error
+	if quietHours.From, err = parseTimeOfDay(from); err != nil {
+		return quietHours, err
+	}
+	if quietHours.To, err = parseTimeOfDay(to); err != nil {
+		return quietHours, err
+	}
+	return quietHours, nil
+}
+
+func parseTimeOfDay(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	parsed, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected a time such as 08:30", value)
+	}
+	return parsed.Hour()*minutesPerHour + parsed.Minute(), nil
+}
+
+func (q QuietHours) startsOn(day time.Weekday) bool {
+	if len(q.Days) == 0 {
+		return true
+	}
+	for _, d := range q.Days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// end returns the end of the window containing t, and false if t is not in the window.
+func (q QuietHours) end(t time.Time) (time.Time, bool) {
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	minute := t.Hour()*minutesPerHour + t.Minute()
+	at := func(day time.Time, minutes int) time.Time {
+		return time.Date(day.Year(), day.Month(), day.Day(), minutes/minutesPerHour, minutes%minutesPerHour,
+			0, 0, day.Location())
+	}
+	tomorrow := midnight.AddDate(0, 0, 1)
+	yesterday := midnight.AddDate(0, 0, -1)
+
+	switch {
+	case q.From == q.To:
+		if q.startsOn(t.Weekday()) {
+			return at(tomorrow, q.To), true
+		}
+	case q.From < q.To:
+		if q.startsOn(t.Weekday()) && minute >= q.From && minute < q.To {
+			return at(midnight, q.To), true
+		}
+	default:
+		if q.startsOn(t.Weekday()) && minute >= q.From {
+			return at(tomorrow, q.To), true
+		}
+		if q.startsOn(yesterday.Weekday()) && minute < q.To {
+			return at(midnight, q.To), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Job is a named job with its schedule.
+type Job struct {
+	Name string
+	// Spec is the schedule of the job, see Parse.
+	Spec string
+}
+
+type scheduledJob struct {
+	spec     string
+	loc      *time.Location
+	schedule Schedule
+	next     time.Time
+}
+
+// Scheduler keeps track of when jobs are due, skipping quiet hours.
+type Scheduler struct {
+	loc        *time.Location
+	quietHours []QuietHours
+	jobs       map[string]*scheduledJob
+}
+
+// NewScheduler creates a Scheduler without jobs.
+func NewScheduler() *Scheduler {
+	return &Scheduler{loc: time.Local, jobs: map[string]*scheduledJob{}}
+}
+
+// Update replaces the jobs, the time zone and the quiet hours. Jobs whose schedule did not change
+// keep their next run, new jobs are due as soon as quiet hours allow and other jobs are due at
+// their next scheduled time after now.
+func (s *Scheduler) Update(jobs []Job, loc *time.Location, quietHours []QuietHours, now time.Time) error {
+	s.loc = loc
+	s.quietHours = quietHours
+	updated := map[string]*scheduledJob{}
+	for _, job := range jobs {
+		existing, ok := s.jobs[job.Name]
+		if ok && existing.spec == job.Spec && existing.loc.String() == loc.String() {
+			updated[job.Name] = existing
+			continue
+		}
+		parsed, err := Parse(job.Spec, loc)
+		if err != nil {
+			return fmt.Errorf("job %s: %w", job.Name, err)
+		}
+		scheduled := &scheduledJob{spec: job.Spec, loc: loc, schedule: parsed}
+		if ok {
+			scheduled.next = s.nextRun(parsed, now)
+		} else {
+			scheduled.next = s.quietEnd(now)
+		}
+		updated[job.Name] = scheduled
+	}
+	s.jobs = updated
+	return nil
+}
+
+// Next returns when the next job is due, or the zero time if there are no jobs.
+func (s *Scheduler) Next() time.Time {
+	var next time.Time
+	for _, job := range s.jobs {
+		if next.IsZero() || job.next.Before(next) {
+			next = job.next
+		}
+	}
+	return next
+}
+
+// Due returns the names of the jobs due at now, sorted.
+func (s *Scheduler) Due(now time.Time) []string {
+	var due []string
+	for name, job := range s.jobs {
+		if !job.next.After(now) {
+			due = append(due, name)
+		}
+	}
+	sort.Strings(due)
+	return due
+}
+
+// Quiet returns whether now is in quiet hours.
+func (s *Scheduler) Quiet(now time.Time) bool {
+	return !s.quietEnd(now).Equal(now)
+}
+
+// All returns the names of all the jobs, sorted.
+func (s *Scheduler) All() []string {
+	names := make([]string, 0, len(s.jobs))
+	for name := range s.jobs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Ran schedules the next run of the named jobs, which ran at the given time.
+func (s *Scheduler) Ran(names []string, at time.Time) {
+	for _, name := range names {
+		if job, ok := s.jobs[name]; ok {
+			job.next = s.nextRun(job.schedule, at)
+		}
+	}
+}
+
+// nextRun returns the first run time of schedule after t that is not in quiet hours; jobs running
+// at an interval run as soon as quiet hours end.
+func (s *Scheduler) nextRun(schedule Schedule, t time.Time) time.Time {
+	next := schedule.Next(t.In(s.loc))
+	for i := 0; i < maxQuietWindows; i++ {
+		end := s.quietEnd(next)
+		if end.Equal(next) {
+			return next
+		}
+		if _, ok := schedule.(every); ok {
+			return end
+		}
+		next = schedule.Next(end.Add(-time.Second))
+	}
+	return next
+}
+
+// quietEnd returns t if it is not in quiet hours, otherwise the time at which quiet hours end.
+func (s *Scheduler) quietEnd(t time.Time) time.Time {
+	t = t.In(s.loc)
+	for i := 0; i < maxQuietWindows; i++ {
+		quiet := false
+		for _, quietHours := range s.quietHours {
+			if end, ok := quietHours.end(t); ok {
+				t = end
+				quiet = true
+			}
+		}
+		if !quiet {
+			return t
+		}
+	}
+	return t
+}
@@ -0,0 +1,193 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	// Friday
+	now := time.Date(2024, 3, 1, 10, 2, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name          string
+		spec          string
+		expected      time.Time
+		expectedError bool
+	}{
+		{
+			name:     "Interval",
+			spec:     "15m",
+			expected: now.Add(15 * time.Minute),
+		},
+		{
+			name:     "Cron expression",
+			spec:     "*/5 8-18 * * mon-fri",
+			expected: time.Date(2024, 3, 1, 10, 5, 0, 0, time.UTC),
+		},
+		{
+			name:     "Cron expression skipping the weekend",
+			spec:     "0 9 * * mon-fri",
+			expected: time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "Descriptor",
+			spec:     "@hourly",
+			expected: time.Date(2024, 3, 1, 11, 0, 0, 0, time.UTC),
+		},
+		{
+			name:          "Negative interval",
+			spec:          "-5m",
+			expectedError: true,
+		},
+		{
+			name:          "Invalid expression",
+			spec:          "every five minutes",
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			schedule, err := Parse(tc.spec, time.UTC)
+			if tc.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, schedule.Next(now).UTC())
+		})
+	}
+}
+
+func TestParseQuietHours(t *testing.T) {
+	testCases := []struct {
+		name          string
+		days          []string
+		from          string
+		to            string
+		expected      QuietHours
+		expectedError bool
+	}{
+		{
+			name:     "Every night",
+			from:     "20:00",
+			to:       "08:30",
+			expected: QuietHours{From: 20 * 60, To: 8*60 + 30},
+		},
+		{
+			name:     "Weekend",
+			days:     []string{"Sat", "sunday"},
+			expected: QuietHours{Days: []time.Weekday{time.Saturday, time.Sunday}},
+		},
+		{
+			name:          "Unknown day",
+			days:          []string{"someday"},
+			expectedError: true,
+		},
+		{
+			name:          "Invalid time",
+			from:          "8pm",
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			quietHours, err := ParseQuietHours(tc.days, tc.from, tc.to)
+			if tc.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, quietHours)
+		})
+	}
+}
+
+func TestSchedulerQuietHours(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Rome")
+	require.NoError(t, err)
+	nights, err := ParseQuietHours(nil, "20:00", "08:00")
+	require.NoError(t, err)
+	weekends, err := ParseQuietHours([]string{"sat", "sun"}, "", "")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name         string
+		spec         string
+		ranAt        time.Time
+		expectedNext time.Time
+	}{
+		{
+			name:         "Interval outside quiet hours",
+			spec:         "1h",
+			ranAt:        time.Date(2024, 3, 5, 10, 0, 0, 0, loc),
+			expectedNext: time.Date(2024, 3, 5, 11, 0, 0, 0, loc),
+		},
+		{
+			name:         "Interval resumes when the night ends",
+			spec:         "1h",
+			ranAt:        time.Date(2024, 3, 5, 19, 30, 0, 0, loc),
+			expectedNext: time.Date(2024, 3, 6, 8, 0, 0, 0, loc),
+		},
+		{
+			name:         "Interval resumes after the weekend and the night",
+			spec:         "1h",
+			ranAt:        time.Date(2024, 3, 8, 19, 30, 0, 0, loc),
+			expectedNext: time.Date(2024, 3, 11, 8, 0, 0, 0, loc),
+		},
+		{
+			name:         "Cron expression skips quiet hours",
+			spec:         "15 * * * *",
+			ranAt:        time.Date(2024, 3, 8, 19, 15, 0, 0, loc),
+			expectedNext: time.Date(2024, 3, 11, 8, 15, 0, 0, loc),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			scheduler := NewScheduler()
+			jobs := []Job{{Name: "job", Spec: tc.spec}}
+			require.NoError(t, scheduler.Update(jobs, loc, []QuietHours{nights, weekends}, tc.ranAt))
+
+			scheduler.Ran([]string{"job"}, tc.ranAt)
+			assert.Equal(t, tc.expectedNext, scheduler.Next())
+		})
+	}
+	scheduler := NewScheduler()
+	require.NoError(t, scheduler.Update(nil, loc, []QuietHours{nights}, time.Now()))
+	assert.True(t, scheduler.Quiet(time.Date(2024, 3, 5, 21, 0, 0, 0, loc)))
+	assert.False(t, scheduler.Quiet(time.Date(2024, 3, 5, 10, 0, 0, 0, loc)))
+}
+
+func TestSchedulerUpdate(t *testing.T) {
+	now := time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)
+	scheduler := NewScheduler()
+	require.NoError(t, scheduler.Update([]Job{{Name: "jira/work", Spec: "5m"}, {Name: "projects", Spec: "1h"}},
+		time.UTC, nil, now))
+	assert.Equal(t, []string{"jira/work", "projects"}, scheduler.Due(now))
+
+	scheduler.Ran([]string{"jira/work", "projects"}, now)
+	assert.Empty(t, scheduler.Due(now))
+	assert.Equal(t, now.Add(5*time.Minute), scheduler.Next())
+
+	later := now.Add(10 * time.Minute)
+	assert.Equal(t, []string{"jira/work"}, scheduler.Due(later))
+
+	// unchanged jobs keep their next run, changed ones are rescheduled and new ones are due
+	require.NoError(t, scheduler.Update([]Job{
+		{Name: "jira/work", Spec: "5m"},
+		{Name: "jira/oss", Spec: "1h"},
+		{Name: "projects", Spec: "2h"},
+	}, time.UTC, nil, later))
+	assert.Equal(t, []string{"jira/oss", "jira/work"}, scheduler.Due(later))
+	assert.Equal(t, []string{"jira/oss", "jira/work", "projects"}, scheduler.All())
+	scheduler.Ran([]string{"jira/oss", "jira/work"}, later)
+	assert.Equal(t, later.Add(5*time.Minute), scheduler.Next())
+
+	assert.Error(t, scheduler.Update([]Job{{Name: "projects", Spec: "sometimes"}}, time.UTC, nil, later))
+}
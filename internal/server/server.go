@@ -0,0 +1,137 @@
+// Package server exposes the health and the metrics of the daemon over HTTP.
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/fabiocorneti/todoist-assistant/internal/config"
+	"github.com/fabiocorneti/todoist-assistant/internal/metrics"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	readHeaderTimeout = 5 * time.Second
+	shutdownTimeout   = 5 * time.Second
+)
+
+// Status tracks the runs of the daemon to tell whether it is ready.
+type Status struct {
+	mu          sync.Mutex
+	lastRun     time.Time
+	lastSuccess time.Time
+	interval    time.Duration
+}
+
+// NewStatus creates a Status without runs.
+func NewStatus() *Status {
+	return &Status{}
+}
+
+// RunStarted records the start of a run.
+func (s *Status) RunStarted(at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastRun = at
+}
+
+// RunFinished records the end of a run and whether it succeeded.
+func (s *Status) RunFinished(at time.Time, succeeded bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if succeeded {
+		s.lastSuccess = at
+	}
+}
+
+// Scheduled records when the next run is due; the interval between the start of the last run and
+// the next one is the unit of the readiness check, so that quiet hours do not make the daemon
+// unready.
+func (s *Status) Scheduled(next time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.lastRun.IsZero() && next.After(s.lastRun) {
+		s.interval = next.Sub(s.lastRun)
+	}
+}
+
+// Ready returns nil if a run succeeded within the given number of intervals before now, otherwise
+// an error describing why the daemon is not ready; fallback is the interval used before the first
+// run is scheduled.
+func (s *Status) Ready(now time.Time, intervals int, fallback time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lastSuccess.IsZero() {
+		return errors.New("no run has succeeded yet")
+	}
+	interval := s.interval
+	if interval <= 0 {
+		interval = fallback
+	}
+	if since := now.Sub(s.lastSuccess); since > time.Duration(intervals)*interval {
+		return fmt.Errorf("the last successful run ended %s ago, more than %d intervals of %s",
+			since.Round(time.Second), intervals, interval.Round(time.Second))
+	}
+	return nil
+}
+
+// Handler returns the handler serving /healthz, /readyz and /metrics; readiness is checked against
+// the current configuration of provider.
+func Handler(provider *config.Provider, status *Status) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, _ *http.Request) {
+		cfg := provider.Get()
+		err := status.Ready(time.Now(), cfg.Server.ReadyIntervals, time.Duration(cfg.UpdateInterval)*time.Minute)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+	mux.Handle("/metrics", metrics.Handler())
+	return mux
+}
+
+// Serve serves Handler on the configured address until ctx is done; it returns immediately if
+// the server is disabled. The address is read once, so changing it requires a restart.
+func Serve(ctx context.Context, provider *config.Provider, status *Status, logger *logrus.Logger) error {
+	address := provider.Get().Server.Address
+	if address == "" {
+		return nil
+	}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	server := &http.Server{
+		Handler:           Handler(provider, status),
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if shutdownErr := server.Shutdown(shutdownCtx); shutdownErr != nil {
+			logger.WithError(shutdownErr).Error("Error stopping the HTTP server")
+		}
+	}()
+
+	logger.Infof("Serving health checks and metrics on %s", listener.Addr())
+	err = server.Serve(listener)
+	if errors.Is(err, http.ErrServerClosed) {
+		<-done
+		return nil
+	}
+	return err
+}
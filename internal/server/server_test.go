@@ -0,0 +1,112 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fabiocorneti/todoist-assistant/internal/config"
+	"github.com/fabiocorneti/todoist-assistant/internal/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusReady(t *testing.T) {
+	start := time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name          string
+		succeeded     bool
+		next          time.Time
+		now           time.Time
+		expectedReady bool
+	}{
+		{
+			name: "No successful run",
+			now:  start.Add(time.Minute),
+		},
+		{
+			name:          "Successful run within the intervals",
+			succeeded:     true,
+			next:          start.Add(5 * time.Minute),
+			now:           start.Add(14 * time.Minute),
+			expectedReady: true,
+		},
+		{
+			name:      "Successful run older than the intervals",
+			succeeded: true,
+			next:      start.Add(5 * time.Minute),
+			now:       start.Add(17 * time.Minute),
+		},
+		{
+			name:          "Quiet hours lengthen the interval",
+			succeeded:     true,
+			next:          start.Add(12 * time.Hour),
+			now:           start.Add(11 * time.Hour),
+			expectedReady: true,
+		},
+		{
+			name:          "Fallback interval before the next run is scheduled",
+			succeeded:     true,
+			now:           start.Add(2 * time.Minute),
+			expectedReady: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			status := NewStatus()
+			status.RunStarted(start)
+			status.RunFinished(start.Add(time.Minute), tc.succeeded)
+			if !tc.next.IsZero() {
+				status.Scheduled(tc.next)
+			}
+
+			err := status.Ready(tc.now, 3, time.Minute)
+			if tc.expectedReady {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestHandler(t *testing.T) {
+	cfg := config.Config{UpdateInterval: 5, Server: config.ServerConfig{ReadyIntervals: 3}}
+	status := NewStatus()
+	server := httptest.NewServer(Handler(config.NewProvider(cfg), status))
+	defer server.Close()
+
+	get := func(path string) (int, string) {
+		resp, err := http.Get(server.URL + path) //nolint:noctx // test request
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(body)
+	}
+
+	code, _ := get("/healthz")
+	assert.Equal(t, http.StatusOK, code)
+
+	code, body := get("/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Contains(t, body, "no run has succeeded yet")
+
+	now := time.Now()
+	status.RunStarted(now)
+	status.RunFinished(now, true)
+	code, _ = get("/readyz")
+	assert.Equal(t, http.StatusOK, code)
+
+	metrics.ObserveRequest("todoist", "GET tasks/{id}", http.StatusNotFound, time.Millisecond)
+	metrics.CountJiraTask("https://example.atlassian.net", metrics.TaskCreated)
+	code, body = get("/metrics")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, `todoist_assistant_api_requests_total{api="todoist",endpoint="GET tasks/{id}",status="404"} 1`)
+	assert.Contains(t, body,
+		`todoist_assistant_jira_tasks_total{action="created",jira_site="https://example.atlassian.net"} 1`)
+}
@@ -0,0 +1,201 @@
+package state
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/fabiocorneti/todoist-assistant/internal/todoist"
+)
+
+const (
+	fileMode = 0o600
+	dirMode  = 0o700
+)
+
+// JiraIssue is the state recorded for a Jira issue linked to a Todoist task.
+type JiraIssue struct {
+	TaskID string `json:"taskId"`
+	Hash   string `json:"hash"`
+	// DueDate is the due date of the issue when it was last synced, or empty.
+	DueDate string `json:"dueDate,omitempty"`
+}
+
+type data struct {
+	Jira        map[string]map[string]JiraIssue `json:"jira"`
+	NextActions map[string]string               `json:"nextActions"`
+	TodoistSync todoist.SyncState               `json:"todoistSync"`
+}
+
+// Store is a file-backed store for state that must survive between runs.
+type Store struct {
+	path  string
+	mu    sync.Mutex
+	data  data
+	dirty bool
+}
+
+// Open loads the store at path; a missing file results in an empty store.
+func Open(path string) (*Store, error) {
+	store := &Store{
+		path: path,
+		data: data{
+			Jira:        make(map[string]map[string]JiraIssue),
+			NextActions: make(map[string]string),
+		},
+	}
+
+	file, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(file, &store.data); err != nil {
+		return nil, err
+	}
+	if store.data.Jira == nil {
+		store.data.Jira = make(map[string]map[string]JiraIssue)
+	}
+	if store.data.NextActions == nil {
+		store.data.NextActions = make(map[string]string)
+	}
+	return store, nil
+}
+
+// Save writes the store to disk if it has been modified since it was opened or last saved.
+func (s *Store) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.dirty {
+		return nil
+	}
+
+	content, err := json.MarshalIndent(s.data, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(s.path)
+	if err = os.MkdirAll(dir, dirMode); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Chmod(fileMode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp.Name(), s.path); err != nil {
+		return err
+	}
+
+	s.dirty = false
+	return nil
+}
+
+// JiraIssue returns the state of the issue with the given key on a Jira site.
+func (s *Store) JiraIssue(site, key string) (JiraIssue, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	issue, exists := s.data.Jira[site][key]
+	return issue, exists
+}
+
+// SetJiraIssue records the Todoist task linked to an issue and the hash of its last seen version.
+func (s *Store) SetJiraIssue(site, key string, issue JiraIssue) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.data.Jira[site] == nil {
+		s.data.Jira[site] = make(map[string]JiraIssue)
+	}
+	if s.data.Jira[site][key] == issue {
+		return
+	}
+	s.data.Jira[site][key] = issue
+	s.dirty = true
+}
+
+// RemoveJiraIssue forgets an issue.
+func (s *Store) RemoveJiraIssue(site, key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.data.Jira[site][key]; !exists {
+		return
+	}
+	delete(s.data.Jira[site], key)
+	s.dirty = true
+}
+
+// NextAction returns the ID of the task that was the next action of a project in the last run.
+func (s *Store) NextAction(projectID string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.data.NextActions[projectID]
+}
+
+// SetNextAction records the next action of a project; an empty task ID clears it.
+func (s *Store) SetNextAction(projectID, taskID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.data.NextActions[projectID] == taskID {
+		return
+	}
+	if taskID == "" {
+		delete(s.data.NextActions, projectID)
+	} else {
+		s.data.NextActions[projectID] = taskID
+	}
+	s.dirty = true
+}
+
+// TodoistSync returns the persisted Todoist Sync API state.
+func (s *Store) TodoistSync() todoist.SyncState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.data.TodoistSync
+}
+
+// SetTodoistSync persists the Todoist Sync API state.
+func (s *Store) SetTodoistSync(syncState todoist.SyncState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.TodoistSync = syncState
+	s.dirty = true
+}
+
+// Hash returns a stable hash of the JSON representation of the given values.
+func Hash(values ...any) (string, error) {
+	hash := sha256.New()
+	encoder := json.NewEncoder(hash)
+	for _, value := range values {
+		if err := encoder.Encode(value); err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,49 @@
+package state
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/fabiocorneti/todoist-assistant/internal/todoist"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStoreRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "state.json")
+
+	store, err := Open(path)
+	assert.NoError(t, err)
+
+	_, exists := store.JiraIssue("https://example.atlassian.net", "FOO-1")
+	assert.False(t, exists)
+
+	store.SetJiraIssue("https://example.atlassian.net", "FOO-1", JiraIssue{TaskID: "1", Hash: "abc"})
+	store.SetJiraIssue("https://example.atlassian.net", "FOO-2", JiraIssue{TaskID: "2", Hash: "def"})
+	store.RemoveJiraIssue("https://example.atlassian.net", "FOO-2")
+	store.SetNextAction("10", "1")
+	store.SetTodoistSync(todoist.SyncState{Token: "token"})
+	assert.NoError(t, store.Save())
+
+	reopened, err := Open(path)
+	assert.NoError(t, err)
+
+	issue, exists := reopened.JiraIssue("https://example.atlassian.net", "FOO-1")
+	assert.True(t, exists)
+	assert.Equal(t, JiraIssue{TaskID: "1", Hash: "abc"}, issue)
+	_, exists = reopened.JiraIssue("https://example.atlassian.net", "FOO-2")
+	assert.False(t, exists)
+	assert.Equal(t, "1", reopened.NextAction("10"))
+	assert.Equal(t, "token", reopened.TodoistSync().Token)
+}
+
+func TestHash(t *testing.T) {
+	first, err := Hash("FOO-1", []string{"a"})
+	assert.NoError(t, err)
+	second, err := Hash("FOO-1", []string{"a"})
+	assert.NoError(t, err)
+	third, err := Hash("FOO-1", []string{"b"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, first, second)
+	assert.NotEqual(t, first, third)
+}
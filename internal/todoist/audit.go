@@ -0,0 +1,104 @@
+package todoist
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+const maxAuditLineSize = 1 << 20
+
+// TaskState is the state of a task before or after a write.
+type TaskState struct {
+	Labels []string `json:"labels"`
+	// Priority is 0 if the priority of the task was not known.
+	Priority  int  `json:"priority,omitempty"`
+	Completed bool `json:"completed"`
+	// Content is only set for content updates.
+	Content string `json:"content,omitempty"`
+	// DueDate is only set for due date updates, with the time of tasks due at a given time, and is
+	// empty if the task has no due date.
+	DueDate string `json:"dueDate,omitempty"`
+	// Description is only set for description updates.
+	Description string `json:"description,omitempty"`
+}
+
+// AuditEntry records a write to a task; Before is not set for created tasks.
+type AuditEntry struct {
+	Time      time.Time  `json:"time"`
+	RunID     string     `json:"runId"`
+	Action    string     `json:"action"`
+	TaskID    string     `json:"taskId"`
+	Content   string     `json:"content,omitempty"`
+	ProjectID string     `json:"projectId,omitempty"`
+	Before    *TaskState `json:"before,omitempty"`
+	After     *TaskState `json:"after,omitempty"`
+}
+
+// AuditLog appends the writes made during a run to a JSONL file, one entry per line.
+type AuditLog struct {
+	mu    sync.Mutex
+	path  string
+	runID string
+	now   func() time.Time
+}
+
+// NewAuditLog creates an AuditLog for the run with the given ID; the file at path is created on
+// the first write.
+func NewAuditLog(path, runID string) *AuditLog {
+	return &AuditLog{path: path, runID: runID, now: time.Now}
+}
+
+func (a *AuditLog) write(entry AuditEntry) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	entry.Time = a.now().UTC()
+	entry.RunID = a.runID
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(a.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("error opening audit log: %w", err)
+	}
+	if _, err = file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return fmt.Errorf("error writing audit log: %w", err)
+	}
+	return file.Close()
+}
+
+// ReadAuditLog returns the entries recorded for the run with the given ID in the audit log at
+// path, in the order they were written.
+func ReadAuditLog(path, runID string) ([]AuditEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening audit log: %w", err)
+	}
+	defer file.Close()
+
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, maxAuditLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry AuditEntry
+		if err = json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid audit entry: %w", path, line, err)
+		}
+		if entry.RunID == runID {
+			entries = append(entries, entry)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading audit log: %w", err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,182 @@
+package todoist
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fabiocorneti/todoist-assistant/internal/httpclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAudit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	now := time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)
+	mockTransport := MockTransport{}
+	client := Client{transport: &mockTransport}
+	auditLog := NewAuditLog(path, "run-1")
+	auditLog.now = func() time.Time { return now }
+	client.EnableAudit(auditLog)
+	ctx := context.Background()
+
+	priority := 1
+	newPriority := 1
+	mockTransport.On("getAllTasks", mock.Anything).Return([]Task{
+		{ID: "1", Content: "Existing", Labels: []string{"Work"}, Priority: &priority, Due: &Due{Date: "2024-03-05T10:00:00"}},
+	}, nil)
+	mockTransport.On("createTask", mock.Anything, "New task", "").
+		Return(&Task{ID: "2", Content: "New task", Labels: []string{}, Priority: &newPriority}, nil)
+	mockTransport.On("updateTaskLabels", mock.Anything, "1", []string{"Jira"}).Return(nil)
+	mockTransport.On("setTaskPriority", mock.Anything, "1", 4).Return(nil)
+	mockTransport.On("updateTaskContent", mock.Anything, "1", "Renamed").Return(nil)
+	mockTransport.On("setTaskDueDate", mock.Anything, "1", "2024-03-08").Return(nil)
+	mockTransport.On("updateTaskDescription", mock.Anything, "1", "Story points: 5").Return(nil)
+	mockTransport.On("completeTask", mock.Anything, "1").Return(nil)
+	mockTransport.On("lastWrite").Return("")
+
+	_, err := client.GetAllTasks(ctx)
+	require.NoError(t, err)
+	_, err = client.CreateTask(ctx, "New task", "")
+	require.NoError(t, err)
+	require.NoError(t, client.ReplaceTaskLabels(ctx, "1", []string{"Jira"}))
+	require.NoError(t, client.SetTaskPriority(ctx, "1", 4))
+	require.NoError(t, client.UpdateTaskContent(ctx, "1", "Renamed"))
+	require.NoError(t, client.SetTaskDueDate(ctx, "1", "2024-03-08"))
+	require.NoError(t, client.UpdateTaskDescription(ctx, "1", "Story points: 5"))
+	require.NoError(t, client.CompleteTask(ctx, "1"))
+	mockTransport.AssertExpectations(t)
+
+	// entries of other runs are ignored
+	require.NoError(t, NewAuditLog(path, "run-2").write(AuditEntry{Action: ActionDeleteTask, TaskID: "2"}))
+
+	entries, err := ReadAuditLog(path, "run-1")
+	require.NoError(t, err)
+	entry := func(action, taskID, content string, before, after *TaskState) AuditEntry {
+		return AuditEntry{Time: now, RunID: "run-1", Action: action, TaskID: taskID, Content: content,
+			Before: before, After: after}
+	}
+	assert.Equal(t, []AuditEntry{
+		entry(ActionCreateTask, "2", "New task", nil, &TaskState{Labels: []string{}, Priority: 1}),
+		entry(ActionReplaceLabels, "1", "Existing",
+			&TaskState{Labels: []string{"Work"}, Priority: 1}, &TaskState{Labels: []string{"Jira"}, Priority: 1}),
+		entry(ActionSetPriority, "1", "Existing",
+			&TaskState{Labels: []string{"Jira"}, Priority: 1}, &TaskState{Labels: []string{"Jira"}, Priority: 4}),
+		entry(ActionUpdateContent, "1", "Renamed",
+			&TaskState{Labels: []string{"Jira"}, Priority: 4, Content: "Existing"},
+			&TaskState{Labels: []string{"Jira"}, Priority: 4, Content: "Renamed"}),
+		entry(ActionSetDueDate, "1", "Renamed",
+			&TaskState{Labels: []string{"Jira"}, Priority: 4, DueDate: "2024-03-05T10:00:00"},
+			&TaskState{Labels: []string{"Jira"}, Priority: 4, DueDate: "2024-03-08"}),
+		entry(ActionUpdateDescription, "1", "Renamed",
+			&TaskState{Labels: []string{"Jira"}, Priority: 4},
+			&TaskState{Labels: []string{"Jira"}, Priority: 4, Description: "Story points: 5"}),
+		entry(ActionCompleteTask, "1", "Renamed",
+			&TaskState{Labels: []string{"Jira"}, Priority: 4},
+			&TaskState{Labels: []string{"Jira"}, Priority: 4, Completed: true}),
+	}, entries)
+
+	require.NoError(t, os.WriteFile(path, []byte("{\"runId\":\"run-1\"}\nnot json\n"), 0o600))
+	_, err = ReadAuditLog(path, "run-1")
+	assert.ErrorContains(t, err, "audit.jsonl:2: invalid audit entry")
+}
+
+func TestAuditSyncTransport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	ctx := context.Background()
+	fail := true
+	// the update of task 404 is rejected, and the first batch cannot be sent
+	transport := newTestSyncTransport(t, func(request syncRequest) (int, syncResponse) {
+		if len(request.commands) > 0 && fail {
+			fail = false
+			return http.StatusServiceUnavailable, syncResponse{}
+		}
+		response := syncResponse{SyncToken: "token", SyncStatus: okStatus(request.commands)}
+		for _, command := range request.commands {
+			switch {
+			case command.Type == "item_add":
+				response.TempIDMapping = map[string]string{command.TempID: "2"}
+			case command.Args["id"] == "404":
+				response.SyncStatus[command.UUID] = json.RawMessage(`{"error": "Item not found", "http_code": 404}`)
+			}
+		}
+		return http.StatusOK, response
+	})
+	client := Client{transport: transport}
+	client.EnableAudit(NewAuditLog(path, "run-1"))
+
+	created, err := client.CreateTask(ctx, "New task", "")
+	require.NoError(t, err)
+	require.NoError(t, client.SetTaskPriority(ctx, "404", 4))
+
+	assert.Error(t, client.Flush(ctx))
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err), "writes that were not sent are not audited")
+
+	err = client.Flush(ctx)
+	assert.True(t, httpclient.IsNotFound(err), err)
+	entries, err := ReadAuditLog(path, "run-1")
+	require.NoError(t, err)
+	require.Len(t, entries, 1, "rejected writes are not audited")
+	assert.Equal(t, ActionCreateTask, entries[0].Action)
+	assert.Equal(t, "2", entries[0].TaskID)
+	assert.NotEqual(t, created.ID, entries[0].TaskID)
+	assert.Empty(t, client.pendingAudit)
+}
+
+func TestAuditBufferedWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	ctx := context.Background()
+	mockTransport := MockTransport{}
+	client := Client{transport: &mockTransport}
+	client.EnableAudit(NewAuditLog(path, "run-1"))
+	mockTransport.On("createTask", mock.Anything, "New task", "").Return(&Task{ID: "temp-1"}, nil)
+	mockTransport.On("completeTask", mock.Anything, "1").Return(nil)
+	mockTransport.On("deleteTask", mock.Anything, "2").Return(nil)
+	mockTransport.On("lastWrite").Return("write-1").Once()
+	mockTransport.On("lastWrite").Return("write-2").Once()
+	mockTransport.On("lastWrite").Return("write-3").Once()
+	mockTransport.On("flush", mock.Anything).Return(nil)
+	mockTransport.On("writeStatus", "write-1").Return(writeApplied)
+	mockTransport.On("writeStatus", "write-2").Return(writeQueued)
+	mockTransport.On("writeStatus", "write-3").Return(writeRejected)
+	mockTransport.On("resolveID", "temp-1").Return("1")
+
+	_, err := client.CreateTask(ctx, "New task", "")
+	require.NoError(t, err)
+	require.NoError(t, client.CompleteTask(ctx, "1"))
+	require.NoError(t, client.DeleteTask(ctx, "2"))
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err), "buffered writes are audited when flushed")
+
+	require.NoError(t, client.Flush(ctx))
+	entries, err := ReadAuditLog(path, "run-1")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, ActionCreateTask, entries[0].Action)
+	assert.Equal(t, "1", entries[0].TaskID)
+	require.Len(t, client.pendingAudit, 1, "queued writes are audited by a later flush")
+	assert.Equal(t, "write-2", client.pendingAudit[0].writeID)
+	mockTransport.AssertExpectations(t)
+}
+
+func TestAuditFailure(t *testing.T) {
+	mockTransport := MockTransport{}
+	client := Client{transport: &mockTransport}
+	client.EnableAudit(NewAuditLog(filepath.Join(t.TempDir(), "missing", "audit.jsonl"), "run-1"))
+	mockTransport.On("setTaskPriority", mock.Anything, "1", 4).Return(nil)
+	mockTransport.On("completeTask", mock.Anything, "1").Return(nil)
+	mockTransport.On("lastWrite").Return("")
+
+	assert.NoError(t, client.AuditError())
+	assert.NoError(t, client.SetTaskPriority(context.Background(), "1", 4), "the write was made")
+	assert.ErrorContains(t, client.AuditError(), "task 1 was changed but the change was not audited")
+	assert.NoError(t, client.CompleteTask(context.Background(), "1"))
+	assert.ErrorContains(t, client.AuditError(), "2 changes were not audited")
+	mockTransport.AssertExpectations(t)
+}
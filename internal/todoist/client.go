@@ -1,21 +1,92 @@
 package todoist
 
 import (
+	"context"
 	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fabiocorneti/todoist-assistant/internal/httpclient"
+)
+
+const (
+	TransportREST = "rest"
+	TransportSync = "sync"
+
+	// DefaultAPIURL is the base URL of the Todoist REST and Sync APIs.
+	DefaultAPIURL = "https://api.todoist.com/"
+
+	maxRequests = 450
+	interval    = 15 * time.Minute
 )
 
 type Client struct {
-	transport Transport
+	httpClient   *httpclient.RateLimitedClient
+	transport    Transport
+	plan         *Plan
+	tasks        map[string]Task
+	auditLog     *AuditLog
+	pendingAudit []pendingAuditEntry
+	// auditFailures are the errors of the writes that could not be recorded in the audit log.
+	auditFailures []error
 }
 
-func NewTodoistClient(token string, testMode bool) *Client {
-	return &Client{
-		transport: NewRESTTodoistTransport(token, testMode),
+// pendingAuditEntry is the audit entry of a write buffered by the transport with the given ID.
+type pendingAuditEntry struct {
+	writeID string
+	entry   AuditEntry
+}
+
+// NewTodoistClient creates a client using the given transport type (TransportREST or TransportSync);
+// apiURL is the base URL of the Todoist APIs, DefaultAPIURL if empty.
+func NewTodoistClient(apiURL, token, transportType string, retry httpclient.RetryPolicy,
+	requestTimeout time.Duration, testMode bool) (*Client, error) {
+	if apiURL == "" {
+		apiURL = DefaultAPIURL
+	}
+	apiURL = strings.TrimSuffix(apiURL, "/") + "/"
+
+	limit := httpclient.Limit{Requests: maxRequests, Interval: interval}
+	httpClient := httpclient.NewRateLimitedClient("todoist", limit, retry, requestTimeout)
+
+	var transport Transport
+	switch transportType {
+	case TransportREST, "":
+		transport = NewRESTTodoistTransport(httpClient, apiURL+restPath, token, testMode)
+	case TransportSync:
+		transport = NewSyncTodoistTransport(httpClient, apiURL+syncPath, token, testMode)
+	default:
+		return nil, fmt.Errorf("unknown Todoist transport %s", transportType)
 	}
+	return &Client{
+		httpClient: httpClient,
+		transport:  transport,
+	}, nil
+}
+
+// UseCassette makes the client record its requests or replay recorded responses, see
+// httpclient.Cassette.
+func (tc *Client) UseCassette(cassette httpclient.Cassette) {
+	tc.httpClient.UseCassette(cassette)
 }
 
-func (tc *Client) GetProjects() ([]Project, error) {
-	return tc.transport.getProjects()
+// EnableDryRun makes the client record mutations in a plan instead of sending them.
+func (tc *Client) EnableDryRun() {
+	tc.plan = &Plan{}
+}
+
+// Plan returns the changes recorded in dry-run mode, or nil if dry-run is not enabled.
+func (tc *Client) Plan() *Plan {
+	return tc.plan
+}
+
+func (tc *Client) GetProjects(ctx context.Context) ([]Project, error) {
+	return tc.transport.getProjects(ctx)
+}
+
+// GetCompletedTasks returns the tasks completed since the given time.
+func (tc *Client) GetCompletedTasks(ctx context.Context, since time.Time) ([]CompletedTask, error) {
+	return tc.transport.getCompletedTasks(ctx, since)
 }
 
 func (tc *Client) FindProjectID(projects []Project, name string) (string, error) {
@@ -34,49 +105,235 @@ func (tc *Client) FindProjectID(projects []Project, name string) (string, error)
 	return id, nil
 }
 
-func (tc *Client) GetAllTasks() ([]Task, error) {
-	return tc.transport.getAllTasks()
+func (tc *Client) GetAllTasks(ctx context.Context) ([]Task, error) {
+	tasks, err := tc.transport.getAllTasks(ctx)
+	tc.rememberTasks(tasks)
+	return tasks, err
+}
+
+func (tc *Client) GetTasksForProject(ctx context.Context, projectID string) ([]Task, error) {
+	tasks, err := tc.transport.getTasksForProject(ctx, projectID)
+	tc.rememberTasks(tasks)
+	return tasks, err
+}
+
+func (tc *Client) CreateTask(ctx context.Context, content, projectID string) (*Task, error) {
+	if tc.plan != nil {
+		priority := 1
+		task := Task{
+			ID:        fmt.Sprintf("%s%d", dryRunIDPrefix, len(tc.plan.Changes)+1),
+			Labels:    []string{},
+			ProjectID: projectID,
+			Content:   content,
+			Priority:  &priority,
+		}
+		tc.remember(task)
+		tc.plan.record(Change{Action: ActionCreateTask, TaskID: task.ID, Content: content, ProjectID: projectID})
+		return &task, nil
+	}
+	task, err := tc.transport.createTask(ctx, content, projectID)
+	if err != nil {
+		return nil, err
+	}
+	tc.remember(*task)
+	after := stateOf(*task)
+	tc.audit(AuditEntry{
+		Action: ActionCreateTask, TaskID: task.ID, Content: content, ProjectID: projectID, After: &after,
+	})
+	return task, nil
+}
+
+func (tc *Client) CompleteTask(ctx context.Context, taskID string) error {
+	if tc.plan != nil {
+		tc.plan.record(Change{Action: ActionCompleteTask, TaskID: taskID, Content: tc.tasks[taskID].Content})
+		return nil
+	}
+	if err := tc.transport.completeTask(ctx, taskID); err != nil {
+		return err
+	}
+	before := stateOf(tc.tasks[taskID])
+	after := before
+	after.Completed = true
+	tc.auditChange(ActionCompleteTask, taskID, before, after)
+	return nil
 }
 
-func (tc *Client) GetTasksForProject(projectID string) ([]Task, error) {
-	return tc.transport.getTasksForProject(projectID)
+// ReopenTask reopens a completed task.
+func (tc *Client) ReopenTask(ctx context.Context, taskID string) error {
+	if tc.plan != nil {
+		tc.plan.record(Change{Action: ActionReopenTask, TaskID: taskID, Content: tc.tasks[taskID].Content})
+		return nil
+	}
+	if err := tc.transport.reopenTask(ctx, taskID); err != nil {
+		return err
+	}
+	after := stateOf(tc.tasks[taskID])
+	before := after
+	before.Completed = true
+	tc.auditChange(ActionReopenTask, taskID, before, after)
+	return nil
 }
 
-func (tc *Client) CreateTask(content, projectID string) (*Task, error) {
-	return tc.transport.createTask(content, projectID)
+// DeleteTask deletes a task.
+func (tc *Client) DeleteTask(ctx context.Context, taskID string) error {
+	if tc.plan != nil {
+		tc.plan.record(Change{Action: ActionDeleteTask, TaskID: taskID, Content: tc.tasks[taskID].Content})
+		return nil
+	}
+	if err := tc.transport.deleteTask(ctx, taskID); err != nil {
+		return err
+	}
+	before := stateOf(tc.tasks[taskID])
+	entry := AuditEntry{Action: ActionDeleteTask, TaskID: taskID, Content: tc.tasks[taskID].Content, Before: &before}
+	delete(tc.tasks, taskID)
+	tc.audit(entry)
+	return nil
 }
 
-func (tc *Client) CompleteTask(taskID string) error {
-	return tc.transport.completeTask(taskID)
+func (tc *Client) ReplaceTaskLabels(ctx context.Context, taskID string, labels []string) error {
+	if tc.plan != nil {
+		tc.plan.record(Change{Action: ActionReplaceLabels, TaskID: taskID, Content: tc.tasks[taskID].Content,
+			Labels: labels})
+		return nil
+	}
+
+	var before TaskState
+	if tc.auditLog != nil {
+		currentLabels, err := tc.currentLabels(ctx, taskID)
+		if err != nil {
+			return err
+		}
+		before = stateOf(tc.tasks[taskID])
+		before.Labels = copyLabels(currentLabels)
+	}
+	return tc.updateLabels(ctx, ActionReplaceLabels, taskID, before, labels)
 }
 
-func (tc *Client) ReplaceTaskLabels(taskID string, labels []string) error {
-	return tc.transport.updateTaskLabels(taskID, labels)
+func (tc *Client) SetTaskPriority(ctx context.Context, taskID string, priority int) error {
+	if tc.plan != nil {
+		tc.plan.record(Change{Action: ActionSetPriority, TaskID: taskID, Content: tc.tasks[taskID].Content,
+			Priority: priority})
+		return nil
+	}
+	if err := tc.transport.setTaskPriority(ctx, taskID, priority); err != nil {
+		return err
+	}
+	before := stateOf(tc.tasks[taskID])
+	after := before
+	after.Priority = priority
+	if task, known := tc.tasks[taskID]; known {
+		task.Priority = &priority
+		tc.tasks[taskID] = task
+	}
+	tc.auditChange(ActionSetPriority, taskID, before, after)
+	return nil
 }
 
-func (tc *Client) SetTaskPriority(taskID string, priority int) error {
-	return tc.transport.setTaskPriority(taskID, priority)
+// UpdateTaskContent replaces the content of a task.
+func (tc *Client) UpdateTaskContent(ctx context.Context, taskID, content string) error {
+	if tc.plan != nil {
+		tc.plan.record(Change{Action: ActionUpdateContent, TaskID: taskID, Content: tc.tasks[taskID].Content,
+			NewContent: content})
+		return nil
+	}
+	if err := tc.transport.updateTaskContent(ctx, taskID, content); err != nil {
+		return err
+	}
+	before := stateOf(tc.tasks[taskID])
+	before.Content = tc.tasks[taskID].Content
+	after := before
+	after.Content = content
+	if task, known := tc.tasks[taskID]; known {
+		task.Content = content
+		tc.tasks[taskID] = task
+	}
+	tc.auditChange(ActionUpdateContent, taskID, before, after)
+	return nil
 }
 
-func (tc *Client) AddLabelsToTask(taskID string, labels []string) error {
-	taskLabels, err := tc.transport.getTaskLabels(taskID)
+// UpdateTaskDescription replaces the description of a task.
+func (tc *Client) UpdateTaskDescription(ctx context.Context, taskID, description string) error {
+	if tc.plan != nil {
+		tc.plan.record(Change{Action: ActionUpdateDescription, TaskID: taskID, Content: tc.tasks[taskID].Content,
+			Description: description})
+		return nil
+	}
+	if err := tc.transport.updateTaskDescription(ctx, taskID, description); err != nil {
+		return err
+	}
+	before := stateOf(tc.tasks[taskID])
+	before.Description = tc.tasks[taskID].Description
+	after := before
+	after.Description = description
+	if task, known := tc.tasks[taskID]; known {
+		task.Description = description
+		tc.tasks[taskID] = task
+	}
+	tc.auditChange(ActionUpdateDescription, taskID, before, after)
+	return nil
+}
+
+// SetTaskDueDate sets the due date of a task to a date such as 2024-03-05, or to a date and time
+// such as 2024-03-05T10:00:00, or removes it if date is empty.
+func (tc *Client) SetTaskDueDate(ctx context.Context, taskID, date string) error {
+	if tc.plan != nil {
+		tc.plan.record(Change{Action: ActionSetDueDate, TaskID: taskID, Content: tc.tasks[taskID].Content,
+			DueDate: date})
+		return nil
+	}
+	if err := tc.transport.setTaskDueDate(ctx, taskID, date); err != nil {
+		return err
+	}
+	before := stateOf(tc.tasks[taskID])
+	before.DueDate = tc.tasks[taskID].Due.Value()
+	after := before
+	after.DueDate = date
+	if task, known := tc.tasks[taskID]; known {
+		task.Due = nil
+		if date != "" {
+			task.Due = &Due{Date: date}
+		}
+		tc.tasks[taskID] = task
+	}
+	tc.auditChange(ActionSetDueDate, taskID, before, after)
+	return nil
+}
+
+func (tc *Client) AddLabelsToTask(ctx context.Context, taskID string, labels []string) error {
+	if tc.plan != nil {
+		tc.plan.record(Change{Action: ActionAddLabels, TaskID: taskID, Content: tc.tasks[taskID].Content,
+			Labels: labels})
+		return nil
+	}
+
+	taskLabels, err := tc.transport.getTaskLabels(ctx, taskID)
 	if err != nil {
 		return err
 	}
+	before := stateOf(tc.tasks[taskID])
+	before.Labels = copyLabels(taskLabels)
 
 	for _, label := range labels {
 		if !Contains(taskLabels, label) {
 			taskLabels = append(taskLabels, label)
 		}
 	}
-	return tc.transport.updateTaskLabels(taskID, taskLabels)
+	return tc.updateLabels(ctx, ActionAddLabels, taskID, before, taskLabels)
 }
 
-func (tc *Client) RemoveLabelsFromTask(taskID string, labels []string) error {
-	currentLabels, err := tc.transport.getTaskLabels(taskID)
+func (tc *Client) RemoveLabelsFromTask(ctx context.Context, taskID string, labels []string) error {
+	if tc.plan != nil {
+		tc.plan.record(Change{Action: ActionRemoveLabels, TaskID: taskID, Content: tc.tasks[taskID].Content,
+			Labels: labels})
+		return nil
+	}
+
+	currentLabels, err := tc.transport.getTaskLabels(ctx, taskID)
 	if err != nil {
 		return err
 	}
+	before := stateOf(tc.tasks[taskID])
+	before.Labels = copyLabels(currentLabels)
 
 	var newLabels []string
 	for _, label := range currentLabels {
@@ -85,7 +342,141 @@ func (tc *Client) RemoveLabelsFromTask(taskID string, labels []string) error {
 		}
 	}
 
-	return tc.transport.updateTaskLabels(taskID, newLabels)
+	return tc.updateLabels(ctx, ActionRemoveLabels, taskID, before, newLabels)
+}
+
+// EnableAudit makes the client record every write in log.
+func (tc *Client) EnableAudit(log *AuditLog) {
+	tc.auditLog = log
+}
+
+// Flush sends any pending changes buffered by the transport. With the Sync API transport, the
+// writes applied by Todoist are recorded in the audit log at this point, once the IDs of created
+// tasks are known; writes rejected by Todoist are not recorded, and writes that could not be sent
+// are recorded by the flush that sends them.
+func (tc *Client) Flush(ctx context.Context) error {
+	err := tc.transport.flush(ctx)
+	pending := tc.pendingAudit
+	tc.pendingAudit = nil
+	for _, p := range pending {
+		switch tc.transport.writeStatus(p.writeID) {
+		case writeApplied:
+			p.entry.TaskID = tc.transport.resolveID(p.entry.TaskID)
+			tc.writeAudit(p.entry)
+		case writeQueued:
+			tc.pendingAudit = append(tc.pendingAudit, p)
+		}
+	}
+	return err
+}
+
+// SyncedTaskID returns the ID of a task once all the changes made to it by the client have been
+// applied by Todoist, resolving the temporary ID of a task created through the Sync API; ok is false
+// if some changes were rejected or are still pending. Other transports apply changes immediately.
+func (tc *Client) SyncedTaskID(taskID string) (id string, ok bool) {
+	return tc.transport.syncedID(taskID)
+}
+
+// SyncState returns a snapshot of the Sync API cache; ok is false for other transports.
+func (tc *Client) SyncState() (SyncState, bool) {
+	return tc.transport.exportState()
+}
+
+// RestoreSyncState loads a snapshot returned by SyncState; it is ignored by other transports.
+func (tc *Client) RestoreSyncState(state SyncState) {
+	tc.transport.restoreState(state)
+}
+
+// remember keeps the last known state of a task, which is used to describe changes in dry-run
+// mode and to record the state before a write in the audit log.
+func (tc *Client) remember(task Task) {
+	if tc.tasks == nil {
+		tc.tasks = make(map[string]Task)
+	}
+	tc.tasks[task.ID] = task
+}
+
+func (tc *Client) rememberTasks(tasks []Task) {
+	for _, task := range tasks {
+		tc.remember(task)
+	}
+}
+
+// currentLabels returns the labels of a task, fetching them if the task is not known.
+func (tc *Client) currentLabels(ctx context.Context, taskID string) ([]string, error) {
+	if task, known := tc.tasks[taskID]; known {
+		return copyLabels(task.Labels), nil
+	}
+	return tc.transport.getTaskLabels(ctx, taskID)
+}
+
+// updateLabels sets the labels of a task and audits the change from before.
+func (tc *Client) updateLabels(ctx context.Context, action, taskID string, before TaskState, labels []string) error {
+	if err := tc.transport.updateTaskLabels(ctx, taskID, labels); err != nil {
+		return err
+	}
+	if task, known := tc.tasks[taskID]; known {
+		task.Labels = copyLabels(labels)
+		tc.tasks[taskID] = task
+	}
+	after := before
+	after.Labels = copyLabels(labels)
+	tc.auditChange(action, taskID, before, after)
+	return nil
+}
+
+func (tc *Client) auditChange(action, taskID string, before, after TaskState) {
+	tc.audit(AuditEntry{
+		Action: action, TaskID: taskID, Content: tc.tasks[taskID].Content, Before: &before, After: &after,
+	})
+}
+
+// audit records a write in the audit log, if enabled; writes buffered by the transport, such as
+// the commands queued by the Sync API transport, are recorded when the client is flushed.
+func (tc *Client) audit(entry AuditEntry) {
+	if tc.auditLog == nil {
+		return
+	}
+	if writeID := tc.transport.lastWrite(); writeID != "" {
+		tc.pendingAudit = append(tc.pendingAudit, pendingAuditEntry{writeID: writeID, entry: entry})
+		return
+	}
+	tc.writeAudit(entry)
+}
+
+// writeAudit writes an entry to the audit log; the write it records was made, so a failure does not
+// fail it and is returned by AuditError instead.
+func (tc *Client) writeAudit(entry AuditEntry) {
+	if err := tc.auditLog.write(entry); err != nil {
+		tc.auditFailures = append(tc.auditFailures,
+			fmt.Errorf("task %s was changed but the change was not audited: %w", entry.TaskID, err))
+	}
+}
+
+// AuditError returns an error if some writes could not be recorded in the audit log, or nil.
+func (tc *Client) AuditError() error {
+	switch len(tc.auditFailures) {
+	case 0:
+		return nil
+	case 1:
+		return tc.auditFailures[0]
+	default:
+		return fmt.Errorf("%d changes were not audited: %w", len(tc.auditFailures), tc.auditFailures[0])
+	}
+}
+
+// stateOf returns the state of an active task; the priority is 0 if it is not known.
+func stateOf(task Task) TaskState {
+	state := TaskState{Labels: copyLabels(task.Labels)}
+	if task.Priority != nil {
+		state.Priority = *task.Priority
+	}
+	return state
+}
+
+// copyLabels returns a copy of labels that is never nil, so that no labels are recorded as [].
+func copyLabels(labels []string) []string {
+	return append([]string{}, labels...)
 }
 
 func Contains(slice []string, str string) bool {
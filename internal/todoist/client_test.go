@@ -1,10 +1,13 @@
 package todoist
 
 import (
+	"context"
 	"errors"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestFindProjectId(t *testing.T) {
@@ -71,3 +74,75 @@ func TestFindProjectId(t *testing.T) {
 		})
 	}
 }
+
+func TestDryRun(t *testing.T) {
+	mockTransport := MockTransport{}
+	client := Client{
+		transport: &mockTransport,
+	}
+	client.EnableDryRun()
+	ctx := context.Background()
+
+	priority := 1
+	mockTransport.On("getAllTasks", mock.Anything).Return([]Task{{ID: "1", Content: "Existing", Priority: &priority}}, nil)
+
+	_, err := client.GetAllTasks(ctx)
+	assert.NoError(t, err)
+	task, err := client.CreateTask(ctx, "New task", "")
+	assert.NoError(t, err)
+	assert.NoError(t, client.SetTaskPriority(ctx, task.ID, 4))
+	assert.NoError(t, client.SetTaskDueDate(ctx, task.ID, "2024-03-08"))
+	assert.NoError(t, client.AddLabelsToTask(ctx, "1", []string{"Next Action"}))
+	assert.NoError(t, client.UpdateTaskContent(ctx, "1", "Renamed"))
+	assert.NoError(t, client.SetTaskDueDate(ctx, "1", ""))
+	assert.NoError(t, client.UpdateTaskDescription(ctx, "1", "Story points: 5"))
+	assert.NoError(t, client.CompleteTask(ctx, "1"))
+
+	mockTransport.AssertExpectations(t)
+
+	assert.Equal(t, []Change{
+		{Action: ActionCreateTask, TaskID: task.ID, Content: "New task"},
+		{Action: ActionSetPriority, TaskID: task.ID, Content: "New task", Priority: 4},
+		{Action: ActionSetDueDate, TaskID: task.ID, Content: "New task", DueDate: "2024-03-08"},
+		{Action: ActionAddLabels, TaskID: "1", Content: "Existing", Labels: []string{"Next Action"}},
+		{Action: ActionUpdateContent, TaskID: "1", Content: "Existing", NewContent: "Renamed"},
+		{Action: ActionSetDueDate, TaskID: "1", Content: "Existing"},
+		{Action: ActionUpdateDescription, TaskID: "1", Content: "Existing", Description: "Story points: 5"},
+		{Action: ActionCompleteTask, TaskID: "1", Content: "Existing"},
+	}, client.Plan().Changes)
+
+	var output strings.Builder
+	assert.NoError(t, client.Plan().Write(&output, PlanFormatText))
+	assert.Equal(t, `8 changes to Todoist tasks:
+  - create task "New task" in the inbox
+  - set priority of task "New task" (dry-run-1) to 4
+  - set due date of task "New task" (dry-run-1) to 2024-03-08
+  - add labels [Next Action] to task "Existing" (1)
+  - change content of task "Existing" (1) to "Renamed"
+  - remove due date of task "Existing" (1)
+  - change description of task "Existing" (1) to "Story points: 5"
+  - complete task "Existing" (1)
+`, output.String())
+}
+
+func TestSyncState(t *testing.T) {
+	mockTransport := MockTransport{}
+	client := Client{transport: &mockTransport}
+	state := SyncState{Token: "token", Tasks: []Task{{ID: "1"}}}
+	mockTransport.On("exportState").Return(state, true)
+	mockTransport.On("restoreState", state).Return()
+	mockTransport.On("syncedID", "temp-1").Return("1", true)
+	mockTransport.On("syncedID", "2").Return("", false)
+
+	exported, ok := client.SyncState()
+	assert.True(t, ok)
+	assert.Equal(t, state, exported)
+	client.RestoreSyncState(state)
+
+	id, ok := client.SyncedTaskID("temp-1")
+	assert.True(t, ok)
+	assert.Equal(t, "1", id)
+	_, ok = client.SyncedTaskID("2")
+	assert.False(t, ok, "the changes to the task are pending")
+	mockTransport.AssertExpectations(t)
+}
@@ -0,0 +1,69 @@
+package todoist
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/fabiocorneti/todoist-assistant/internal/httpclient"
+)
+
+const (
+	// completedPath is the completed-items endpoint of the Sync API, which both transports use
+	// since the REST API does not return completed tasks.
+	completedPath     = "sync/v9/completed/get_all"
+	completedEndpoint = "GET completed/get_all"
+	completedPageSize = 200
+	// completedTimeFormat is the format of the since parameter, in UTC.
+	completedTimeFormat = "2006-01-02T15:04"
+)
+
+// CompletedTask is a task completed in Todoist.
+type CompletedTask struct {
+	TaskID      string    `json:"task_id"`
+	Content     string    `json:"content"`
+	CompletedAt time.Time `json:"completed_at"`
+}
+
+type requestFunc func(ctx context.Context, method, url string, body io.Reader) (*http.Request, error)
+
+// getCompletedTasks fetches the tasks completed since the given time from the completed-items
+// endpoint at completedURL, one page at a time.
+func getCompletedTasks(ctx context.Context, httpClient *httpclient.RateLimitedClient, newRequest requestFunc,
+	completedURL string, since time.Time) ([]CompletedTask, error) {
+	ctx = httpclient.WithEndpoint(ctx, completedEndpoint)
+	var completed []CompletedTask
+	for offset := 0; ; offset += completedPageSize {
+		query := url.Values{}
+		query.Set("since", since.UTC().Format(completedTimeFormat))
+		query.Set("limit", strconv.Itoa(completedPageSize))
+		query.Set("offset", strconv.Itoa(offset))
+		req, err := newRequest(ctx, http.MethodGet, completedURL+"?"+query.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if err = httpclient.CheckResponse(resp, completedEndpoint); err != nil {
+			return nil, err
+		}
+		var page struct {
+			Items []CompletedTask `json:"items"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		completed = append(completed, page.Items...)
+		if len(page.Items) < completedPageSize {
+			return completed, nil
+		}
+	}
+}
@@ -2,24 +2,29 @@
 
 package todoist
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	context "context"
+	"time"
+
+	mock "github.com/stretchr/testify/mock"
+)
 
 // MockTransport is an autogenerated mock type for the Transport type
 type MockTransport struct {
 	mock.Mock
 }
 
-// completeTask provides a mock function with given fields: taskID
-func (_m *MockTransport) completeTask(taskID string) error {
-	ret := _m.Called(taskID)
+// completeTask provides a mock function with given fields: ctx, taskID
+func (_m *MockTransport) completeTask(ctx context.Context, taskID string) error {
+	ret := _m.Called(ctx, taskID)
 
 	if len(ret) == 0 {
 		panic("no return value specified for completeTask")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(string) error); ok {
-		r0 = rf(taskID)
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, taskID)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -27,9 +32,9 @@ func (_m *MockTransport) completeTask(taskID string) error {
 	return r0
 }
 
-// createTask provides a mock function with given fields: content, projectID
-func (_m *MockTransport) createTask(content string, projectID string) (*Task, error) {
-	ret := _m.Called(content, projectID)
+// createTask provides a mock function with given fields: ctx, content, projectID
+func (_m *MockTransport) createTask(ctx context.Context, content string, projectID string) (*Task, error) {
+	ret := _m.Called(ctx, content, projectID)
 
 	if len(ret) == 0 {
 		panic("no return value specified for createTask")
@@ -37,19 +42,19 @@ func (_m *MockTransport) createTask(content string, projectID string) (*Task, er
 
 	var r0 *Task
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string, string) (*Task, error)); ok {
-		return rf(content, projectID)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*Task, error)); ok {
+		return rf(ctx, content, projectID)
 	}
-	if rf, ok := ret.Get(0).(func(string, string) *Task); ok {
-		r0 = rf(content, projectID)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *Task); ok {
+		r0 = rf(ctx, content, projectID)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*Task)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(string, string) error); ok {
-		r1 = rf(content, projectID)
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, content, projectID)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -57,29 +62,93 @@ func (_m *MockTransport) createTask(content string, projectID string) (*Task, er
 	return r0, r1
 }
 
-// getAllTasks provides a mock function with given fields:
-func (_m *MockTransport) getAllTasks() ([]Task, error) {
+// deleteTask provides a mock function with given fields: ctx, taskID
+func (_m *MockTransport) deleteTask(ctx context.Context, taskID string) error {
+	ret := _m.Called(ctx, taskID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for deleteTask")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, taskID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// exportState provides a mock function with given fields:
+func (_m *MockTransport) exportState() (SyncState, bool) {
 	ret := _m.Called()
 
+	if len(ret) == 0 {
+		panic("no return value specified for exportState")
+	}
+
+	var r0 SyncState
+	var r1 bool
+	if rf, ok := ret.Get(0).(func() (SyncState, bool)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() SyncState); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(SyncState)
+	}
+
+	if rf, ok := ret.Get(1).(func() bool); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	return r0, r1
+}
+
+// flush provides a mock function with given fields: ctx
+func (_m *MockTransport) flush(ctx context.Context) error {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for flush")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context) error); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// getAllTasks provides a mock function with given fields: ctx
+func (_m *MockTransport) getAllTasks(ctx context.Context) ([]Task, error) {
+	ret := _m.Called(ctx)
+
 	if len(ret) == 0 {
 		panic("no return value specified for getAllTasks")
 	}
 
 	var r0 []Task
 	var r1 error
-	if rf, ok := ret.Get(0).(func() ([]Task, error)); ok {
-		return rf()
+	if rf, ok := ret.Get(0).(func(context.Context) ([]Task, error)); ok {
+		return rf(ctx)
 	}
-	if rf, ok := ret.Get(0).(func() []Task); ok {
-		r0 = rf()
+	if rf, ok := ret.Get(0).(func(context.Context) []Task); ok {
+		r0 = rf(ctx)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]Task)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func() error); ok {
-		r1 = rf()
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -87,9 +156,39 @@ func (_m *MockTransport) getAllTasks() ([]Task, error) {
 	return r0, r1
 }
 
-// getProjects provides a mock function with given fields:
-func (_m *MockTransport) getProjects() ([]Project, error) {
-	ret := _m.Called()
+// getCompletedTasks provides a mock function with given fields: ctx, since
+func (_m *MockTransport) getCompletedTasks(ctx context.Context, since time.Time) ([]CompletedTask, error) {
+	ret := _m.Called(ctx, since)
+
+	if len(ret) == 0 {
+		panic("no return value specified for getCompletedTasks")
+	}
+
+	var r0 []CompletedTask
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) ([]CompletedTask, error)); ok {
+		return rf(ctx, since)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) []CompletedTask); ok {
+		r0 = rf(ctx, since)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]CompletedTask)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = rf(ctx, since)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// getProjects provides a mock function with given fields: ctx
+func (_m *MockTransport) getProjects(ctx context.Context) ([]Project, error) {
+	ret := _m.Called(ctx)
 
 	if len(ret) == 0 {
 		panic("no return value specified for getProjects")
@@ -97,19 +196,19 @@ func (_m *MockTransport) getProjects() ([]Project, error) {
 
 	var r0 []Project
 	var r1 error
-	if rf, ok := ret.Get(0).(func() ([]Project, error)); ok {
-		return rf()
+	if rf, ok := ret.Get(0).(func(context.Context) ([]Project, error)); ok {
+		return rf(ctx)
 	}
-	if rf, ok := ret.Get(0).(func() []Project); ok {
-		r0 = rf()
+	if rf, ok := ret.Get(0).(func(context.Context) []Project); ok {
+		r0 = rf(ctx)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]Project)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func() error); ok {
-		r1 = rf()
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -117,9 +216,9 @@ func (_m *MockTransport) getProjects() ([]Project, error) {
 	return r0, r1
 }
 
-// getTaskLabels provides a mock function with given fields: taskID
-func (_m *MockTransport) getTaskLabels(taskID string) ([]string, error) {
-	ret := _m.Called(taskID)
+// getTaskLabels provides a mock function with given fields: ctx, taskID
+func (_m *MockTransport) getTaskLabels(ctx context.Context, taskID string) ([]string, error) {
+	ret := _m.Called(ctx, taskID)
 
 	if len(ret) == 0 {
 		panic("no return value specified for getTaskLabels")
@@ -127,19 +226,19 @@ func (_m *MockTransport) getTaskLabels(taskID string) ([]string, error) {
 
 	var r0 []string
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string) ([]string, error)); ok {
-		return rf(taskID)
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]string, error)); ok {
+		return rf(ctx, taskID)
 	}
-	if rf, ok := ret.Get(0).(func(string) []string); ok {
-		r0 = rf(taskID)
+	if rf, ok := ret.Get(0).(func(context.Context, string) []string); ok {
+		r0 = rf(ctx, taskID)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]string)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(taskID)
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, taskID)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -147,9 +246,9 @@ func (_m *MockTransport) getTaskLabels(taskID string) ([]string, error) {
 	return r0, r1
 }
 
-// getTasksForProject provides a mock function with given fields: projectID
-func (_m *MockTransport) getTasksForProject(projectID string) ([]Task, error) {
-	ret := _m.Called(projectID)
+// getTasksForProject provides a mock function with given fields: ctx, projectID
+func (_m *MockTransport) getTasksForProject(ctx context.Context, projectID string) ([]Task, error) {
+	ret := _m.Called(ctx, projectID)
 
 	if len(ret) == 0 {
 		panic("no return value specified for getTasksForProject")
@@ -157,19 +256,19 @@ func (_m *MockTransport) getTasksForProject(projectID string) ([]Task, error) {
 
 	var r0 []Task
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string) ([]Task, error)); ok {
-		return rf(projectID)
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]Task, error)); ok {
+		return rf(ctx, projectID)
 	}
-	if rf, ok := ret.Get(0).(func(string) []Task); ok {
-		r0 = rf(projectID)
+	if rf, ok := ret.Get(0).(func(context.Context, string) []Task); ok {
+		r0 = rf(ctx, projectID)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]Task)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(projectID)
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, projectID)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -177,17 +276,94 @@ func (_m *MockTransport) getTasksForProject(projectID string) ([]Task, error) {
 	return r0, r1
 }
 
-// setTaskPriority provides a mock function with given fields: taskID, priority
-func (_m *MockTransport) setTaskPriority(taskID string, priority int) error {
-	ret := _m.Called(taskID, priority)
+// lastWrite provides a mock function with given fields:
+func (_m *MockTransport) lastWrite() string {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for lastWrite")
+	}
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
+// reopenTask provides a mock function with given fields: ctx, taskID
+func (_m *MockTransport) reopenTask(ctx context.Context, taskID string) error {
+	ret := _m.Called(ctx, taskID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for reopenTask")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, taskID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// resolveID provides a mock function with given fields: taskID
+func (_m *MockTransport) resolveID(taskID string) string {
+	ret := _m.Called(taskID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for resolveID")
+	}
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(string) string); ok {
+		r0 = rf(taskID)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
+// restoreState provides a mock function with given fields: state
+func (_m *MockTransport) restoreState(state SyncState) {
+	_m.Called(state)
+}
+
+// setTaskDueDate provides a mock function with given fields: ctx, taskID, date
+func (_m *MockTransport) setTaskDueDate(ctx context.Context, taskID string, date string) error {
+	ret := _m.Called(ctx, taskID, date)
+
+	if len(ret) == 0 {
+		panic("no return value specified for setTaskDueDate")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, taskID, date)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// setTaskPriority provides a mock function with given fields: ctx, taskID, priority
+func (_m *MockTransport) setTaskPriority(ctx context.Context, taskID string, priority int) error {
+	ret := _m.Called(ctx, taskID, priority)
 
 	if len(ret) == 0 {
 		panic("no return value specified for setTaskPriority")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(string, int) error); ok {
-		r0 = rf(taskID, priority)
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) error); ok {
+		r0 = rf(ctx, taskID, priority)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -195,17 +371,81 @@ func (_m *MockTransport) setTaskPriority(taskID string, priority int) error {
 	return r0
 }
 
-// updateTaskLabels provides a mock function with given fields: taskID, labels
-func (_m *MockTransport) updateTaskLabels(taskID string, labels []string) error {
-	ret := _m.Called(taskID, labels)
+// syncedID provides a mock function with given fields: taskID
+func (_m *MockTransport) syncedID(taskID string) (string, bool) {
+	ret := _m.Called(taskID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for syncedID")
+	}
+
+	var r0 string
+	var r1 bool
+	if rf, ok := ret.Get(0).(func(string) (string, bool)); ok {
+		return rf(taskID)
+	}
+	if rf, ok := ret.Get(0).(func(string) string); ok {
+		r0 = rf(taskID)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) bool); ok {
+		r1 = rf(taskID)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	return r0, r1
+}
+
+// updateTaskContent provides a mock function with given fields: ctx, taskID, content
+func (_m *MockTransport) updateTaskContent(ctx context.Context, taskID string, content string) error {
+	ret := _m.Called(ctx, taskID, content)
+
+	if len(ret) == 0 {
+		panic("no return value specified for updateTaskContent")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, taskID, content)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// updateTaskDescription provides a mock function with given fields: ctx, taskID, description
+func (_m *MockTransport) updateTaskDescription(ctx context.Context, taskID string, description string) error {
+	ret := _m.Called(ctx, taskID, description)
+
+	if len(ret) == 0 {
+		panic("no return value specified for updateTaskDescription")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, taskID, description)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// updateTaskLabels provides a mock function with given fields: ctx, taskID, labels
+func (_m *MockTransport) updateTaskLabels(ctx context.Context, taskID string, labels []string) error {
+	ret := _m.Called(ctx, taskID, labels)
 
 	if len(ret) == 0 {
 		panic("no return value specified for updateTaskLabels")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(string, []string) error); ok {
-		r0 = rf(taskID, labels)
+	if rf, ok := ret.Get(0).(func(context.Context, string, []string) error); ok {
+		r0 = rf(ctx, taskID, labels)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -213,6 +453,24 @@ func (_m *MockTransport) updateTaskLabels(taskID string, labels []string) error
 	return r0
 }
 
+// writeStatus provides a mock function with given fields: writeID
+func (_m *MockTransport) writeStatus(writeID string) writeStatus {
+	ret := _m.Called(writeID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for writeStatus")
+	}
+
+	var r0 writeStatus
+	if rf, ok := ret.Get(0).(func(string) writeStatus); ok {
+		r0 = rf(writeID)
+	} else {
+		r0 = ret.Get(0).(writeStatus)
+	}
+
+	return r0
+}
+
 // NewMockTransport creates a new instance of MockTransport. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewMockTransport(t interface {
@@ -1,5 +1,7 @@
 package todoist
 
+import "time"
+
 type Project struct {
 	ID       string `json:"id"`
 	Name     string `json:"name"`
@@ -11,8 +13,40 @@ type Task struct {
 	Labels    []string `json:"labels"`
 	ProjectID string   `json:"project_id,omitempty"`
 	Content   string   `json:"content"`
-	Order     *int     `json:"order,omitempty"`
-	Priority  *int     `json:"priority,omitempty"`
+	// Description is the description of the task, below its content.
+	Description string `json:"description,omitempty"`
+	Order       *int   `json:"order,omitempty"`
+	Priority    *int   `json:"priority,omitempty"`
+	Due         *Due   `json:"due,omitempty"`
+}
+
+// Due is the due date of a task; Date is a date such as 2024-03-05, followed by a time in the Sync
+// API if the task is due at a given time. The REST API returns that time in Datetime instead.
+type Due struct {
+	Date     string `json:"date"`
+	Datetime string `json:"datetime,omitempty"`
+}
+
+// Day returns the date a task is due, without its time; it returns an empty string if the task
+// has no due date.
+func (d *Due) Day() string {
+	if d == nil || len(d.Date) < len(time.DateOnly) {
+		return ""
+	}
+	return d.Date[:len(time.DateOnly)]
+}
+
+// Value returns the date a task is due with its time, if any, as accepted by SetTaskDueDate; it
+// returns an empty string if the task has no due date.
+func (d *Due) Value() string {
+	switch {
+	case d == nil:
+		return ""
+	case d.Datetime != "":
+		return d.Datetime
+	default:
+		return d.Date
+	}
 }
 
 type Label struct {
@@ -0,0 +1,131 @@
+package todoist
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+const (
+	ActionCreateTask        = "create_task"
+	ActionCompleteTask      = "complete_task"
+	ActionReplaceLabels     = "replace_labels"
+	ActionSetPriority       = "set_priority"
+	ActionAddLabels         = "add_labels"
+	ActionRemoveLabels      = "remove_labels"
+	ActionReopenTask        = "reopen_task"
+	ActionDeleteTask        = "delete_task"
+	ActionUpdateContent     = "update_content"
+	ActionSetDueDate        = "set_due_date"
+	ActionUpdateDescription = "update_description"
+
+	PlanFormatText = "text"
+	PlanFormatJSON = "json"
+
+	dryRunIDPrefix = "dry-run-"
+)
+
+// Change is a mutation that would have been sent to Todoist.
+type Change struct {
+	Action    string   `json:"action"`
+	TaskID    string   `json:"taskId"`
+	Content   string   `json:"content,omitempty"`
+	ProjectID string   `json:"projectId,omitempty"`
+	Labels    []string `json:"labels,omitempty"`
+	Priority  int      `json:"priority,omitempty"`
+	// NewContent is the content set by ActionUpdateContent.
+	NewContent string `json:"newContent,omitempty"`
+	// DueDate is the due date set by ActionSetDueDate; the due date is removed if empty.
+	DueDate string `json:"dueDate,omitempty"`
+	// Description is the description set by ActionUpdateDescription.
+	Description string `json:"description,omitempty"`
+}
+
+// Plan collects the changes recorded by a client in dry-run mode.
+type Plan struct {
+	mu      sync.Mutex
+	Changes []Change `json:"changes"`
+}
+
+func (p *Plan) record(change Change) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.Changes = append(p.Changes, change)
+}
+
+// Write prints the plan in the given format (PlanFormatText or PlanFormatJSON).
+func (p *Plan) Write(w io.Writer, format string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch format {
+	case PlanFormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(p)
+	case PlanFormatText, "":
+		return p.writeText(w)
+	default:
+		return fmt.Errorf("unknown plan format %s", format)
+	}
+}
+
+func (p *Plan) writeText(w io.Writer) error {
+	if len(p.Changes) == 0 {
+		_, err := fmt.Fprintln(w, "No changes to Todoist tasks.")
+		return err
+	}
+
+	if _, err := fmt.Fprintf(w, "%d changes to Todoist tasks:\n", len(p.Changes)); err != nil {
+		return err
+	}
+	for _, change := range p.Changes {
+		if _, err := fmt.Fprintf(w, "  - %s\n", change.describe()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Change) describe() string {
+	task := c.TaskID
+	if c.Content != "" {
+		task = fmt.Sprintf("%q (%s)", c.Content, c.TaskID)
+	}
+	labels := strings.Join(c.Labels, ", ")
+
+	switch c.Action {
+	case ActionCreateTask:
+		if c.ProjectID != "" {
+			return fmt.Sprintf("create task %q in project %s", c.Content, c.ProjectID)
+		}
+		return fmt.Sprintf("create task %q in the inbox", c.Content)
+	case ActionCompleteTask:
+		return "complete task " + task
+	case ActionReplaceLabels:
+		return fmt.Sprintf("set labels of task %s to [%s]", task, labels)
+	case ActionSetPriority:
+		return fmt.Sprintf("set priority of task %s to %d", task, c.Priority)
+	case ActionAddLabels:
+		return fmt.Sprintf("add labels [%s] to task %s", labels, task)
+	case ActionRemoveLabels:
+		return fmt.Sprintf("remove labels [%s] from task %s", labels, task)
+	case ActionReopenTask:
+		return "reopen task " + task
+	case ActionDeleteTask:
+		return "delete task " + task
+	case ActionUpdateContent:
+		return fmt.Sprintf("change content of task %s to %q", task, c.NewContent)
+	case ActionUpdateDescription:
+		return fmt.Sprintf("change description of task %s to %q", task, c.Description)
+	case ActionSetDueDate:
+		if c.DueDate == "" {
+			return "remove due date of task " + task
+		}
+		return fmt.Sprintf("set due date of task %s to %s", task, c.DueDate)
+	default:
+		return fmt.Sprintf("%s on task %s", c.Action, task)
+	}
+}
@@ -4,137 +4,81 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"strings"
+	"time"
+
+	"github.com/fabiocorneti/todoist-assistant/internal/httpclient"
 )
 
 const (
-	apiURL    = "https://api.todoist.com/rest/v2/"
+	restPath  = "rest/v2/"
 	tasksPath = "tasks"
+
+	// noDueDate is the due string removing the due date of a task.
+	noDueDate = "no date"
 )
 
 type RESTTodoistTransport struct {
-	httpClient *RateLimitedClient
-	token      string
-	testMode   bool
+	httpClient   *httpclient.RateLimitedClient
+	baseURL      string
+	completedURL string
+	token        string
+	testMode     bool
 }
 
-func NewRESTTodoistTransport(token string, testMode bool) Transport {
+// NewRESTTodoistTransport creates a transport for the REST API at baseURL, such as
+// https://api.todoist.com/rest/v2/; completed tasks are fetched from the Sync API next to it.
+func NewRESTTodoistTransport(httpClient *httpclient.RateLimitedClient, baseURL, token string,
+	testMode bool) Transport {
 	return &RESTTodoistTransport{
-		httpClient: NewRateLimitedClient(),
-		token:      token,
-		testMode:   testMode,
+		httpClient:   httpClient,
+		baseURL:      baseURL,
+		completedURL: strings.TrimSuffix(baseURL, restPath) + completedPath,
+		token:        token,
+		testMode:     testMode,
 	}
 }
 
-func (t *RESTTodoistTransport) getProjects() ([]Project, error) {
-	req, err := t.newRequest("GET", apiURL+"projects", nil)
-	if err != nil {
-		return nil, err
-	}
-
-	resp, err := t.httpClient.Do(req.WithContext(context.TODO()))
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("received non-OK HTTP status code: %d", resp.StatusCode)
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-
+func (t *RESTTodoistTransport) getProjects(ctx context.Context) ([]Project, error) {
 	var projects []Project
-	if err = json.Unmarshal(body, &projects); err != nil {
+	if err := t.do(ctx, http.MethodGet, "projects", "projects", nil, &projects); err != nil {
 		return nil, err
 	}
-
 	return projects, nil
 }
 
-func (t *RESTTodoistTransport) getTasksForProject(projectID string) ([]Task, error) {
-	req, err := t.newRequest("GET", apiURL+tasksPath+"?project_id="+projectID, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	resp, err := t.httpClient.Do(req.WithContext(context.TODO()))
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-
+func (t *RESTTodoistTransport) getTasksForProject(ctx context.Context, projectID string) ([]Task, error) {
 	var tasks []Task
-	if err = json.Unmarshal(body, &tasks); err != nil {
+	if err := t.do(ctx, http.MethodGet, tasksPath+"?project_id="+projectID, tasksPath, nil, &tasks); err != nil {
 		return nil, err
 	}
-
 	return tasks, nil
 }
 
-func (t *RESTTodoistTransport) getAllTasks() ([]Task, error) {
-	req, err := t.newRequest("GET", apiURL+tasksPath, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	resp, err := t.httpClient.Do(req.WithContext(context.TODO()))
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
+func (t *RESTTodoistTransport) getCompletedTasks(ctx context.Context, since time.Time) ([]CompletedTask, error) {
+	return getCompletedTasks(ctx, t.httpClient, t.newRequest, t.completedURL, since)
+}
 
+func (t *RESTTodoistTransport) getAllTasks(ctx context.Context) ([]Task, error) {
 	var tasks []Task
-	if err = json.Unmarshal(body, &tasks); err != nil {
+	if err := t.do(ctx, http.MethodGet, tasksPath, tasksPath, nil, &tasks); err != nil {
 		return nil, err
 	}
-
 	return tasks, nil
 }
 
-func (t *RESTTodoistTransport) getTaskLabels(taskID string) ([]string, error) {
-	req, err := t.newRequest("GET", apiURL+tasksPath+"/"+taskID, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	resp, err := t.httpClient.Do(req.WithContext(context.TODO()))
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-
+func (t *RESTTodoistTransport) getTaskLabels(ctx context.Context, taskID string) ([]string, error) {
 	var task Task
-	if err = json.Unmarshal(body, &task); err != nil {
+	if err := t.do(ctx, http.MethodGet, tasksPath+"/"+taskID, tasksPath+"/{id}", nil, &task); err != nil {
 		return nil, err
 	}
-
 	return task.Labels, nil
 }
 
-func (t *RESTTodoistTransport) createTask(content, projectID string) (*Task, error) {
+func (t *RESTTodoistTransport) createTask(ctx context.Context, content, projectID string) (*Task, error) {
 	task := Task{
 		Content: content,
 	}
@@ -143,94 +87,136 @@ func (t *RESTTodoistTransport) createTask(content, projectID string) (*Task, err
 		task.ProjectID = projectID
 	}
 
-	jsonTask, err := json.Marshal(task)
-	if err != nil {
+	var createdTask Task
+	if err := t.do(ctx, http.MethodPost, tasksPath, tasksPath, task, &createdTask); err != nil {
 		return nil, err
 	}
+	return &createdTask, nil
+}
 
-	req, err := t.newRequest("POST", apiURL+tasksPath, bytes.NewBuffer(jsonTask))
-	if err != nil {
-		return nil, err
+func (t *RESTTodoistTransport) updateTaskLabels(ctx context.Context, taskID string, labels []string) error {
+	if labels == nil {
+		labels = []string{}
 	}
+	return t.do(ctx, http.MethodPost, tasksPath+"/"+taskID, tasksPath+"/{id}",
+		map[string][]string{"labels": labels}, nil)
+}
 
-	resp, err := t.httpClient.Do(req.WithContext(context.TODO()))
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+func (t *RESTTodoistTransport) updateTaskContent(ctx context.Context, taskID, content string) error {
+	return t.do(ctx, http.MethodPost, tasksPath+"/"+taskID, tasksPath+"/{id}",
+		map[string]string{"content": content}, nil)
+}
 
-	var createdTask Task
-	if err = json.NewDecoder(resp.Body).Decode(&createdTask); err != nil {
-		return nil, err
+func (t *RESTTodoistTransport) updateTaskDescription(ctx context.Context, taskID, description string) error {
+	return t.do(ctx, http.MethodPost, tasksPath+"/"+taskID, tasksPath+"/{id}",
+		map[string]string{"description": description}, nil)
+}
+
+func (t *RESTTodoistTransport) setTaskDueDate(ctx context.Context, taskID, date string) error {
+	payload := map[string]string{"due_date": date}
+	switch {
+	case date == "":
+		payload = map[string]string{"due_string": noDueDate}
+	case len(date) > len(time.DateOnly):
+		payload = map[string]string{"due_datetime": date}
 	}
+	return t.do(ctx, http.MethodPost, tasksPath+"/"+taskID, tasksPath+"/{id}", payload, nil)
+}
 
-	return &createdTask, nil
+func (t *RESTTodoistTransport) setTaskPriority(ctx context.Context, taskID string, priority int) error {
+	return t.do(ctx, http.MethodPost, tasksPath+"/"+taskID, tasksPath+"/{id}",
+		map[string]int{"priority": priority}, nil)
 }
 
-func (t *RESTTodoistTransport) updateTaskLabels(taskID string, labels []string) error {
-	jsonData, err := json.Marshal(map[string][]string{"labels": labels})
-	if err != nil {
-		return err
-	}
+func (t *RESTTodoistTransport) completeTask(ctx context.Context, taskID string) error {
+	return t.do(ctx, http.MethodPost, tasksPath+"/"+taskID+"/close", tasksPath+"/{id}/close", nil, nil)
+}
 
-	req, err := t.newRequest("POST", apiURL+tasksPath+"/"+taskID, strings.NewReader(string(jsonData)))
-	if err != nil {
-		return err
-	}
+func (t *RESTTodoistTransport) reopenTask(ctx context.Context, taskID string) error {
+	return t.do(ctx, http.MethodPost, tasksPath+"/"+taskID+"/reopen", tasksPath+"/{id}/reopen", nil, nil)
+}
 
-	resp, err := t.httpClient.Do(req.WithContext(context.TODO()))
-	if err != nil {
-		return err
-	}
+func (t *RESTTodoistTransport) deleteTask(ctx context.Context, taskID string) error {
+	return t.do(ctx, http.MethodDelete, tasksPath+"/"+taskID, tasksPath+"/{id}", nil, nil)
+}
 
-	defer resp.Body.Close()
-	return err
+func (t *RESTTodoistTransport) flush(_ context.Context) error {
+	return nil
 }
 
-func (t *RESTTodoistTransport) setTaskPriority(taskID string, priority int) error {
-	jsonData, err := json.Marshal(map[string]int{"priority": priority})
-	if err != nil {
-		return err
-	}
+// lastWrite returns "", since the REST API applies every write immediately.
+func (t *RESTTodoistTransport) lastWrite() string {
+	return ""
+}
 
-	req, err := t.newRequest("POST", apiURL+tasksPath+"/"+taskID, strings.NewReader(string(jsonData)))
-	if err != nil {
-		return err
-	}
+func (t *RESTTodoistTransport) writeStatus(_ string) writeStatus {
+	return writeApplied
+}
 
-	resp, err := t.httpClient.Do(req.WithContext(context.TODO()))
-	if err != nil {
-		return err
-	}
+func (t *RESTTodoistTransport) resolveID(taskID string) string {
+	return taskID
+}
 
-	resp.Body.Close()
-	return nil
+func (t *RESTTodoistTransport) syncedID(taskID string) (string, bool) {
+	return taskID, true
 }
 
-func (t *RESTTodoistTransport) completeTask(taskID string) error {
-	req, err := t.newRequest("POST", apiURL+tasksPath+"/"+taskID+"/close", nil)
+// exportState returns false, since the REST transport does not cache anything.
+func (t *RESTTodoistTransport) exportState() (SyncState, bool) {
+	return SyncState{}, false
+}
+
+func (t *RESTTodoistTransport) restoreState(_ SyncState) {}
+
+// do sends a request to path, encoding payload as JSON if set and decoding the response into
+// result if set; endpoint identifies the path without IDs in errors.
+func (t *RESTTodoistTransport) do(ctx context.Context, method, path, endpoint string, payload, result any) error {
+	var body io.Reader
+	if payload != nil {
+		jsonData, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(jsonData)
+	}
+
+	endpoint = method + " " + endpoint
+	req, err := t.newRequest(httpclient.WithEndpoint(ctx, endpoint), method, t.baseURL+path, body)
 	if err != nil {
 		return err
 	}
 
-	resp, err := t.httpClient.Do(req.WithContext(context.TODO()))
+	resp, err := t.httpClient.Do(req)
 	if err != nil {
 		return err
 	}
+	if err = httpclient.CheckResponse(resp, endpoint); err != nil {
+		return err
+	}
+	defer resp.Body.Close()
 
-	resp.Body.Close()
-	return nil
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
 }
 
-func (t *RESTTodoistTransport) newRequest(method, url string, body io.Reader) (*http.Request, error) {
+func (t *RESTTodoistTransport) newRequest(ctx context.Context, method, url string, body io.Reader) (*http.Request, error) {
 	if t.testMode {
 		log.Fatal("Cannot send requests in test mode")
 	}
-	req, err := http.NewRequestWithContext(context.TODO(), method, url, body)
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("Authorization", "Bearer "+t.token)
+	if method == http.MethodPost {
+		requestID, uuidErr := newUUID()
+		if uuidErr != nil {
+			return nil, uuidErr
+		}
+		req.Header.Set(httpclient.RequestIDHeader, requestID)
+	}
 	req.Header.Set("Content-Type", "application/json")
 	return req, nil
 }
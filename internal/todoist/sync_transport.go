@@ -0,0 +1,639 @@
+package todoist
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fabiocorneti/todoist-assistant/internal/httpclient"
+)
+
+const (
+	syncPath         = "sync/v9/sync"
+	initialSyncToken = "*"
+	maxBatchCommands = 100
+	syncEndpoint     = "POST sync"
+)
+
+// SyncTodoistTransport is a Transport backed by the Todoist Sync API.
+//
+// Projects and tasks are kept in a local cache refreshed through incremental
+// sync tokens; writes are queued as commands, applied optimistically to the
+// cache and sent in batches when the queue is full, before a full read or
+// when the transport is flushed. A command rejected by Todoist is reported to the
+// call that queued it if the batch is sent by that call, and otherwise when the
+// transport is flushed.
+type SyncTodoistTransport struct {
+	httpClient   *httpclient.RateLimitedClient
+	apiURL       string
+	completedURL string
+	token        string
+	testMode     bool
+
+	syncToken string
+	projects  map[string]Project
+	items     map[string]syncItem
+	commands  []syncCommand
+	tempIDs   map[string]string
+	// failures are the commands rejected by Todoist that have not been reported yet, and rejected
+	// are the IDs of the tasks changed by the commands rejected so far.
+	failures []*CommandError
+	rejected map[string]bool
+	// applied are the UUIDs of the commands applied by Todoist, and lastUUID is the UUID of the
+	// last command queued.
+	applied  map[string]bool
+	lastUUID string
+}
+
+// CommandError is a Sync API command rejected by Todoist, reported for the task it changed.
+type CommandError struct {
+	// TaskID is the ID of the task, or its temporary ID if the command created it.
+	TaskID  string
+	Command string
+	Err     *httpclient.APIError
+
+	uuid string
+}
+
+func (e *CommandError) Error() string {
+	return fmt.Sprintf("%s of task %s failed: %v", e.Command, e.TaskID, e.Err)
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// SyncState is a snapshot of the Sync API cache that can be persisted between runs.
+type SyncState struct {
+	Token    string    `json:"token"`
+	Projects []Project `json:"projects"`
+	Tasks    []Task    `json:"tasks"`
+}
+
+type syncItem struct {
+	ID          string   `json:"id"`
+	ProjectID   string   `json:"project_id"`
+	Content     string   `json:"content"`
+	Description string   `json:"description"`
+	Labels      []string `json:"labels"`
+	Priority    int      `json:"priority"`
+	Due         *Due     `json:"due"`
+	ChildOrder  int      `json:"child_order"`
+	Checked     bool     `json:"checked"`
+	IsDeleted   bool     `json:"is_deleted"`
+}
+
+type syncProject struct {
+	Project
+	IsDeleted  bool `json:"is_deleted"`
+	IsArchived bool `json:"is_archived"`
+}
+
+type syncCommand struct {
+	Type   string         `json:"type"`
+	UUID   string         `json:"uuid"`
+	TempID string         `json:"temp_id,omitempty"`
+	Args   map[string]any `json:"args"`
+}
+
+type syncResponse struct {
+	SyncToken     string                     `json:"sync_token"`
+	FullSync      bool                       `json:"full_sync"`
+	Projects      []syncProject              `json:"projects"`
+	Items         []syncItem                 `json:"items"`
+	SyncStatus    map[string]json.RawMessage `json:"sync_status"`
+	TempIDMapping map[string]string          `json:"temp_id_mapping"`
+}
+
+// NewSyncTodoistTransport creates a transport for the Sync API endpoint at apiURL, such as
+// https://api.todoist.com/sync/v9/sync; completed tasks are fetched from the completed-items
+// endpoint next to it.
+func NewSyncTodoistTransport(httpClient *httpclient.RateLimitedClient, apiURL, token string,
+	testMode bool) Transport {
+	return &SyncTodoistTransport{
+		httpClient:   httpClient,
+		apiURL:       apiURL,
+		completedURL: strings.TrimSuffix(apiURL, syncPath) + completedPath,
+		token:        token,
+		testMode:     testMode,
+		syncToken:    initialSyncToken,
+		projects:     make(map[string]Project),
+		items:        make(map[string]syncItem),
+		tempIDs:      make(map[string]string),
+		applied:      make(map[string]bool),
+		rejected:     make(map[string]bool),
+	}
+}
+
+func (t *SyncTodoistTransport) getCompletedTasks(ctx context.Context, since time.Time) ([]CompletedTask, error) {
+	return getCompletedTasks(ctx, t.httpClient, t.newRequest, t.completedURL, since)
+}
+
+func (t *SyncTodoistTransport) getProjects(ctx context.Context) ([]Project, error) {
+	if err := t.sync(ctx); err != nil {
+		return nil, err
+	}
+
+	projects := make([]Project, 0, len(t.projects))
+	for _, project := range t.projects {
+		projects = append(projects, project)
+	}
+	sort.Slice(projects, func(i, j int) bool {
+		return projects[i].ID < projects[j].ID
+	})
+	return projects, nil
+}
+
+func (t *SyncTodoistTransport) getAllTasks(ctx context.Context) ([]Task, error) {
+	if err := t.sync(ctx); err != nil {
+		return nil, err
+	}
+	return t.filterTasks(func(syncItem) bool { return true }), nil
+}
+
+func (t *SyncTodoistTransport) getTasksForProject(ctx context.Context, projectID string) ([]Task, error) {
+	if err := t.ensureSynced(ctx); err != nil {
+		return nil, err
+	}
+	return t.filterTasks(func(item syncItem) bool { return item.ProjectID == projectID }), nil
+}
+
+// getTaskLabels returns the labels of a cached task. A task that is not cached may have been
+// reopened by a pending command, so the commands are sent before the task is reported as not found.
+func (t *SyncTodoistTransport) getTaskLabels(ctx context.Context, taskID string) ([]string, error) {
+	if err := t.ensureSynced(ctx); err != nil {
+		return nil, err
+	}
+	item, exists := t.items[t.resolveID(taskID)]
+	if !exists && len(t.commands) > 0 {
+		if err := t.sync(ctx); err != nil {
+			return nil, err
+		}
+		item, exists = t.items[t.resolveID(taskID)]
+	}
+	if !exists {
+		return nil, &httpclient.APIError{Endpoint: syncEndpoint, StatusCode: http.StatusNotFound,
+			Body: fmt.Sprintf("task %s not found", taskID)}
+	}
+	return append([]string(nil), item.Labels...), nil
+}
+
+func (t *SyncTodoistTransport) createTask(ctx context.Context, content, projectID string) (*Task, error) {
+	tempID, err := newUUID()
+	if err != nil {
+		return nil, err
+	}
+	args := map[string]any{"content": content}
+	if projectID != "" {
+		args["project_id"] = t.resolveID(projectID)
+	}
+	t.items[tempID] = syncItem{
+		ID:        tempID,
+		ProjectID: projectID,
+		Content:   content,
+		Labels:    []string{},
+		Priority:  1,
+	}
+	if err = t.enqueue(ctx, "item_add", tempID, args); err != nil {
+		return nil, err
+	}
+
+	task := t.items[t.resolveID(tempID)].toTask()
+	return &task, nil
+}
+
+func (t *SyncTodoistTransport) updateTaskLabels(ctx context.Context, taskID string, labels []string) error {
+	id := t.resolveID(taskID)
+	if item, exists := t.items[id]; exists {
+		item.Labels = append([]string{}, labels...)
+		t.items[id] = item
+	}
+	if labels == nil {
+		labels = []string{}
+	}
+	return t.enqueue(ctx, "item_update", "", map[string]any{"id": id, "labels": labels})
+}
+
+func (t *SyncTodoistTransport) updateTaskContent(ctx context.Context, taskID, content string) error {
+	id := t.resolveID(taskID)
+	if item, exists := t.items[id]; exists {
+		item.Content = content
+		t.items[id] = item
+	}
+	return t.enqueue(ctx, "item_update", "", map[string]any{"id": id, "content": content})
+}
+
+func (t *SyncTodoistTransport) updateTaskDescription(ctx context.Context, taskID, description string) error {
+	id := t.resolveID(taskID)
+	if item, exists := t.items[id]; exists {
+		item.Description = description
+		t.items[id] = item
+	}
+	return t.enqueue(ctx, "item_update", "", map[string]any{"id": id, "description": description})
+}
+
+func (t *SyncTodoistTransport) setTaskDueDate(ctx context.Context, taskID, date string) error {
+	id := t.resolveID(taskID)
+	var due *Due
+	if date != "" {
+		due = &Due{Date: date}
+	}
+	if item, exists := t.items[id]; exists {
+		item.Due = due
+		t.items[id] = item
+	}
+	return t.enqueue(ctx, "item_update", "", map[string]any{"id": id, "due": due})
+}
+
+func (t *SyncTodoistTransport) setTaskPriority(ctx context.Context, taskID string, priority int) error {
+	id := t.resolveID(taskID)
+	if item, exists := t.items[id]; exists {
+		item.Priority = priority
+		t.items[id] = item
+	}
+	return t.enqueue(ctx, "item_update", "", map[string]any{"id": id, "priority": priority})
+}
+
+func (t *SyncTodoistTransport) completeTask(ctx context.Context, taskID string) error {
+	id := t.resolveID(taskID)
+	delete(t.items, id)
+	return t.enqueue(ctx, "item_close", "", map[string]any{"id": id})
+}
+
+// reopenTask reopens a completed task; the task is cached again by the next sync.
+func (t *SyncTodoistTransport) reopenTask(ctx context.Context, taskID string) error {
+	return t.enqueue(ctx, "item_uncomplete", "", map[string]any{"id": t.resolveID(taskID)})
+}
+
+func (t *SyncTodoistTransport) deleteTask(ctx context.Context, taskID string) error {
+	id := t.resolveID(taskID)
+	delete(t.items, id)
+	return t.enqueue(ctx, "item_delete", "", map[string]any{"id": id})
+}
+
+// flush sends the pending commands; it returns the CommandErrors of all the commands rejected
+// since the last flush that were not reported to the calls that queued them.
+func (t *SyncTodoistTransport) flush(ctx context.Context) error {
+	if len(t.commands) > 0 {
+		if err := t.sync(ctx); err != nil {
+			return err
+		}
+	}
+	failures := make([]error, 0, len(t.failures))
+	for _, failure := range t.failures {
+		failures = append(failures, failure)
+	}
+	t.failures = nil
+	return errors.Join(failures...)
+}
+
+// exportState returns the cache and sync token; pending commands are not included.
+func (t *SyncTodoistTransport) exportState() (SyncState, bool) {
+	state := SyncState{
+		Token: t.syncToken,
+		Tasks: t.filterTasks(func(syncItem) bool { return true }),
+	}
+	for _, project := range t.projects {
+		state.Projects = append(state.Projects, project)
+	}
+	return state, true
+}
+
+// restoreState replaces the cache with a snapshot so that the next sync is incremental.
+func (t *SyncTodoistTransport) restoreState(state SyncState) {
+	if state.Token == "" {
+		return
+	}
+	t.syncToken = state.Token
+	t.projects = make(map[string]Project)
+	t.items = make(map[string]syncItem)
+	for _, project := range state.Projects {
+		t.projects[project.ID] = project
+	}
+	for _, task := range state.Tasks {
+		t.items[task.ID] = newSyncItem(task)
+	}
+}
+
+func (t *SyncTodoistTransport) enqueue(ctx context.Context, commandType, tempID string, args map[string]any) error {
+	uuid, err := newUUID()
+	if err != nil {
+		return err
+	}
+	t.commands = append(t.commands, syncCommand{
+		Type:   commandType,
+		UUID:   uuid,
+		TempID: tempID,
+		Args:   args,
+	})
+	t.lastUUID = uuid
+	if len(t.commands) < maxBatchCommands {
+		return nil
+	}
+	if err = t.sync(ctx); err != nil {
+		// the call fails, so its command is not sent again with the rest of the batch
+		t.commands = removeCommand(t.commands, uuid)
+		return err
+	}
+	return t.takeFailure(uuid)
+}
+
+// takeFailure returns the CommandError of the command with the given UUID, which is no longer
+// reported by flush, or nil if the command did not fail.
+func (t *SyncTodoistTransport) takeFailure(uuid string) error {
+	for i, failure := range t.failures {
+		if failure.uuid == uuid {
+			t.failures = append(t.failures[:i], t.failures[i+1:]...)
+			return failure
+		}
+	}
+	return nil
+}
+
+// lastWrite returns the UUID of the last command queued.
+func (t *SyncTodoistTransport) lastWrite() string {
+	return t.lastUUID
+}
+
+// writeStatus returns whether the command with the given UUID was applied, rejected or not sent yet.
+func (t *SyncTodoistTransport) writeStatus(uuid string) writeStatus {
+	switch {
+	case t.applied[uuid]:
+		return writeApplied
+	case t.queued(uuid):
+		return writeQueued
+	default:
+		return writeRejected
+	}
+}
+
+// queued returns whether the command with the given UUID has not been sent yet.
+func (t *SyncTodoistTransport) queued(uuid string) bool {
+	for _, command := range t.commands {
+		if command.UUID == uuid {
+			return true
+		}
+	}
+	return false
+}
+
+// syncedID returns the real ID of a task, or false if some of its changes were rejected by Todoist
+// or have not been sent yet.
+func (t *SyncTodoistTransport) syncedID(taskID string) (string, bool) {
+	id := t.resolveID(taskID)
+	if t.rejected[taskID] || t.rejected[id] {
+		return "", false
+	}
+	for _, command := range t.commands {
+		if command.TempID == taskID || command.Args["id"] == taskID || command.Args["id"] == id {
+			return "", false
+		}
+	}
+	return id, true
+}
+
+func removeCommand(commands []syncCommand, uuid string) []syncCommand {
+	for i, command := range commands {
+		if command.UUID == uuid {
+			return append(commands[:i], commands[i+1:]...)
+		}
+	}
+	return commands
+}
+
+func (t *SyncTodoistTransport) ensureSynced(ctx context.Context) error {
+	if t.syncToken != initialSyncToken {
+		return nil
+	}
+	return t.sync(ctx)
+}
+
+// sync sends the pending commands and fetches the changes since the last sync token.
+//
+// If the request fails, the commands may or may not have been applied: they are queued again, to be
+// sent with the same UUIDs so that Todoist ignores the ones it already applied, and the next sync is
+// a full sync that replaces the changes applied optimistically to the cache.
+func (t *SyncTodoistTransport) sync(ctx context.Context) error {
+	commands := t.commands
+	t.commands = nil
+
+	response, err := t.send(ctx, commands)
+	if err != nil {
+		t.commands = append(commands, t.commands...)
+		t.syncToken = initialSyncToken
+		return err
+	}
+	t.apply(response)
+
+	for _, command := range commands {
+		if string(response.SyncStatus[command.UUID]) == `"ok"` {
+			t.applied[command.UUID] = true
+		}
+		if failure := commandError(command, response.SyncStatus[command.UUID]); failure != nil {
+			t.failures = append(t.failures, failure)
+			t.rejected[failure.TaskID] = true
+		}
+	}
+	if len(t.failures) > 0 {
+		// the cache has changes that Todoist rejected
+		t.syncToken = initialSyncToken
+	}
+	return nil
+}
+
+// send posts commands with the current sync token and returns the response.
+func (t *SyncTodoistTransport) send(ctx context.Context, commands []syncCommand) (*syncResponse, error) {
+	form := url.Values{}
+	form.Set("sync_token", t.syncToken)
+	form.Set("resource_types", `["projects","items"]`)
+	if len(commands) > 0 {
+		jsonCommands, err := json.Marshal(commands)
+		if err != nil {
+			return nil, err
+		}
+		form.Set("commands", string(jsonCommands))
+	}
+
+	ctx = httpclient.WithEndpoint(ctx, syncEndpoint)
+	req, err := t.newRequest(ctx, http.MethodPost, t.apiURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if err = httpclient.CheckResponse(resp, syncEndpoint); err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var response syncResponse
+	if err = json.Unmarshal(body, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+func (t *SyncTodoistTransport) apply(response *syncResponse) {
+	for tempID, realID := range response.TempIDMapping {
+		t.tempIDs[tempID] = realID
+		if item, exists := t.items[tempID]; exists {
+			delete(t.items, tempID)
+			item.ID = realID
+			t.items[realID] = item
+		}
+	}
+
+	if response.FullSync {
+		t.projects = make(map[string]Project)
+		t.items = make(map[string]syncItem)
+	}
+
+	for _, project := range response.Projects {
+		if project.IsDeleted || project.IsArchived {
+			delete(t.projects, project.ID)
+			continue
+		}
+		t.projects[project.ID] = project.Project
+	}
+	for _, item := range response.Items {
+		if item.IsDeleted || item.Checked {
+			delete(t.items, item.ID)
+			continue
+		}
+		t.items[item.ID] = item
+	}
+
+	t.syncToken = response.SyncToken
+}
+
+func (t *SyncTodoistTransport) filterTasks(include func(syncItem) bool) []Task {
+	var tasks []Task
+	for _, item := range t.items {
+		if include(item) {
+			tasks = append(tasks, item.toTask())
+		}
+	}
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return *tasks[i].Order < *tasks[j].Order
+	})
+	return tasks
+}
+
+// resolveID maps a temporary ID to its real ID once the command creating it has been synced.
+func (t *SyncTodoistTransport) resolveID(id string) string {
+	if realID, exists := t.tempIDs[id]; exists {
+		return realID
+	}
+	return id
+}
+
+func (t *SyncTodoistTransport) newRequest(ctx context.Context, method, url string, body io.Reader) (*http.Request, error) {
+	if t.testMode {
+		log.Fatal("Cannot send requests in test mode")
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	if method == http.MethodPost {
+		requestID, uuidErr := newUUID()
+		if uuidErr != nil {
+			return nil, uuidErr
+		}
+		req.Header.Set(httpclient.RequestIDHeader, requestID)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req, nil
+}
+
+func (item syncItem) toTask() Task {
+	order := item.ChildOrder
+	priority := item.Priority
+	return Task{
+		ID:          item.ID,
+		Labels:      item.Labels,
+		ProjectID:   item.ProjectID,
+		Content:     item.Content,
+		Description: item.Description,
+		Order:       &order,
+		Priority:    &priority,
+		Due:         item.Due,
+	}
+}
+
+func newSyncItem(task Task) syncItem {
+	item := syncItem{
+		ID:          task.ID,
+		ProjectID:   task.ProjectID,
+		Content:     task.Content,
+		Description: task.Description,
+		Labels:      task.Labels,
+		Due:         task.Due,
+	}
+	if task.Order != nil {
+		item.ChildOrder = *task.Order
+	}
+	if task.Priority != nil {
+		item.Priority = *task.Priority
+	}
+	return item
+}
+
+// commandError returns a CommandError if the status of a command is not ok; a missing status means
+// that the command was not processed.
+func commandError(command syncCommand, status json.RawMessage) *CommandError {
+	if status == nil || string(status) == `"ok"` {
+		return nil
+	}
+	failure := &CommandError{
+		TaskID:  command.TempID,
+		Command: command.Type,
+		Err:     &httpclient.APIError{Endpoint: syncEndpoint, StatusCode: http.StatusBadRequest, Body: string(status)},
+		uuid:    command.UUID,
+	}
+	if id, ok := command.Args["id"].(string); ok {
+		failure.TaskID = id
+	}
+	var result struct {
+		ErrorCode int    `json:"error_code"`
+		Error     string `json:"error"`
+		HTTPCode  int    `json:"http_code"`
+	}
+	if err := json.Unmarshal(status, &result); err == nil {
+		failure.Err.Body = fmt.Sprintf("%s (code %d)", result.Error, result.ErrorCode)
+		if result.HTTPCode != 0 {
+			failure.Err.StatusCode = result.HTTPCode
+		}
+	}
+	return failure
+}
+
+func newUUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	s := hex.EncodeToString(b)
+	return fmt.Sprintf("%s-%s-%s-%s-%s", s[0:8], s[8:12], s[12:16], s[16:20], s[20:]), nil
+}
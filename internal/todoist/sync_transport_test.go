@@ -0,0 +1,293 @@
+package todoist
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fabiocorneti/todoist-assistant/internal/httpclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// syncRequest is a request received by the fake Sync API of a test.
+type syncRequest struct {
+	token    string
+	commands []syncCommand
+}
+
+// newTestSyncTransport returns a transport whose requests are answered by respond with a status and
+// a response.
+func newTestSyncTransport(t *testing.T, respond func(request syncRequest) (int, syncResponse)) *SyncTodoistTransport {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request := syncRequest{token: r.FormValue("sync_token")}
+		if commands := r.FormValue("commands"); commands != "" {
+			if err := json.Unmarshal([]byte(commands), &request.commands); err != nil {
+				t.Errorf("invalid commands: %v", err)
+			}
+		}
+		status, response := respond(request)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(response) //nolint:errcheck // the transport sees a truncated response
+	}))
+	t.Cleanup(server.Close)
+	client := httpclient.NewRateLimitedClient("todoist", httpclient.Limit{}, httpclient.RetryPolicy{}, time.Second)
+	return NewSyncTodoistTransport(client, server.URL+"/"+syncPath, "TEST", false).(*SyncTodoistTransport)
+}
+
+// okStatus returns the sync status of commands that all succeeded.
+func okStatus(commands []syncCommand) map[string]json.RawMessage {
+	status := make(map[string]json.RawMessage, len(commands))
+	for _, command := range commands {
+		status[command.UUID] = json.RawMessage(`"ok"`)
+	}
+	return status
+}
+
+func TestSyncTransportApply(t *testing.T) {
+	ctx := context.Background()
+	transport := NewSyncTodoistTransport(nil, DefaultAPIURL+syncPath, "TEST", true).(*SyncTodoistTransport)
+
+	transport.apply(&syncResponse{
+		SyncToken: "token1",
+		FullSync:  true,
+		Projects: []syncProject{
+			{Project: Project{ID: "p1", Name: "Inbox"}},
+			{Project: Project{ID: "p2", Name: "Archived"}, IsArchived: true},
+		},
+		Items: []syncItem{
+			{ID: "2", ProjectID: "p1", Content: "Second", ChildOrder: 2},
+			{ID: "1", ProjectID: "p1", Content: "First", ChildOrder: 1, Labels: []string{"work"}},
+			{ID: "3", ProjectID: "p1", Content: "Done", ChildOrder: 3, Checked: true},
+		},
+	})
+
+	assert.Equal(t, "token1", transport.syncToken)
+	assert.Equal(t, map[string]Project{"p1": {ID: "p1", Name: "Inbox"}}, transport.projects)
+
+	tasks, err := transport.getTasksForProject(ctx, "p1")
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 2)
+	assert.Equal(t, "First", tasks[0].Content)
+	assert.Equal(t, "Second", tasks[1].Content)
+
+	created, err := transport.createTask(ctx, "New", "p1")
+	assert.NoError(t, err)
+	assert.NoError(t, transport.updateTaskLabels(ctx, created.ID, []string{"Jira"}))
+	assert.NoError(t, transport.setTaskDueDate(ctx, "1", "2024-03-08"))
+	assert.Len(t, transport.commands, 3)
+
+	transport.apply(&syncResponse{
+		SyncToken:     "token2",
+		TempIDMapping: map[string]string{created.ID: "4"},
+		Items: []syncItem{
+			{ID: "2", IsDeleted: true},
+		},
+	})
+
+	labels, err := transport.getTaskLabels(ctx, created.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Jira"}, labels)
+	assert.Contains(t, transport.items, "4")
+	assert.NotContains(t, transport.items, "2")
+	assert.Equal(t, "4", transport.resolveID(created.ID))
+
+	assert.Equal(t, "2024-03-08", transport.items["1"].toTask().Due.Day())
+}
+
+func TestSyncTransportFailedSync(t *testing.T) {
+	ctx := context.Background()
+	var requests []syncRequest
+	transport := newTestSyncTransport(t, func(request syncRequest) (int, syncResponse) {
+		requests = append(requests, request)
+		response := syncResponse{
+			SyncToken:  fmt.Sprintf("token%d", len(requests)),
+			FullSync:   request.token == initialSyncToken,
+			SyncStatus: okStatus(request.commands),
+		}
+		switch len(requests) {
+		case 1:
+			response.Items = []syncItem{{ID: "1", Content: "First", Labels: []string{}}}
+		case 2:
+			return http.StatusServiceUnavailable, syncResponse{}
+		case 3:
+			response.TempIDMapping = map[string]string{request.commands[0].TempID: "2"}
+			response.Items = []syncItem{
+				{ID: "1", Content: "First", Labels: []string{"Jira"}},
+				{ID: "2", Content: "New", Labels: []string{}, ChildOrder: 1},
+			}
+		}
+		return http.StatusOK, response
+	})
+
+	_, err := transport.getAllTasks(ctx)
+	require.NoError(t, err)
+	created, err := transport.createTask(ctx, "New", "")
+	require.NoError(t, err)
+	require.NoError(t, transport.updateTaskLabels(ctx, "1", []string{"Jira"}))
+
+	assert.Error(t, transport.flush(ctx))
+	assert.Len(t, transport.commands, 2, "the commands of a failed sync are queued again")
+	state, _ := transport.exportState()
+	assert.Equal(t, initialSyncToken, state.Token, "the cache is replaced by the next sync")
+
+	require.NoError(t, transport.flush(ctx))
+	require.Len(t, requests, 3)
+	assert.Equal(t, requests[1].commands, requests[2].commands)
+	assert.Equal(t, initialSyncToken, requests[2].token)
+	assert.Empty(t, transport.commands)
+	assert.Equal(t, "2", transport.resolveID(created.ID))
+	state, ok := transport.exportState()
+	require.True(t, ok)
+	assert.Equal(t, "token3", state.Token)
+	assert.Len(t, state.Tasks, 2)
+}
+
+func TestSyncTransportCommandErrors(t *testing.T) {
+	ctx := context.Background()
+	// Todoist rejects the updates of task 404, which was deleted
+	transport := newTestSyncTransport(t, func(request syncRequest) (int, syncResponse) {
+		status := okStatus(request.commands)
+		for _, command := range request.commands {
+			if command.Args["id"] == "404" {
+				status[command.UUID] = json.RawMessage(`{"error_code": 22, "error": "Item not found", "http_code": 404}`)
+			}
+		}
+		return http.StatusOK, syncResponse{SyncToken: "token", SyncStatus: status}
+	})
+
+	require.NoError(t, transport.setTaskPriority(ctx, "404", 4))
+	for i := 0; i < maxBatchCommands-2; i++ {
+		require.NoError(t, transport.setTaskPriority(ctx, "1", 2))
+	}
+	assert.NoError(t, transport.setTaskPriority(ctx, "2", 3), "the call sending the batch did not fail")
+	assert.Empty(t, transport.commands)
+	assert.Equal(t, initialSyncToken, transport.syncToken, "the cache is replaced by the next sync")
+
+	for i := 0; i < maxBatchCommands-1; i++ {
+		require.NoError(t, transport.setTaskPriority(ctx, "1", 2))
+	}
+	err := transport.setTaskPriority(ctx, "404", 4)
+	assert.True(t, httpclient.IsNotFound(err), "the call sending the batch failed: %v", err)
+	var commandError *CommandError
+	require.ErrorAs(t, err, &commandError)
+	assert.Equal(t, "404", commandError.TaskID)
+
+	err = transport.flush(ctx)
+	require.ErrorAs(t, err, &commandError)
+	assert.Equal(t, "404", commandError.TaskID)
+	assert.True(t, httpclient.IsNotFound(err))
+	assert.EqualError(t, err,
+		"item_update of task 404 failed: POST sync returned HTTP status 404: Item not found (code 22)")
+	assert.NoError(t, transport.flush(ctx), "failures are reported once")
+}
+
+func TestSyncTransportSyncedID(t *testing.T) {
+	ctx := context.Background()
+	// Todoist rejects the updates of task 404, which was deleted
+	transport := newTestSyncTransport(t, func(request syncRequest) (int, syncResponse) {
+		response := syncResponse{SyncToken: "token", SyncStatus: okStatus(request.commands)}
+		for _, command := range request.commands {
+			switch {
+			case command.Type == "item_add":
+				response.TempIDMapping = map[string]string{command.TempID: "2"}
+			case command.Args["id"] == "404":
+				response.SyncStatus[command.UUID] = json.RawMessage(`{"error": "Item not found", "http_code": 404}`)
+			}
+		}
+		return http.StatusOK, response
+	})
+
+	created, err := transport.createTask(ctx, "New", "")
+	require.NoError(t, err)
+	require.NoError(t, transport.setTaskPriority(ctx, "404", 4))
+	_, ok := transport.syncedID(created.ID)
+	assert.False(t, ok, "the task was not created yet")
+
+	assert.Error(t, transport.flush(ctx))
+	id, ok := transport.syncedID(created.ID)
+	assert.True(t, ok)
+	assert.Equal(t, "2", id)
+	_, ok = transport.syncedID("404")
+	assert.False(t, ok, "the change was rejected")
+
+	require.NoError(t, transport.setTaskPriority(ctx, created.ID, 4))
+	_, ok = transport.syncedID(created.ID)
+	assert.False(t, ok, "the change was not sent yet")
+	id, ok = transport.syncedID("1")
+	assert.True(t, ok, "the task was not changed")
+	assert.Equal(t, "1", id)
+}
+
+func TestSyncTransportTaskLabels(t *testing.T) {
+	ctx := context.Background()
+	// task 3 is completed until it is reopened
+	transport := newTestSyncTransport(t, func(request syncRequest) (int, syncResponse) {
+		response := syncResponse{SyncToken: "token", SyncStatus: okStatus(request.commands)}
+		if request.token == initialSyncToken {
+			response.Items = []syncItem{{ID: "1", Content: "First", Labels: []string{}}}
+		}
+		for _, command := range request.commands {
+			if command.Type == "item_uncomplete" {
+				response.Items = append(response.Items, syncItem{ID: "3", Content: "Done", Labels: []string{"Work"}})
+			}
+		}
+		return http.StatusOK, response
+	})
+
+	_, err := transport.getTaskLabels(ctx, "3")
+	assert.True(t, httpclient.IsNotFound(err), err)
+
+	require.NoError(t, transport.reopenTask(ctx, "3"))
+	labels, err := transport.getTaskLabels(ctx, "3")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Work"}, labels, "the reopened task is fetched")
+	assert.Empty(t, transport.commands)
+}
+
+func TestCommandError(t *testing.T) {
+	testCases := []struct {
+		name          string
+		command       syncCommand
+		status        json.RawMessage
+		expectedError string
+	}{
+		{
+			name:    "Successful command",
+			command: syncCommand{Type: "item_update", Args: map[string]any{"id": "1"}},
+			status:  json.RawMessage(`"ok"`),
+		},
+		{
+			name:    "Unprocessed command",
+			command: syncCommand{Type: "item_update", Args: map[string]any{"id": "1"}},
+		},
+		{
+			name:          "Failed command",
+			command:       syncCommand{Type: "item_close", Args: map[string]any{"id": "1"}},
+			status:        json.RawMessage(`{"error_code": 22, "error": "Item not found", "http_code": 404}`),
+			expectedError: "item_close of task 1 failed: POST sync returned HTTP status 404: Item not found (code 22)",
+		},
+		{
+			name:          "Failed creation",
+			command:       syncCommand{Type: "item_add", TempID: "temp", Args: map[string]any{"content": "New"}},
+			status:        json.RawMessage(`"invalid"`),
+			expectedError: `item_add of task temp failed: POST sync returned HTTP status 400: "invalid"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := commandError(tc.command, tc.status)
+			if tc.expectedError == "" {
+				assert.Nil(t, err)
+			} else {
+				assert.EqualError(t, err, tc.expectedError)
+			}
+		})
+	}
+}
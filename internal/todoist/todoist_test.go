@@ -1,10 +1,12 @@
 package todoist
 
 import (
+	"context"
 	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestRemoveLabelsFromTask(t *testing.T) {
@@ -58,13 +60,13 @@ func TestRemoveLabelsFromTask(t *testing.T) {
 			}
 
 			if tc.expectError {
-				mockTransport.On("getTaskLabels", tc.taskID).Return(nil, errors.New("Could not get labels"))
+				mockTransport.On("getTaskLabels", mock.Anything, tc.taskID).Return(nil, errors.New("Could not get labels"))
 			} else {
-				mockTransport.On("getTaskLabels", tc.taskID).Return(tc.currentLabels, nil)
-				mockTransport.On("updateTaskLabels", tc.taskID, tc.expectedLabels).Return(nil)
+				mockTransport.On("getTaskLabels", mock.Anything, tc.taskID).Return(tc.currentLabels, nil)
+				mockTransport.On("updateTaskLabels", mock.Anything, tc.taskID, tc.expectedLabels).Return(nil)
 			}
 
-			err := client.RemoveLabelsFromTask(tc.taskID, tc.labelsToRemove)
+			err := client.RemoveLabelsFromTask(context.Background(), tc.taskID, tc.labelsToRemove)
 
 			if tc.expectError {
 				assert.Error(t, err)
@@ -120,19 +122,19 @@ func TestAddLabelsToTask(t *testing.T) {
 			}
 
 			if tc.expectError {
-				mockTransport.On("getTaskLabels", tc.taskID).Return(nil, errors.New("error"))
+				mockTransport.On("getTaskLabels", mock.Anything, tc.taskID).Return(nil, errors.New("error"))
 			} else {
-				mockTransport.On("getTaskLabels", tc.taskID).Return(tc.existingLabels, nil)
-				mockTransport.On("updateTaskLabels", tc.taskID, tc.expectedLabels).Return(nil)
+				mockTransport.On("getTaskLabels", mock.Anything, tc.taskID).Return(tc.existingLabels, nil)
+				mockTransport.On("updateTaskLabels", mock.Anything, tc.taskID, tc.expectedLabels).Return(nil)
 			}
 
-			err := client.AddLabelsToTask(tc.taskID, tc.labelsToAdd)
+			err := client.AddLabelsToTask(context.Background(), tc.taskID, tc.labelsToAdd)
 
 			if tc.expectError {
 				assert.Error(t, err)
 			} else {
 				assert.NoError(t, err)
-				mockTransport.AssertCalled(t, "updateTaskLabels", tc.taskID, tc.expectedLabels)
+				mockTransport.AssertCalled(t, "updateTaskLabels", mock.Anything, tc.taskID, tc.expectedLabels)
 			}
 
 			mockTransport.AssertExpectations(t)
@@ -0,0 +1,517 @@
+// Package todoisttest provides an in-memory fake of the Todoist REST API for tests.
+package todoisttest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fabiocorneti/todoist-assistant/internal/httpclient"
+	"github.com/fabiocorneti/todoist-assistant/internal/todoist"
+)
+
+const (
+	// InboxID is the ID of the inbox project, where tasks created without a project are added.
+	InboxID = "inbox"
+
+	restPrefix      = "/rest/v2/"
+	completedPath   = "/sync/v9/completed/get_all"
+	completedFormat = "2006-01-02T15:04"
+	// dueDatetimeLayout is the layout of the due times of tasks, which are floating.
+	dueDatetimeLayout = "2006-01-02T15:04:05"
+	defaultPriority   = 1
+	maxPriority       = 4
+)
+
+// Task is a task stored by the fake server.
+type Task struct {
+	ID          string
+	ProjectID   string
+	Content     string
+	Description string
+	Labels      []string
+	Priority    int
+	Order       int
+	// Due is the date the task is due, such as 2024-03-05, followed by a time such as T10:00:00 if
+	// the task is due at a given time, or empty.
+	Due       string
+	Completed bool
+	// CompletedAt is when the task was completed; it defaults to when it was added or closed.
+	CompletedAt time.Time
+}
+
+// completedItem is a task as returned by the completed-items endpoint of the Sync API.
+type completedItem struct {
+	TaskID      string    `json:"task_id"`
+	Content     string    `json:"content"`
+	ProjectID   string    `json:"project_id"`
+	CompletedAt time.Time `json:"completed_at"`
+}
+
+// restTask is a task as returned by the REST API.
+type restTask struct {
+	ID          string   `json:"id"`
+	ProjectID   string   `json:"project_id"`
+	Content     string   `json:"content"`
+	Description string   `json:"description"`
+	Labels      []string `json:"labels"`
+	Priority    int      `json:"priority"`
+	Order       int      `json:"order"`
+	Due         *restDue `json:"due"`
+	IsCompleted bool     `json:"is_completed"`
+}
+
+type restDue struct {
+	Date     string `json:"date"`
+	Datetime string `json:"datetime,omitempty"`
+}
+
+type failure struct {
+	endpoint   string
+	status     int
+	retryAfter string
+	remaining  int
+}
+
+// Server is a fake of the REST v2 endpoints used by the assistant, backed by an httptest.Server:
+// projects, tasks and their creation, update, completion, reopening and deletion, as well as the
+// completed-items endpoint of the Sync API. Requests must
+// carry the token of the server; requests with the same X-Request-Id create a single task, like
+// the real API does.
+type Server struct {
+	// URL is the base URL to use as the Todoist API URL.
+	URL string
+
+	server *httptest.Server
+	token  string
+
+	mu       sync.Mutex
+	projects []todoist.Project
+	tasks    map[string]*Task
+	ids      []string
+	nextID   int
+	created  map[string]string
+	failures []*failure
+	requests []string
+}
+
+// NewServer starts a server accepting the given token, with an inbox project and no tasks; the
+// server is closed when the test ends.
+func NewServer(t testing.TB, token string) *Server {
+	s := &Server{
+		token:    token,
+		projects: []todoist.Project{{ID: InboxID, Name: "Inbox"}},
+		tasks:    make(map[string]*Task),
+		created:  make(map[string]string),
+	}
+	s.server = httptest.NewServer(s)
+	s.URL = s.server.URL
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+// AddProject adds a project; parentID is empty for top-level projects.
+func (s *Server) AddProject(name, parentID string) todoist.Project {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	project := todoist.Project{ID: s.newID(), Name: name, ParentID: parentID}
+	s.projects = append(s.projects, project)
+	return project
+}
+
+// AddTask adds a task and returns it; the ID, project, priority and order are set if empty.
+func (s *Server) AddTask(task Task) Task {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return *s.addTask(task)
+}
+
+// Complete completes a task, as a user would in the Todoist apps.
+func (s *Server) Complete(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if task, ok := s.tasks[id]; ok {
+		task.Completed = true
+		task.CompletedAt = time.Now()
+	}
+}
+
+// Reschedule sets the due date of a task, as a user would in the Todoist apps; the due date is
+// removed if due is empty.
+func (s *Server) Reschedule(id, due string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if task, ok := s.tasks[id]; ok {
+		task.Due = due
+	}
+}
+
+// Relabel replaces the labels of a task, as a user would in the Todoist apps.
+func (s *Server) Relabel(id string, labels []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if task, ok := s.tasks[id]; ok {
+		task.Labels = append([]string{}, labels...)
+	}
+}
+
+// Move moves a task to another project, as a user would in the Todoist apps.
+func (s *Server) Move(id, projectID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if task, ok := s.tasks[id]; ok {
+		task.ProjectID = projectID
+	}
+}
+
+// Task returns a task, including completed ones.
+func (s *Server) Task(id string) (Task, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	task, ok := s.tasks[id]
+	if !ok {
+		return Task{}, false
+	}
+	return copyTask(task), true
+}
+
+// Tasks returns all the tasks, including completed ones, in the order they were added.
+func (s *Server) Tasks() []Task {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tasks := make([]Task, 0, len(s.ids))
+	for _, id := range s.ids {
+		if task, ok := s.tasks[id]; ok {
+			tasks = append(tasks, copyTask(task))
+		}
+	}
+	return tasks
+}
+
+// InjectError makes the next count requests to endpoint fail with status; endpoint is a method and
+// a path without IDs, such as POST tasks/{id}/close, or empty to match any request.
+func (s *Server) InjectError(endpoint string, status, count int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, &failure{endpoint: endpoint, status: status, remaining: count})
+}
+
+// RateLimit makes the next count requests fail with 429 Too Many Requests and the given
+// Retry-After, rounded to seconds.
+func (s *Server) RateLimit(count int, retryAfter time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, &failure{
+		status:     http.StatusTooManyRequests,
+		retryAfter: strconv.Itoa(int(retryAfter.Seconds())),
+		remaining:  count,
+	})
+}
+
+// Requests returns the endpoints of the requests received so far, such as GET tasks, in order.
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	endpoint, id := route(r)
+	s.requests = append(s.requests, endpoint)
+	if r.Header.Get("Authorization") != "Bearer "+s.token {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if f := s.takeFailure(endpoint); f != nil {
+		if f.retryAfter != "" {
+			w.Header().Set("Retry-After", f.retryAfter)
+		}
+		http.Error(w, http.StatusText(f.status), f.status)
+		return
+	}
+
+	switch endpoint {
+	case "GET projects":
+		writeJSON(w, s.projects)
+	case "GET tasks":
+		s.listTasks(w, r.URL.Query().Get("project_id"))
+	case "POST tasks":
+		s.createTask(w, r)
+	case "GET " + completedPath:
+		s.listCompleted(w, r)
+	case "GET tasks/{id}", "POST tasks/{id}", "DELETE tasks/{id}", "POST tasks/{id}/close", "POST tasks/{id}/reopen":
+		task, ok := s.tasks[id]
+		if !ok || (task.Completed && endpoint != "POST tasks/{id}/reopen" && endpoint != "DELETE tasks/{id}") {
+			http.Error(w, "Task not found", http.StatusNotFound)
+			return
+		}
+		s.handleTask(w, r, endpoint, task)
+	default:
+		http.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
+func (s *Server) handleTask(w http.ResponseWriter, r *http.Request, endpoint string, task *Task) {
+	switch endpoint {
+	case "GET tasks/{id}":
+		writeJSON(w, toREST(task))
+	case "POST tasks/{id}":
+		var update struct {
+			Content     *string   `json:"content"`
+			Description *string   `json:"description"`
+			Labels      *[]string `json:"labels"`
+			Priority    *int      `json:"priority"`
+			DueDate     *string   `json:"due_date"`
+			DueString   *string   `json:"due_string"`
+			DueDatetime *string   `json:"due_datetime"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if update.Priority != nil && (*update.Priority < defaultPriority || *update.Priority > maxPriority) {
+			http.Error(w, "Invalid priority", http.StatusBadRequest)
+			return
+		}
+		if update.DueDate != nil {
+			if _, err := time.Parse(time.DateOnly, *update.DueDate); err != nil {
+				http.Error(w, "Invalid due date", http.StatusBadRequest)
+				return
+			}
+		}
+		if update.DueDatetime != nil {
+			if _, err := time.Parse(dueDatetimeLayout, *update.DueDatetime); err != nil {
+				http.Error(w, "Invalid due datetime", http.StatusBadRequest)
+				return
+			}
+		}
+		// only removing the due date is supported through due strings
+		if update.DueString != nil && *update.DueString != "no date" {
+			http.Error(w, "Unsupported due string", http.StatusBadRequest)
+			return
+		}
+		if update.Content != nil {
+			task.Content = *update.Content
+		}
+		if update.Description != nil {
+			task.Description = *update.Description
+		}
+		if update.Labels != nil {
+			task.Labels = append([]string{}, *update.Labels...)
+		}
+		if update.Priority != nil {
+			task.Priority = *update.Priority
+		}
+		if update.DueDate != nil {
+			task.Due = *update.DueDate
+		}
+		if update.DueDatetime != nil {
+			task.Due = *update.DueDatetime
+		}
+		if update.DueString != nil {
+			task.Due = ""
+		}
+		writeJSON(w, toREST(task))
+	case "DELETE tasks/{id}":
+		delete(s.tasks, task.ID)
+		w.WriteHeader(http.StatusNoContent)
+	case "POST tasks/{id}/close":
+		task.Completed = true
+		task.CompletedAt = time.Now()
+		w.WriteHeader(http.StatusNoContent)
+	case "POST tasks/{id}/reopen":
+		task.Completed = false
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (s *Server) listTasks(w http.ResponseWriter, projectID string) {
+	tasks := []restTask{}
+	for _, id := range s.ids {
+		task, ok := s.tasks[id]
+		if !ok || task.Completed || (projectID != "" && task.ProjectID != projectID) {
+			continue
+		}
+		tasks = append(tasks, toREST(task))
+	}
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return tasks[i].Order < tasks[j].Order
+	})
+	writeJSON(w, tasks)
+}
+
+func (s *Server) listCompleted(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	since, err := time.Parse(completedFormat, query.Get("since"))
+	if err != nil && query.Get("since") != "" {
+		http.Error(w, "Invalid since", http.StatusBadRequest)
+		return
+	}
+	offset, _ := strconv.Atoi(query.Get("offset")) //nolint:errcheck // defaults to 0
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 30
+	}
+
+	items := []completedItem{}
+	for _, id := range s.ids {
+		task, ok := s.tasks[id]
+		if !ok || !task.Completed || task.CompletedAt.Before(since) {
+			continue
+		}
+		items = append(items, completedItem{
+			TaskID:      task.ID,
+			Content:     task.Content,
+			ProjectID:   task.ProjectID,
+			CompletedAt: task.CompletedAt.UTC(),
+		})
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].CompletedAt.After(items[j].CompletedAt)
+	})
+	if offset > len(items) {
+		offset = len(items)
+	}
+	if offset+limit < len(items) {
+		items = items[:offset+limit]
+	}
+	writeJSON(w, map[string]any{"items": items[offset:], "projects": map[string]any{}})
+}
+
+func (s *Server) createTask(w http.ResponseWriter, r *http.Request) {
+	requestID := r.Header.Get(httpclient.RequestIDHeader)
+	if id, ok := s.created[requestID]; ok && requestID != "" {
+		writeJSON(w, toREST(s.tasks[id]))
+		return
+	}
+
+	var request struct {
+		Content   string   `json:"content"`
+		ProjectID string   `json:"project_id"`
+		Labels    []string `json:"labels"`
+		Priority  int      `json:"priority"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Content == "" {
+		http.Error(w, "Invalid task", http.StatusBadRequest)
+		return
+	}
+	if request.ProjectID != "" && !s.hasProject(request.ProjectID) {
+		http.Error(w, "Project not found", http.StatusBadRequest)
+		return
+	}
+	task := s.addTask(Task{
+		Content:   request.Content,
+		ProjectID: request.ProjectID,
+		Labels:    request.Labels,
+		Priority:  request.Priority,
+	})
+	if requestID != "" {
+		s.created[requestID] = task.ID
+	}
+	writeJSON(w, toREST(task))
+}
+
+func (s *Server) addTask(task Task) *Task {
+	if task.ID == "" {
+		task.ID = s.newID()
+	}
+	if task.ProjectID == "" {
+		task.ProjectID = InboxID
+	}
+	if task.Priority == 0 {
+		task.Priority = defaultPriority
+	}
+	if task.Order == 0 {
+		task.Order = len(s.ids) + 1
+	}
+	if task.Completed && task.CompletedAt.IsZero() {
+		task.CompletedAt = time.Now()
+	}
+	task.Labels = append([]string{}, task.Labels...)
+	s.tasks[task.ID] = &task
+	s.ids = append(s.ids, task.ID)
+	return &task
+}
+
+func (s *Server) hasProject(id string) bool {
+	for _, project := range s.projects {
+		if project.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) newID() string {
+	s.nextID++
+	return strconv.Itoa(s.nextID)
+}
+
+// takeFailure returns the injected failure for a request to endpoint, if any.
+func (s *Server) takeFailure(endpoint string) *failure {
+	for i, f := range s.failures {
+		if f.endpoint != "" && f.endpoint != endpoint {
+			continue
+		}
+		f.remaining--
+		if f.remaining <= 0 {
+			s.failures = append(s.failures[:i], s.failures[i+1:]...)
+		}
+		return f
+	}
+	return nil
+}
+
+// route returns the endpoint of a request, such as POST tasks/{id}/close, and the ID in its path.
+func route(r *http.Request) (string, string) {
+	path := strings.TrimPrefix(r.URL.Path, restPrefix)
+	if path == r.URL.Path {
+		return r.Method + " " + r.URL.Path, ""
+	}
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) > 1 && segments[0] == "tasks" {
+		id := segments[1]
+		segments[1] = "{id}"
+		return r.Method + " " + strings.Join(segments, "/"), id
+	}
+	return r.Method + " " + strings.Join(segments, "/"), ""
+}
+
+func toREST(task *Task) restTask {
+	result := restTask{
+		ID:          task.ID,
+		ProjectID:   task.ProjectID,
+		Content:     task.Content,
+		Description: task.Description,
+		Labels:      append([]string{}, task.Labels...),
+		Priority:    task.Priority,
+		Order:       task.Order,
+		IsCompleted: task.Completed,
+	}
+	if len(task.Due) > len(time.DateOnly) {
+		result.Due = &restDue{Date: task.Due[:len(time.DateOnly)], Datetime: task.Due}
+	} else if task.Due != "" {
+		result.Due = &restDue{Date: task.Due}
+	}
+	return result
+}
+
+func copyTask(task *Task) Task {
+	result := *task
+	result.Labels = append([]string{}, task.Labels...)
+	return result
+}
+
+func writeJSON(w http.ResponseWriter, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(value) //nolint:errcheck // the client sees a truncated response
+}
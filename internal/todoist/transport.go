@@ -1,13 +1,48 @@
 package todoist
 
+import (
+	"context"
+	"time"
+)
+
 //go:generate mockery --name=Transport --inpackage --structname=MockTransport
 type Transport interface {
-	getProjects() ([]Project, error)
-	getAllTasks() ([]Task, error)
-	getTasksForProject(projectID string) ([]Task, error)
-	getTaskLabels(taskID string) ([]string, error)
-	setTaskPriority(taskID string, priority int) error
-	completeTask(taskID string) error
-	createTask(content, projectID string) (*Task, error)
-	updateTaskLabels(taskID string, labels []string) error
+	getProjects(ctx context.Context) ([]Project, error)
+	getAllTasks(ctx context.Context) ([]Task, error)
+	getCompletedTasks(ctx context.Context, since time.Time) ([]CompletedTask, error)
+	getTasksForProject(ctx context.Context, projectID string) ([]Task, error)
+	getTaskLabels(ctx context.Context, taskID string) ([]string, error)
+	setTaskPriority(ctx context.Context, taskID string, priority int) error
+	completeTask(ctx context.Context, taskID string) error
+	reopenTask(ctx context.Context, taskID string) error
+	deleteTask(ctx context.Context, taskID string) error
+	createTask(ctx context.Context, content, projectID string) (*Task, error)
+	updateTaskLabels(ctx context.Context, taskID string, labels []string) error
+	updateTaskContent(ctx context.Context, taskID, content string) error
+	updateTaskDescription(ctx context.Context, taskID, description string) error
+	setTaskDueDate(ctx context.Context, taskID, date string) error
+	flush(ctx context.Context) error
+
+	// lastWrite returns the ID of the last write if it is buffered until the transport is flushed,
+	// or "" if it was applied immediately; writeStatus returns the outcome of a buffered write.
+	lastWrite() string
+	writeStatus(writeID string) writeStatus
+	// resolveID returns the ID assigned by Todoist to a task created with a temporary ID.
+	resolveID(taskID string) string
+	// syncedID returns the ID of a task once all its writes have been applied, or false if some
+	// were rejected or are still buffered.
+	syncedID(taskID string) (string, bool)
+	// exportState returns a snapshot of the cache of the transport, or false if it does not cache;
+	// restoreState loads it.
+	exportState() (SyncState, bool)
+	restoreState(state SyncState)
 }
+
+// writeStatus is the outcome of a write buffered by a Transport.
+type writeStatus int
+
+const (
+	writeApplied writeStatus = iota
+	writeQueued
+	writeRejected
+)
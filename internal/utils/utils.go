@@ -2,11 +2,20 @@ package utils
 
 import (
 	"fmt"
+	"regexp"
+	"strings"
 
 	"github.com/fabiocorneti/todoist-assistant/internal/config"
 	"github.com/fabiocorneti/todoist-assistant/internal/jira"
 )
 
+var (
+	// linkStart matches the start of the link written by FormatTodoistTaskContent and the issue key.
+	linkStart = regexp.MustCompile(`\[\[([A-Z0-9-]+)\] `)
+	// linkEnd matches the end of a markdown link and its target.
+	linkEnd = regexp.MustCompile(`\]\(([^\s()]+)\)`)
+)
+
 func Contains(slice []string, str string) bool {
 	for _, v := range slice {
 		if v == str {
@@ -39,7 +48,33 @@ func HaveSameElements(slice1, slice2 []string) bool {
 }
 
 func FormatTodoistTaskContent(jiraConfig config.JiraConfig, issue jira.Issue) string {
-	issueURL := fmt.Sprintf("%s/browse/%s", jiraConfig.Site, issue.Key)
-	taskContent := fmt.Sprintf("[[%s] %s](%s)", issue.Key, issue.Fields.Summary, issueURL)
+	taskContent := fmt.Sprintf("[[%s] %s](%s)", issue.Key, issue.Fields.Summary, jira.BrowseURL(jiraConfig, issue.Key))
 	return taskContent
 }
+
+// ParseTodoistTaskContent finds the link to a Jira issue written by FormatTodoistTaskContent in the
+// content of a task; it returns the key of the issue and the text before and after the link, which
+// was added by the user.
+func ParseTodoistTaskContent(content string) (key, prefix, suffix string, ok bool) {
+	start := linkStart.FindStringSubmatchIndex(content)
+	if start == nil {
+		return "", "", "", false
+	}
+	key = content[start[2]:start[3]]
+	rest := content[start[1]:]
+	ends := linkEnd.FindAllStringSubmatchIndex(rest, -1)
+	if len(ends) == 0 {
+		return "", "", "", false
+	}
+
+	// The summary may contain links itself, so the link ends at the first target pointing to the
+	// issue or, failing that, at the last link end.
+	end := ends[len(ends)-1]
+	for _, candidate := range ends {
+		if strings.HasSuffix(rest[candidate[2]:candidate[3]], "/browse/"+key) {
+			end = candidate
+			break
+		}
+	}
+	return key, content[:start[0]], rest[end[1]:], true
+}
@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTodoistTaskContent(t *testing.T) {
+	testCases := []struct {
+		name           string
+		content        string
+		expectedKey    string
+		expectedPrefix string
+		expectedSuffix string
+		expectedOK     bool
+	}{
+		{
+			name:        "Link only",
+			content:     "[[PRJ-1] Fix the login](https://example.atlassian.net/browse/PRJ-1)",
+			expectedKey: "PRJ-1",
+			expectedOK:  true,
+		},
+		{
+			name:           "Prefix and suffix",
+			content:        "Urgent: [[PRJ-1] Fix the login](https://example.atlassian.net/browse/PRJ-1) by Friday",
+			expectedKey:    "PRJ-1",
+			expectedPrefix: "Urgent: ",
+			expectedSuffix: " by Friday",
+			expectedOK:     true,
+		},
+		{
+			name: "Links in the summary and the suffix",
+			content: "[[PRJ-1] Fix [the docs](https://docs.example.com)](https://example.atlassian.net/browse/PRJ-1)" +
+				" see [notes](https://notes.example.com)",
+			expectedKey:    "PRJ-1",
+			expectedSuffix: " see [notes](https://notes.example.com)",
+			expectedOK:     true,
+		},
+		{
+			name:    "No link",
+			content: "[PRJ-1] Fix the login",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			key, prefix, suffix, ok := ParseTodoistTaskContent(tc.content)
+			assert.Equal(t, tc.expectedOK, ok)
+			assert.Equal(t, tc.expectedKey, key)
+			assert.Equal(t, tc.expectedPrefix, prefix)
+			assert.Equal(t, tc.expectedSuffix, suffix)
+		})
+	}
+}
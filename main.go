@@ -1,23 +1,12 @@
 package main
 
 import (
-	"time"
+	"os"
+	_ "time/tzdata" // schedule time zones must be available in images without tzdata
 
-	"github.com/fabiocorneti/todoist-assistant/internal/config"
-	"github.com/fabiocorneti/todoist-assistant/internal/process"
+	"github.com/fabiocorneti/todoist-assistant/internal/cli"
 )
 
 func main() {
-	cfg := config.GetConfiguration()
-	logger := config.GetLogger()
-
-	ticker := time.NewTicker(time.Duration(cfg.UpdateInterval) * time.Minute)
-	defer ticker.Stop()
-
-	process.RunProcess(cfg, logger)
-	logger.Infof("Waiting %d minutes to perform the next update", cfg.UpdateInterval)
-	for range ticker.C {
-		process.RunProcess(cfg, logger)
-		logger.Infof("Waiting %d minutes to perform the next update", cfg.UpdateInterval)
-	}
+	os.Exit(cli.Execute())
 }